@@ -30,6 +30,24 @@ func AddDays(t time.Time, days int) time.Time {
 	return t.Add(time.Hour * 24 * time.Duration(days))
 }
 
+// Date is the calendar date of t, wherever it is, at midnight UTC.  UTC has no daylight saving,
+// so AddDays on a Date always lands on midnight of another date.
+func Date(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// DaysBetween is the number of calendar days from a to b, ignoring the time of day,
+// so 23:00 one day to 01:00 the next is 1 day.  It is negative if b is before a.
+func DaysBetween(a, b time.Time) int {
+	return int(Date(b).Sub(Date(a)) / (time.Hour * 24))
+}
+
+// DaysInMonth is the number of days in the month
+func DaysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
 // DaysFromNowIgnoringTime works out the day differential from now but based on actual days, rather than units of 24hrs
 func DaysFromNowIgnoringTime(t time.Time, days int) int {
 	futureDate := AddDays(t, days)
@@ -0,0 +1,55 @@
+package datetime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDaysBetween(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name     string
+		a, b     time.Time
+		expected int
+	}{
+		{"same day", time.Date(2021, 1, 1, 1, 0, 0, 0, time.UTC), time.Date(2021, 1, 1, 23, 0, 0, 0, time.UTC), 0},
+		{"late to early", time.Date(2021, 1, 1, 23, 0, 0, 0, time.UTC), time.Date(2021, 1, 2, 1, 0, 0, 0, time.UTC), 1},
+		{"leap year", time.Date(2020, 2, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 3, 1, 0, 0, 0, 0, time.UTC), 29},
+		{"backwards", time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), -28},
+		{"across the clocks going forward", time.Date(2021, 3, 27, 0, 30, 0, 0, london), time.Date(2021, 3, 29, 0, 30, 0, 0, london), 2},
+		{"across the clocks going back", time.Date(2021, 10, 30, 0, 0, 0, 0, london), time.Date(2021, 11, 1, 0, 0, 0, 0, london), 2},
+	}
+
+	for _, test := range tests {
+		got := DaysBetween(test.a, test.b)
+		if got != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestDaysInMonth(t *testing.T) {
+	tests := []struct {
+		year     int
+		month    time.Month
+		expected int
+	}{
+		{2021, time.January, 31},
+		{2021, time.February, 28},
+		{2020, time.February, 29},
+		{2100, time.February, 28},
+		{2021, time.April, 30},
+		{2021, time.December, 31},
+	}
+
+	for _, test := range tests {
+		got := DaysInMonth(test.year, test.month)
+		if got != test.expected {
+			t.Errorf("Testing %d %s. Expected %v; got %v", test.year, test.month, test.expected, got)
+		}
+	}
+}
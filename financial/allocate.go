@@ -0,0 +1,154 @@
+package financial
+
+import (
+	"math"
+	"math/big"
+	"sort"
+)
+
+// AllocationMethod decides which parts get the leftover cents when an amount
+// doesn't divide exactly
+type AllocationMethod uint
+
+const (
+	// AllocateLargestRemainder gives the leftover cents to the parts that lost the most
+	// in rounding down, earliest first where they lost the same
+	AllocateLargestRemainder AllocationMethod = iota
+	// AllocateToFirst gives the leftover cents to the first parts, one each
+	AllocateToFirst
+	// AllocateToLast gives the leftover cents to the last parts, one each
+	AllocateToLast
+)
+
+// Allocate splits the amount into n parts which always add up to the original amount,
+// unlike DivideByQty which rounds each share on its own.  So 100 in 3 parts is 34, 33, 33.
+func (c Cents) Allocate(n int) []Cents {
+	return c.AllocateMethod(n, AllocateLargestRemainder)
+}
+
+// AllocateMethod splits the amount into n equal parts, with the leftover cents put
+// where the method says
+func (c Cents) AllocateMethod(n int, method AllocationMethod) []Cents {
+	if n <= 0 {
+		return nil
+	}
+
+	ratios := make([]int, n)
+	for i := range ratios {
+		ratios[i] = 1
+	}
+
+	return c.AllocateByRatiosMethod(ratios, method)
+}
+
+// AllocateByRatios splits the amount in proportion to the ratios, e.g. line totals when
+// spreading an order discount.  The parts always add up to the original amount.
+// Negative ratios are treated as zero, and if all of the ratios are zero, the amount is split equally.
+func (c Cents) AllocateByRatios(ratios []int) []Cents {
+	return c.AllocateByRatiosMethod(ratios, AllocateLargestRemainder)
+}
+
+func (c Cents) AllocateByRatiosMethod(ratios []int, method AllocationMethod) []Cents {
+	rats := make([]*big.Rat, len(ratios))
+	for i, r := range ratios {
+		rats[i] = new(big.Rat).SetInt64(int64(r))
+	}
+
+	return c.allocate(rats, method)
+}
+
+// AllocateByFloatRatios is the same as AllocateByRatios, for ratios such as weights or percentages
+func (c Cents) AllocateByFloatRatios(ratios []float64) []Cents {
+	return c.AllocateByFloatRatiosMethod(ratios, AllocateLargestRemainder)
+}
+
+func (c Cents) AllocateByFloatRatiosMethod(ratios []float64, method AllocationMethod) []Cents {
+	rats := make([]*big.Rat, len(ratios))
+	for i, r := range ratios {
+		rats[i] = new(big.Rat)
+		// NaN and infinities can't be used as a ratio, so they are treated like a zero
+		if !math.IsNaN(r) && !math.IsInf(r, 0) {
+			rats[i].SetFloat64(r)
+		}
+	}
+
+	return c.allocate(rats, method)
+}
+
+type allocationPart struct {
+	index     int
+	remainder *big.Rat
+}
+
+func (c Cents) allocate(ratios []*big.Rat, method AllocationMethod) []Cents {
+	if len(ratios) == 0 {
+		return nil
+	}
+
+	// Work with a positive amount and put the sign back on at the end
+	isNegative := c < 0
+	amount := new(big.Int).SetInt64(int64(c))
+	amount.Abs(amount)
+
+	total := new(big.Rat)
+	for _, r := range ratios {
+		if r.Sign() < 0 {
+			r.SetInt64(0)
+		}
+		total.Add(total, r)
+	}
+
+	if total.Sign() == 0 {
+		for _, r := range ratios {
+			r.SetInt64(1)
+		}
+		total.SetInt64(int64(len(ratios)))
+	}
+
+	res := make([]Cents, len(ratios))
+	var parts []allocationPart
+	allocated := new(big.Int)
+
+	for i, r := range ratios {
+		// exact share = amount * ratio / total, which we split into
+		// a whole number of cents and a remainder
+		exact := new(big.Rat).SetInt(amount)
+		exact.Mul(exact, r)
+		exact.Quo(exact, total)
+
+		whole := new(big.Int).Quo(exact.Num(), exact.Denom())
+		res[i] = Cents(whole.Int64())
+		allocated.Add(allocated, whole)
+
+		if r.Sign() > 0 {
+			remainder := new(big.Rat).Sub(exact, new(big.Rat).SetInt(whole))
+			parts = append(parts, allocationPart{index: i, remainder: remainder})
+		}
+	}
+
+	switch method {
+	case AllocateToFirst:
+	case AllocateToLast:
+		for i, j := 0, len(parts)-1; i < j; i, j = i+1, j-1 {
+			parts[i], parts[j] = parts[j], parts[i]
+		}
+	default:
+		sort.SliceStable(parts, func(i, j int) bool {
+			return parts[i].remainder.Cmp(parts[j].remainder) > 0
+		})
+	}
+
+	// The leftover is always less than the number of parts with a positive ratio
+	leftover := new(big.Int).Sub(amount, allocated).Int64()
+	for i := int64(0); i < leftover; i++ {
+		res[parts[i].index]++
+	}
+
+	if isNegative {
+		for i := range res {
+			res[i] = -res[i]
+		}
+	}
+
+	return res
+}
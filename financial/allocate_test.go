@@ -0,0 +1,125 @@
+package financial
+
+import (
+	"math"
+	"testing"
+	"testing/quick"
+)
+
+func compareCentSlices(a, b []Cents) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func sumCents(cs []Cents) (total Cents) {
+	for _, c := range cs {
+		total += c
+	}
+
+	return
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        Cents
+		n        int
+		method   AllocationMethod
+		expected []Cents
+	}{
+		{"zero parts", 100, 0, AllocateLargestRemainder, nil},
+		{"one part", 100, 1, AllocateLargestRemainder, []Cents{100}},
+		{"exact", 100, 4, AllocateLargestRemainder, []Cents{25, 25, 25, 25}},
+		{"100 three ways", 100, 3, AllocateLargestRemainder, []Cents{34, 33, 33}},
+		{"100 three ways to last", 100, 3, AllocateToLast, []Cents{33, 33, 34}},
+		{"101 three ways to first", 101, 3, AllocateToFirst, []Cents{34, 34, 33}},
+		{"101 three ways to last", 101, 3, AllocateToLast, []Cents{33, 34, 34}},
+		{"less than parts", 2, 5, AllocateLargestRemainder, []Cents{1, 1, 0, 0, 0}},
+		{"negative", -100, 3, AllocateLargestRemainder, []Cents{-34, -33, -33}},
+		{"zero", 0, 3, AllocateLargestRemainder, []Cents{0, 0, 0}},
+	}
+
+	for _, test := range tests {
+		res := test.c.AllocateMethod(test.n, test.method)
+		if !compareCentSlices(res, test.expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+	}
+}
+
+func TestAllocateByRatios(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        Cents
+		ratios   []int
+		expected []Cents
+	}{
+		{"no ratios", 100, nil, nil},
+		{"even", 100, []int{1, 1}, []Cents{50, 50}},
+		{"70/30 of 5", 5, []int{70, 30}, []Cents{4, 1}},
+		{"largest remainder wins", 100, []int{1, 2, 3}, []Cents{17, 33, 50}},
+		{"largest remainder, not first", 1000, []int{333, 333, 334}, []Cents{333, 333, 334}},
+		{"zero ratio gets nothing", 100, []int{0, 1, 2}, []Cents{0, 33, 67}},
+		{"negative ratio is zero", 100, []int{-5, 1, 1}, []Cents{0, 50, 50}},
+		{"all zero ratios is even", 100, []int{0, 0, 0}, []Cents{34, 33, 33}},
+		{"discount across lines", -1000, []int{1999, 4999, 999}, []Cents{-250, -625, -125}},
+	}
+
+	for _, test := range tests {
+		res := test.c.AllocateByRatios(test.ratios)
+		if !compareCentSlices(res, test.expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+	}
+}
+
+func TestAllocateByFloatRatios(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        Cents
+		ratios   []float64
+		expected []Cents
+	}{
+		{"thirds", 100, []float64{1.0 / 3, 1.0 / 3, 1.0 / 3}, []Cents{34, 33, 33}},
+		{"weights", 1000, []float64{2.5, 1.25, 1.25}, []Cents{500, 250, 250}},
+		{"percentages", 999, []float64{50, 30, 20}, []Cents{499, 300, 200}},
+		{"NaN is zero", 100, []float64{math.NaN(), 1}, []Cents{0, 100}},
+		{"infinity is zero", 100, []float64{math.Inf(1), 1}, []Cents{0, 100}},
+	}
+
+	for _, test := range tests {
+		res := test.c.AllocateByFloatRatios(test.ratios)
+		if !compareCentSlices(res, test.expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+	}
+}
+
+func TestAllocateAlwaysAddsUp(t *testing.T) {
+	f := func(c int32, ratios []uint16, method uint8) bool {
+		if len(ratios) == 0 {
+			return true
+		}
+
+		rs := make([]int, len(ratios))
+		for i, r := range ratios {
+			rs[i] = int(r)
+		}
+
+		res := Cents(c).AllocateByRatiosMethod(rs, AllocationMethod(method%3))
+		return len(res) == len(rs) && sumCents(res) == Cents(c)
+	}
+
+	if err := quick.Check(f, nil); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,257 @@
+package financial
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/dogpakk/lib/datetime"
+)
+
+var (
+	ErrInvalidBillingInterval = errors.New("invalid billing interval")
+	ErrOutsideBillingSchedule = errors.New("date is before the billing schedule starts")
+)
+
+type BillingUnit uint
+
+const (
+	BillingUnitMonth BillingUnit = iota
+	BillingUnitYear
+	BillingUnitWeek
+	BillingUnitDay
+)
+
+// BillingInterval is how often a subscription is invoiced, e.g. every 1 month, every 3 months or every 14 days
+type BillingInterval struct {
+	Unit  BillingUnit
+	Count int
+}
+
+var (
+	BillingMonthly   = BillingInterval{Unit: BillingUnitMonth, Count: 1}
+	BillingQuarterly = BillingInterval{Unit: BillingUnitMonth, Count: 3}
+	BillingAnnual    = BillingInterval{Unit: BillingUnitYear, Count: 1}
+)
+
+// months is the length of the interval in months, or 0 if it is measured in days
+func (bi BillingInterval) months() int {
+	switch bi.Unit {
+	case BillingUnitMonth:
+		return bi.Count
+	case BillingUnitYear:
+		return bi.Count * 12
+	}
+
+	return 0
+}
+
+// days is the length of the interval in days, or 0 if it is measured in months
+func (bi BillingInterval) days() int {
+	switch bi.Unit {
+	case BillingUnitWeek:
+		return bi.Count * 7
+	case BillingUnitDay:
+		return bi.Count
+	}
+
+	return 0
+}
+
+func (bi BillingInterval) Validate() error {
+	if bi.Count <= 0 || bi.Unit > BillingUnitDay {
+		return fmt.Errorf("%w: %d of unit %d", ErrInvalidBillingInterval, bi.Count, bi.Unit)
+	}
+
+	return nil
+}
+
+// BillingSchedule works out the invoice dates of a subscription.  Start is the first invoice date,
+// and each invoice starts a billing period that runs up to the next.
+//
+// For monthly and yearly intervals, invoices fall on AnchorDay, which defaults to the day of Start.
+// In months that are too short for it, such as February for an anchor of the 29th to 31st, the invoice
+// falls on the last day of the month instead, and goes back to the anchor day the month after.
+// Setting AnchorDay is only needed when Start has itself been moved to the end of a short month.
+type BillingSchedule struct {
+	Start     time.Time
+	Interval  BillingInterval
+	AnchorDay int
+}
+
+func NewBillingSchedule(start time.Time, interval BillingInterval) BillingSchedule {
+	return BillingSchedule{Start: start, Interval: interval}
+}
+
+func (bs BillingSchedule) Validate() error {
+	if err := bs.Interval.Validate(); err != nil {
+		return err
+	}
+
+	if bs.AnchorDay < 0 || bs.AnchorDay > 31 {
+		return fmt.Errorf("%w: anchor day %d", ErrInvalidBillingInterval, bs.AnchorDay)
+	}
+
+	return nil
+}
+
+func (bs BillingSchedule) anchorDay() int {
+	if bs.AnchorDay == 0 {
+		return bs.Start.Day()
+	}
+
+	return bs.AnchorDay
+}
+
+// onDate is the date, at the same time of day and in the same location as Start
+func (bs BillingSchedule) onDate(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, bs.Start.Hour(), bs.Start.Minute(), bs.Start.Second(), bs.Start.Nanosecond(), bs.Start.Location())
+}
+
+// InvoiceDate is the date of the nth invoice, counting Start as 0.  It is always worked out from Start,
+// rather than from the invoice before, so that a short month doesn't pull all the later dates back.
+func (bs BillingSchedule) InvoiceDate(n int) time.Time {
+	if n == 0 {
+		return bs.Start
+	}
+
+	if days := bs.Interval.days(); days > 0 {
+		// Whole days are added to the UTC date, so that daylight saving doesn't move the invoice to another day
+		y, m, d := datetime.AddDays(datetime.Date(bs.Start), n*days).Date()
+		return bs.onDate(y, m, d)
+	}
+
+	first := time.Date(bs.Start.Year(), bs.Start.Month()+time.Month(n*bs.Interval.months()), 1, 0, 0, 0, 0, time.UTC)
+	day := bs.anchorDay()
+	if last := datetime.DaysInMonth(first.Year(), first.Month()); day > last {
+		day = last
+	}
+
+	return bs.onDate(first.Year(), first.Month(), day)
+}
+
+// period is the number of the billing period that t falls in, counting from 0, or -1 if t is before Start
+func (bs BillingSchedule) period(t time.Time) int {
+	if t.Before(bs.Start) {
+		return -1
+	}
+
+	// Estimate, and then correct for anchor days and times of day
+	var n int
+	if days := bs.Interval.days(); days > 0 {
+		n = datetime.DaysBetween(bs.Start, t) / days
+	} else {
+		n = ((t.Year()-bs.Start.Year())*12 + int(t.Month()-bs.Start.Month())) / bs.Interval.months()
+	}
+
+	for n > 0 && bs.InvoiceDate(n).After(t) {
+		n--
+	}
+	for !bs.InvoiceDate(n + 1).After(t) {
+		n++
+	}
+
+	return n
+}
+
+// BillingPeriod is one period of a schedule, from its invoice date up to, but not including, the next
+type BillingPeriod struct {
+	Number     int
+	Start, End time.Time
+}
+
+// Days is the length of the period in calendar days
+func (bp BillingPeriod) Days() int {
+	return datetime.DaysBetween(bp.Start, bp.End)
+}
+
+// Period is the billing period that t falls in
+func (bs BillingSchedule) Period(t time.Time) (BillingPeriod, error) {
+	if err := bs.Validate(); err != nil {
+		return BillingPeriod{}, err
+	}
+
+	n := bs.period(t)
+	if n < 0 {
+		return BillingPeriod{}, fmt.Errorf("%w: %s", ErrOutsideBillingSchedule, t.Format(rateDateFormat))
+	}
+
+	return BillingPeriod{Number: n, Start: bs.InvoiceDate(n), End: bs.InvoiceDate(n + 1)}, nil
+}
+
+// NextInvoiceDate is the first invoice date after t
+func (bs BillingSchedule) NextInvoiceDate(t time.Time) (time.Time, error) {
+	if err := bs.Validate(); err != nil {
+		return time.Time{}, err
+	}
+
+	return bs.InvoiceDate(bs.period(t) + 1), nil
+}
+
+// InvoiceDates are the invoice dates from the from date, inclusive, up to the to date, exclusive
+func (bs BillingSchedule) InvoiceDates(from, to time.Time) ([]time.Time, error) {
+	if err := bs.Validate(); err != nil {
+		return nil, err
+	}
+
+	var res []time.Time
+	n := bs.period(from)
+	if n < 0 {
+		n = 0
+	}
+
+	for d := bs.InvoiceDate(n); d.Before(to); d = bs.InvoiceDate(n) {
+		if !d.Before(from) {
+			res = append(res, d)
+		}
+		n++
+	}
+
+	return res, nil
+}
+
+// Prorate is the share of the amount for the number of days out of the total
+func (c Cents) Prorate(days, totalDays int, mode RoundingMode) Cents {
+	if totalDays == 0 {
+		return 0
+	}
+
+	return divideRounded(new(big.Rat).Mul(centsRat(c), big.NewRat(int64(days), 1)), big.NewRat(int64(totalDays), 1), mode)
+}
+
+// Proration is the credit for the unused part of the old plan and the charge for the rest of the period
+// on the new plan, when a subscription changes plan part way through a billing period.
+// The day of the change is charged on the new plan.
+type Proration struct {
+	Period        BillingPeriod
+	ChangeDate    time.Time
+	DaysRemaining int
+
+	Credit, Charge Cents
+}
+
+// Net is what to invoice for the change, or if it is negative, what to credit
+func (p Proration) Net() Cents {
+	return p.Charge - p.Credit
+}
+
+// Prorate works out the credit and charge for a change from the old price to the new price on the change date,
+// both prices being for a whole billing period
+func (bs BillingSchedule) Prorate(changeDate time.Time, oldPrice, newPrice Cents, mode RoundingMode) (Proration, error) {
+	period, err := bs.Period(changeDate)
+	if err != nil {
+		return Proration{}, err
+	}
+
+	remaining := datetime.DaysBetween(changeDate, period.End)
+	days := period.Days()
+
+	return Proration{
+		Period:        period,
+		ChangeDate:    changeDate,
+		DaysRemaining: remaining,
+		Credit:        oldPrice.Prorate(remaining, days, mode),
+		Charge:        newPrice.Prorate(remaining, days, mode),
+	}, nil
+}
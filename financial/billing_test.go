@@ -0,0 +1,240 @@
+package financial
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func billingDate(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestBillingScheduleInvoiceDate(t *testing.T) {
+	london, err := time.LoadLocation("Europe/London")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	tests := []struct {
+		name     string
+		schedule BillingSchedule
+		expected []time.Time
+	}{
+		{
+			name:     "monthly on the 31st",
+			schedule: NewBillingSchedule(billingDate(2021, 1, 31), BillingMonthly),
+			expected: []time.Time{
+				billingDate(2021, 1, 31), billingDate(2021, 2, 28), billingDate(2021, 3, 31),
+				billingDate(2021, 4, 30), billingDate(2021, 5, 31), billingDate(2021, 6, 30),
+			},
+		},
+		{
+			name:     "monthly on the 30th",
+			schedule: NewBillingSchedule(billingDate(2021, 1, 30), BillingMonthly),
+			expected: []time.Time{billingDate(2021, 1, 30), billingDate(2021, 2, 28), billingDate(2021, 3, 30)},
+		},
+		{
+			name:     "monthly on the 29th in a leap year",
+			schedule: NewBillingSchedule(billingDate(2020, 1, 29), BillingMonthly),
+			expected: []time.Time{billingDate(2020, 1, 29), billingDate(2020, 2, 29), billingDate(2020, 3, 29)},
+		},
+		{
+			name:     "anchor day after a short month",
+			schedule: BillingSchedule{Start: billingDate(2021, 2, 28), Interval: BillingMonthly, AnchorDay: 31},
+			expected: []time.Time{billingDate(2021, 2, 28), billingDate(2021, 3, 31), billingDate(2021, 4, 30)},
+		},
+		{
+			name:     "quarterly",
+			schedule: NewBillingSchedule(billingDate(2021, 8, 31), BillingQuarterly),
+			expected: []time.Time{billingDate(2021, 8, 31), billingDate(2021, 11, 30), billingDate(2022, 2, 28), billingDate(2022, 5, 31)},
+		},
+		{
+			name:     "annual from a leap day",
+			schedule: NewBillingSchedule(billingDate(2020, 2, 29), BillingAnnual),
+			expected: []time.Time{billingDate(2020, 2, 29), billingDate(2021, 2, 28), billingDate(2022, 2, 28), billingDate(2023, 2, 28), billingDate(2024, 2, 29)},
+		},
+		{
+			name:     "fortnightly across the clocks going forward",
+			schedule: NewBillingSchedule(time.Date(2021, 3, 20, 9, 0, 0, 0, london), BillingInterval{Unit: BillingUnitWeek, Count: 2}),
+			expected: []time.Time{time.Date(2021, 3, 20, 9, 0, 0, 0, london), time.Date(2021, 4, 3, 9, 0, 0, 0, london)},
+		},
+		{
+			name:     "every 10 days",
+			schedule: NewBillingSchedule(billingDate(2021, 2, 25), BillingInterval{Unit: BillingUnitDay, Count: 10}),
+			expected: []time.Time{billingDate(2021, 2, 25), billingDate(2021, 3, 7), billingDate(2021, 3, 17)},
+		},
+	}
+
+	for _, test := range tests {
+		for n, expected := range test.expected {
+			got := test.schedule.InvoiceDate(n)
+			if !got.Equal(expected) {
+				t.Errorf("Testing %s invoice %d. Expected %v; got %v", test.name, n, expected, got)
+			}
+		}
+	}
+}
+
+func TestBillingSchedulePeriod(t *testing.T) {
+	bs := NewBillingSchedule(billingDate(2021, 1, 31), BillingMonthly)
+
+	tests := []struct {
+		name          string
+		date          time.Time
+		expected      BillingPeriod
+		expectedDays  int
+		expectedNext  time.Time
+		expectedError error
+	}{
+		{
+			name:         "first period",
+			date:         billingDate(2021, 2, 27),
+			expected:     BillingPeriod{Number: 0, Start: billingDate(2021, 1, 31), End: billingDate(2021, 2, 28)},
+			expectedDays: 28,
+			expectedNext: billingDate(2021, 2, 28),
+		},
+		{
+			name:         "on an invoice date",
+			date:         billingDate(2021, 2, 28),
+			expected:     BillingPeriod{Number: 1, Start: billingDate(2021, 2, 28), End: billingDate(2021, 3, 31)},
+			expectedDays: 31,
+			expectedNext: billingDate(2021, 3, 31),
+		},
+		{
+			name:         "later in the day",
+			date:         time.Date(2021, 4, 30, 15, 0, 0, 0, time.UTC),
+			expected:     BillingPeriod{Number: 3, Start: billingDate(2021, 4, 30), End: billingDate(2021, 5, 31)},
+			expectedDays: 31,
+			expectedNext: billingDate(2021, 5, 31),
+		},
+		{
+			name:          "before the start",
+			date:          billingDate(2021, 1, 30),
+			expectedError: ErrOutsideBillingSchedule,
+			expectedNext:  billingDate(2021, 1, 31),
+		},
+	}
+
+	for _, test := range tests {
+		got, err := bs.Period(test.date)
+		if !errors.Is(err, test.expectedError) {
+			t.Errorf("Testing %s error. Expected %v; got %v", test.name, test.expectedError, err)
+		}
+
+		if got.Number != test.expected.Number || !got.Start.Equal(test.expected.Start) || !got.End.Equal(test.expected.End) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, got)
+		}
+
+		if err == nil && got.Days() != test.expectedDays {
+			t.Errorf("Testing %s days. Expected %v; got %v", test.name, test.expectedDays, got.Days())
+		}
+
+		next, _ := bs.NextInvoiceDate(test.date)
+		if !next.Equal(test.expectedNext) {
+			t.Errorf("Testing %s next invoice. Expected %v; got %v", test.name, test.expectedNext, next)
+		}
+	}
+}
+
+func TestBillingScheduleInvoiceDates(t *testing.T) {
+	bs := NewBillingSchedule(billingDate(2021, 1, 31), BillingMonthly)
+
+	got, err := bs.InvoiceDates(billingDate(2021, 3, 1), billingDate(2021, 7, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []time.Time{billingDate(2021, 3, 31), billingDate(2021, 4, 30), billingDate(2021, 5, 31), billingDate(2021, 6, 30)}
+	if len(got) != len(expected) {
+		t.Fatalf("Testing invoice dates. Expected %v; got %v", expected, got)
+	}
+
+	for i := range expected {
+		if !got[i].Equal(expected[i]) {
+			t.Errorf("Testing invoice date %d. Expected %v; got %v", i, expected[i], got[i])
+		}
+	}
+
+	got, _ = bs.InvoiceDates(billingDate(2020, 1, 1), billingDate(2021, 3, 1))
+	if len(got) != 2 || !got[0].Equal(bs.Start) {
+		t.Errorf("Testing invoice dates from before the start. Expected %v and Feb; got %v", bs.Start, got)
+	}
+
+	if _, err := (BillingSchedule{Start: bs.Start}).InvoiceDates(bs.Start, billingDate(2022, 1, 1)); !errors.Is(err, ErrInvalidBillingInterval) {
+		t.Errorf("Testing invalid interval. Expected %v; got %v", ErrInvalidBillingInterval, err)
+	}
+}
+
+func TestBillingScheduleProrate(t *testing.T) {
+	tests := []struct {
+		name                        string
+		schedule                    BillingSchedule
+		changeDate                  time.Time
+		oldPrice, newPrice          Cents
+		expectedDaysRemaining       int
+		expectedCredit, expectedNet Cents
+	}{
+		{
+			name:                  "upgrade half way through April",
+			schedule:              NewBillingSchedule(billingDate(2021, 4, 1), BillingMonthly),
+			changeDate:            billingDate(2021, 4, 16),
+			oldPrice:              3000,
+			newPrice:              6000,
+			expectedDaysRemaining: 15,
+			expectedCredit:        1500,
+			expectedNet:           1500,
+		},
+		{
+			name:                  "upgrade in January rounds each part",
+			schedule:              NewBillingSchedule(billingDate(2020, 1, 1), BillingMonthly),
+			changeDate:            time.Date(2021, 1, 11, 18, 30, 0, 0, time.UTC),
+			oldPrice:              999,
+			newPrice:              1999,
+			expectedDaysRemaining: 21,
+			expectedCredit:        677,
+			expectedNet:           1354 - 677,
+		},
+		{
+			name:                  "downgrade in February",
+			schedule:              NewBillingSchedule(billingDate(2021, 1, 31), BillingMonthly),
+			changeDate:            billingDate(2021, 3, 24),
+			oldPrice:              6200,
+			newPrice:              3100,
+			expectedDaysRemaining: 7,
+			expectedCredit:        1400,
+			expectedNet:           -700,
+		},
+		{
+			name:                  "annual plan in a leap year",
+			schedule:              NewBillingSchedule(billingDate(2020, 1, 1), BillingAnnual),
+			changeDate:            billingDate(2020, 12, 1),
+			oldPrice:              36600,
+			newPrice:              73200,
+			expectedDaysRemaining: 31,
+			expectedCredit:        3100,
+			expectedNet:           3100,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := test.schedule.Prorate(test.changeDate, test.oldPrice, test.newPrice, RoundHalfUp)
+		if err != nil {
+			t.Errorf("Testing %s. Unexpected error %v", test.name, err)
+			continue
+		}
+
+		if got.DaysRemaining != test.expectedDaysRemaining || got.Credit != test.expectedCredit || got.Net() != test.expectedNet {
+			t.Errorf("Testing %s. Expected %v days, %v credit and %v net; got %v, %v and %v", test.name,
+				test.expectedDaysRemaining, test.expectedCredit, test.expectedNet, got.DaysRemaining, got.Credit, got.Net())
+		}
+	}
+
+	if _, err := NewBillingSchedule(billingDate(2021, 1, 1), BillingMonthly).Prorate(billingDate(2020, 12, 1), 100, 200, RoundHalfUp); !errors.Is(err, ErrOutsideBillingSchedule) {
+		t.Errorf("Testing change before the start. Expected %v; got %v", ErrOutsideBillingSchedule, err)
+	}
+
+	if got := Cents(100).Prorate(1, 0, RoundHalfUp); got != 0 {
+		t.Errorf("Testing prorate with no days. Expected %v; got %v", 0, got)
+	}
+}
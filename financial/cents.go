@@ -12,13 +12,8 @@ import (
 
 type Cents int
 
-func percentageToDecimal(pc float64) float64 {
-	return pc / 100
-}
-
-func percentageToDecimalIncrementer(pc float64) float64 {
-	return 1 + percentageToDecimal(pc)
-}
+// maxCents is the largest amount that fits in a Cents on this platform
+const maxCents = Cents(^uint(0) >> 1)
 
 func (c Cents) LimitTo(n Cents) Cents {
 	if c > n {
@@ -36,24 +31,28 @@ func (c Cents) Min(n Cents) Cents {
 	return c
 }
 
+// ByQty saturates rather than overflowing.  Use MulQty to find out if it did.
 func (c Cents) ByQty(qty int) Cents {
-	return c * Cents(qty)
+	return c.SaturatingMulQty(qty)
 }
 
+// DivideByQty, ByFloat, ByPercentage, RemovePercentage and RemoveTaxableSurcharge
+// all round halves away from zero.  There are ...Rounded versions of each to choose another RoundingMode.
+
 func (c Cents) DivideByQty(qty int) Cents {
-	return Cents(math.Round(float64(c) / float64(qty)))
+	return c.DivideByQtyRounded(qty, RoundHalfUp)
 }
 
 func (c Cents) ByFloat(multiplier float64) Cents {
-	return Cents(math.Round(float64(c) * multiplier))
+	return c.ByFloatRounded(multiplier, RoundHalfUp)
 }
 
 func (c Cents) ByPercentage(pc float64) Cents {
-	return c.ByFloat(percentageToDecimal(pc))
+	return c.ByPercentageRounded(pc, RoundHalfUp)
 }
 
 func (c Cents) RemovePercentage(pc float64) Cents {
-	return c.ByFloat(1 / percentageToDecimalIncrementer(pc))
+	return c.RemovePercentageRounded(pc, RoundHalfUp)
 }
 
 func (c Cents) CalcPercentageDiscount(pc float64) Cents {
@@ -62,12 +61,7 @@ func (c Cents) CalcPercentageDiscount(pc float64) Cents {
 
 // RemoveTaxableSurcharge works backwards from a net total that might include a surcharge which might itself be taxable
 func (c Cents) RemoveTaxableSurcharge(surchargePercentage, taxPercentage float64) Cents {
-	if surchargePercentage == 0 {
-		return c
-	}
-
-	return c.RemovePercentage(surchargePercentage * percentageToDecimalIncrementer(taxPercentage))
-
+	return c.RemoveTaxableSurchargeRounded(surchargePercentage, taxPercentage, RoundHalfUp)
 }
 
 func (c Cents) AddTax(taxPercentage float64) TaxCalc {
@@ -109,6 +103,8 @@ func (c Cents) SplitHundredths() (Cents, Cents) {
 	return remainder, c - remainder
 }
 
+// RoundToNearestPretty snaps up to the target ending within the next pound, e.g. 99 for .99.
+// PrettyPriceRule does the same with other steps and rounding directions.
 func (c Cents) RoundToNearestPretty(target Cents) Cents {
 	// if the target is greater than 100,
 	// we'll take the modulus by 100
@@ -129,29 +125,31 @@ func (c Cents) RoundToNearestPretty(target Cents) Cents {
 	return base + target
 }
 
-func (c Cents) FormatAsPrice() (res string) {
-	// If the number is negative, we'll convert it to positive for processing
-	// and readd back in the sign right at the end
+func (c Cents) FormatAsPrice() string {
+	return formatMinorUnits(c, defaultCurrencyExponent)
+}
+
+// formatMinorUnits formats an amount in minor units as a plain decimal string
+// with the given number of decimal places, e.g. 123456 with exponent 2 is "1234.56"
+func formatMinorUnits(c Cents, exponent int) (res string) {
+	// Format as int64 and strip the sign rather than negating the Cents,
+	// so that the smallest possible int doesn't overflow
+	s := strconv.FormatInt(int64(c), 10)
 	isNegative := c < 0
 	if isNegative {
-		c = -c
+		s = s[1:]
 	}
 
-	s := strconv.Itoa(int(c))
-
-	l := len(s)
+	if exponent <= 0 {
+		res = s
+	} else {
+		// Left pad with zeros so there is always at least one digit before the point
+		if len(s) <= exponent {
+			s = strings.Repeat("0", exponent-len(s)+1) + s
+		}
 
-	switch l {
-	case 0:
-		res = "0.00"
-	case 1:
-		res = "0.0" + s
-	case 2:
-		res = "0." + s
-	default:
-		prefix := s[:l-2]
-		suffix := s[l-2:]
-		res = strings.Join([]string{prefix, suffix}, ".")
+		l := len(s)
+		res = strings.Join([]string{s[:l-exponent], s[l-exponent:]}, ".")
 	}
 
 	if isNegative {
@@ -169,21 +167,21 @@ func FromFloatPrice(f float64) Cents {
 	return Cents(math.Round(100 * f))
 }
 
+// ParseCentsFromPriceString parses a machine formatted price such as "1234.56".
+// We need this to deal with either "." or "," as unit separator
+// but we will NOT allow thousands separators here (as they are a human thing, not a machine thing).
+// Use Locale.ParsePrice for those.  Errors are always a *PriceParseError.
 func ParseCentsFromPriceString(s string) (Cents, error) {
-	// We need this to deal with either "." or "," as unit separator
-	// but we will NOT allow thousands separators here (as they are a human thing, not a machine thing)
-	s = strings.Replace(s, ",", ".", 1)
+	return parsePrice(s, Locale{}, "")
+}
 
-	f, err := strconv.ParseFloat(s, 64)
+// MustParseCentsFromPriceString panics if the price can't be parsed
+func MustParseCentsFromPriceString(s string) Cents {
+	cents, err := ParseCentsFromPriceString(s)
 	if err != nil {
-		return 0, err
+		panic(err)
 	}
 
-	return FromFloatPrice(f), nil
-}
-
-func MustParseCentsFromPriceString(s string) Cents {
-	cents, _ := ParseCentsFromPriceString(s)
 	return cents
 }
 
@@ -240,7 +238,9 @@ func (cd CentDict) IsZero() bool {
 	return true
 }
 
-// Average will calculate the average of EACH KEY using a single divisor
+// Average will calculate the average of EACH KEY using a single divisor.
+// Every key is averaged, including zero and negative values.  For more than
+// an average, see the stats package.
 func (cd CentDict) Average(n int) CentDict {
 	if n == 0 || n == 1 {
 		return cd
@@ -248,9 +248,7 @@ func (cd CentDict) Average(n int) CentDict {
 
 	res := CentDict{}
 	for k, v := range cd {
-		if v > 0 {
-			res[k] = v.DivideByQty(n)
-		}
+		res[k] = v.DivideByQty(n)
 	}
 
 	return res
@@ -327,23 +325,15 @@ func (cd CentDict) Invert() {
 	}
 }
 
-// AddToKey and MergeWith cannot be called on nil maps
-// I can't find a way to deal neatly with this problem
-// as trying to initialise the map in the function call
-// causes the initial reference to be lost so the caller
-// doesn't see the results of the function.
-// Perhaps there's a way to initalise the map in place using
-// some reflect code, but for now, just be sure
-// NOT TO CALL THESE ON NIL MAPS
+// AddToKey and MergeWith cannot be called on nil maps, as there is no way to initialise
+// the map in place through a value receiver, so the caller wouldn't see the results.
+// Use Add and Merge, which take a pointer and initialise a nil map first, wherever the
+// CentDict might not have been made yet.
+// They also saturate rather than overflowing; use AddToKeyChecked and MergeWithChecked
+// to find out if they did.
 
 func (cd CentDict) AddToKey(k string, amount Cents) {
-	// TODO: nil
-
-	if existing, ok := cd[k]; ok {
-		cd[k] = existing + amount
-	} else {
-		cd[k] = amount
-	}
+	cd[k] = cd[k].SaturatingAdd(amount)
 }
 
 func (cd CentDict) MergeWith(incoming CentDict) {
@@ -353,12 +343,37 @@ func (cd CentDict) MergeWith(incoming CentDict) {
 	}
 
 	for k, amount := range incoming {
-		if existing, ok := cd[k]; ok {
-			cd[k] = existing + amount
-		} else {
-			cd[k] = amount
-		}
+		cd[k] = cd[k].SaturatingAdd(amount)
+	}
+}
+
+// Add is AddToKey, but safe to call on a nil CentDict, which it will initialise
+func (cd *CentDict) Add(k string, amount Cents) {
+	if *cd == nil {
+		*cd = CentDict{}
+	}
+
+	cd.AddToKey(k, amount)
+}
+
+// Merge is MergeWith, but safe to call on a nil CentDict, which it will initialise
+func (cd *CentDict) Merge(incoming CentDict) {
+	if *cd == nil {
+		*cd = CentDict{}
 	}
+
+	cd.MergeWith(incoming)
+}
+
+// Copy returns a new CentDict with the same values, so that it can be changed
+// without affecting the original.  The copy of a nil CentDict is an empty one.
+func (cd CentDict) Copy() CentDict {
+	res := make(CentDict, len(cd))
+	for k, v := range cd {
+		res[k] = v
+	}
+
+	return res
 }
 
 // KeyedCentDict is a map of maps: a string keyed map -> CentDict
@@ -263,6 +263,36 @@ func TestMergeWith(t *testing.T) {
 	}
 }
 
+func TestCentDictAverage(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      CentDict
+		n        int
+		expected CentDict
+	}{
+		{
+			name:     "positive",
+			src:      CentDict{"GBP": 1000, "EUR": 501},
+			n:        2,
+			expected: CentDict{"GBP": 500, "EUR": 251},
+		},
+		{
+			name:     "negative and zero are kept",
+			src:      CentDict{"GBP": -1000, "EUR": 0},
+			n:        4,
+			expected: CentDict{"GBP": -250, "EUR": 0},
+		},
+	}
+
+	for _, test := range tests {
+		got := test.src.Average(test.n)
+
+		if !got.Compare(test.expected) {
+			t.Errorf("Testing %s.  Comparison failed. Expected %v; got %v", test.name, test.expected, got)
+		}
+	}
+}
+
 func TestRemoveTaxableSurcharge(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -0,0 +1,159 @@
+package financial
+
+import (
+	"errors"
+	"math/big"
+)
+
+// Cents is an int, so on 32 bit builds it overflows at about 21 million in currency, and even on
+// 64 bit builds a large enough qty or percentage will do it.  Plain Go arithmetic wraps around
+// without any signal, so these check for it.  The checked versions return ErrOverflow along with
+// the saturated result, and the saturating versions just stop at the largest or smallest amount.
+
+var ErrOverflow = errors.New("amount overflows Cents")
+
+// minCents is the smallest amount that fits in a Cents on this platform
+const minCents = -maxCents - 1
+
+var (
+	maxCentsBig = big.NewInt(int64(maxCents))
+	minCentsBig = big.NewInt(int64(minCents))
+)
+
+// saturate is the limit on the side the result was heading
+func saturate(positive bool) Cents {
+	if positive {
+		return maxCents
+	}
+
+	return minCents
+}
+
+func (c Cents) Add(n Cents) (Cents, error) {
+	res := c + n
+	if (n > 0 && res < c) || (n < 0 && res > c) {
+		return saturate(n > 0), ErrOverflow
+	}
+
+	return res, nil
+}
+
+func (c Cents) Sub(n Cents) (Cents, error) {
+	res := c - n
+	if (n < 0 && res < c) || (n > 0 && res > c) {
+		return saturate(n < 0), ErrOverflow
+	}
+
+	return res, nil
+}
+
+// MulQty is a checked ByQty
+func (c Cents) MulQty(qty int) (Cents, error) {
+	if c == 0 || qty == 0 {
+		return 0, nil
+	}
+
+	res := c * Cents(qty)
+
+	// Dividing back is the usual check, but minCents / -1 overflows back to minCents itself
+	if res/Cents(qty) != c || (qty == -1 && c == minCents) {
+		return saturate((c > 0) == (qty > 0)), ErrOverflow
+	}
+
+	return res, nil
+}
+
+// Mul is a checked ByFloat, rounding halves away from zero
+func (c Cents) Mul(multiplier float64) (Cents, error) {
+	return c.MulRounded(multiplier, RoundHalfUp)
+}
+
+func (c Cents) MulRounded(multiplier float64, mode RoundingMode) (Cents, error) {
+	return roundRatChecked(new(big.Rat).Mul(centsRat(c), decimalRat(multiplier)), mode)
+}
+
+func (c Cents) SaturatingAdd(n Cents) Cents {
+	res, _ := c.Add(n)
+	return res
+}
+
+func (c Cents) SaturatingSub(n Cents) Cents {
+	res, _ := c.Sub(n)
+	return res
+}
+
+func (c Cents) SaturatingMulQty(qty int) Cents {
+	res, _ := c.MulQty(qty)
+	return res
+}
+
+func (c Cents) SaturatingMul(multiplier float64) Cents {
+	res, _ := c.Mul(multiplier)
+	return res
+}
+
+// centsFromBigInt converts an exact whole amount, saturating if it doesn't fit
+func centsFromBigInt(i *big.Int) (Cents, error) {
+	if i.Cmp(maxCentsBig) > 0 {
+		return maxCents, ErrOverflow
+	}
+
+	if i.Cmp(minCentsBig) < 0 {
+		return minCents, ErrOverflow
+	}
+
+	return Cents(i.Int64()), nil
+}
+
+// overflowCheck remembers the first overflow in a longer calculation, such as a TaxCalc,
+// so that the calculation can carry on with saturated amounts and report the overflow at the end.
+// A nil overflowCheck just saturates.
+type overflowCheck struct {
+	err error
+}
+
+func (oc *overflowCheck) check(c Cents, err error) Cents {
+	if oc != nil && oc.err == nil && err != nil {
+		oc.err = err
+	}
+
+	return c
+}
+
+func (oc *overflowCheck) add(a, b Cents) Cents {
+	return oc.check(a.Add(b))
+}
+
+func (oc *overflowCheck) sub(a, b Cents) Cents {
+	return oc.check(a.Sub(b))
+}
+
+func (oc *overflowCheck) mulQty(c Cents, qty int) Cents {
+	return oc.check(c.MulQty(qty))
+}
+
+func (oc *overflowCheck) byPercentage(c Cents, pc float64, mode RoundingMode) Cents {
+	return oc.check(c.byPercentageChecked(pc, mode))
+}
+
+func (oc *overflowCheck) removePercentage(c Cents, pc float64, mode RoundingMode) Cents {
+	return oc.check(c.removePercentageChecked(pc, mode))
+}
+
+// AddToKeyChecked is AddToKey, but returns ErrOverflow rather than saturating
+func (cd CentDict) AddToKeyChecked(k string, amount Cents) error {
+	res, err := cd[k].Add(amount)
+	cd[k] = res
+	return err
+}
+
+// MergeWithChecked is MergeWith, but returns ErrOverflow if any of the keys overflow.
+// All of the keys are still merged, with any that overflowed saturated.
+func (cd CentDict) MergeWithChecked(incoming CentDict) error {
+	var oc overflowCheck
+	for k, amount := range incoming {
+		cd[k] = oc.add(cd[k], amount)
+	}
+
+	return oc.err
+}
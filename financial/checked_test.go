@@ -0,0 +1,200 @@
+package financial
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"testing/quick"
+)
+
+// expectedChecked is what a checked operation should give for an exact result
+func expectedChecked(exact *big.Int) (Cents, bool) {
+	if exact.Cmp(maxCentsBig) > 0 {
+		return maxCents, false
+	}
+	if exact.Cmp(minCentsBig) < 0 {
+		return minCents, false
+	}
+
+	return Cents(exact.Int64()), true
+}
+
+func checkAgainstBig(t *testing.T, name string, res Cents, err error, exact *big.Int) bool {
+	expected, ok := expectedChecked(exact)
+	if res != expected || ok != (err == nil) {
+		t.Errorf("Testing %s. Expected %v (ok %v); got %v (%v)", name, expected, ok, res, err)
+		return false
+	}
+
+	if err != nil && !errors.Is(err, ErrOverflow) {
+		t.Errorf("Testing %s. Expected ErrOverflow; got %v", name, err)
+		return false
+	}
+
+	return true
+}
+
+var edgeCents = []Cents{0, 1, -1, 2, -2, maxCents, maxCents - 1, minCents, minCents + 1, maxCents / 2, minCents / 2}
+
+func TestCheckedEdgeValues(t *testing.T) {
+	for _, a := range edgeCents {
+		for _, b := range edgeCents {
+			bigA, bigB := big.NewInt(int64(a)), big.NewInt(int64(b))
+
+			res, err := a.Add(b)
+			checkAgainstBig(t, "Add", res, err, new(big.Int).Add(bigA, bigB))
+
+			res, err = a.Sub(b)
+			checkAgainstBig(t, "Sub", res, err, new(big.Int).Sub(bigA, bigB))
+
+			res, err = a.MulQty(int(b))
+			checkAgainstBig(t, "MulQty", res, err, new(big.Int).Mul(bigA, bigB))
+		}
+	}
+}
+
+func TestCheckedProperties(t *testing.T) {
+	add := func(a, b Cents) bool {
+		res, err := a.Add(b)
+		return checkAgainstBig(t, "Add", res, err, new(big.Int).Add(big.NewInt(int64(a)), big.NewInt(int64(b))))
+	}
+
+	sub := func(a, b Cents) bool {
+		res, err := a.Sub(b)
+		return checkAgainstBig(t, "Sub", res, err, new(big.Int).Sub(big.NewInt(int64(a)), big.NewInt(int64(b))))
+	}
+
+	mulQty := func(a Cents, qty int) bool {
+		res, err := a.MulQty(qty)
+		return checkAgainstBig(t, "MulQty", res, err, new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(qty))))
+	}
+
+	// Small quantities, which are the usual case, and mustn't overflow until the amount is large
+	mulSmallQty := func(a Cents, qty int16) bool {
+		res, err := a.MulQty(int(qty))
+		return checkAgainstBig(t, "MulQty small", res, err, new(big.Int).Mul(big.NewInt(int64(a)), big.NewInt(int64(qty))))
+	}
+
+	saturating := func(a, b Cents) bool {
+		added, _ := a.Add(b)
+		subtracted, _ := a.Sub(b)
+		return a.SaturatingAdd(b) == added && a.SaturatingSub(b) == subtracted
+	}
+
+	for name, f := range map[string]interface{}{
+		"add":         add,
+		"sub":         sub,
+		"mulQty":      mulQty,
+		"mulSmallQty": mulSmallQty,
+		"saturating":  saturating,
+	} {
+		if err := quick.Check(f, &quick.Config{MaxCount: 5000}); err != nil {
+			t.Errorf("Testing %s. %s", name, err)
+		}
+	}
+}
+
+func TestCheckedMul(t *testing.T) {
+	tests := []struct {
+		name       string
+		c          Cents
+		multiplier float64
+		expected   Cents
+		expectErr  bool
+	}{
+		{"simple", 1000, 1.5, 1500, false},
+		{"rounds half up", 5, 0.5, 3, false},
+		{"negative", -5, 0.5, -3, false},
+		{"overflow", maxCents, 2, maxCents, true},
+		{"negative overflow", maxCents, -2, minCents, true},
+		{"shrinking the largest is fine", maxCents, 0.5, maxCents/2 + 1, false},
+	}
+
+	for _, test := range tests {
+		res, err := test.c.Mul(test.multiplier)
+		if res != test.expected || (err != nil) != test.expectErr {
+			t.Errorf("Testing %s. Expected %v (error %v); got %v (%v)", test.name, test.expected, test.expectErr, res, err)
+		}
+
+		if test.c.SaturatingMul(test.multiplier) != test.expected {
+			t.Errorf("Testing %s saturating. Expected %v; got %v", test.name, test.expected, test.c.SaturatingMul(test.multiplier))
+		}
+	}
+}
+
+func TestTaxCalcOverflow(t *testing.T) {
+	tests := []struct {
+		name      string
+		tx        TaxCalc
+		remove    bool
+		expectErr bool
+	}{
+		{"normal", TaxCalc{UnitEx: 1000, LineQty: 3, TaxPercentage: 20}, false, false},
+		{"huge qty", TaxCalc{UnitEx: 1000, LineQty: int(maxCents / 100), TaxPercentage: 20}, false, true},
+		{"huge qty unit method", TaxCalc{RoundingMethod: TaxRoundingMethodUnit, UnitEx: 1000, LineQty: int(maxCents / 100), TaxPercentage: 20}, false, true},
+		{"tax pushes inc over", TaxCalc{UnitEx: maxCents - 10, LineQty: 1, TaxPercentage: 20}, false, true},
+		{"components", TaxCalc{UnitEx: maxCents / 2, LineQty: 3, Components: britishColumbia}, false, true},
+		{"remove is fine with a large inc", TaxCalc{LineInc: maxCents, LineQty: 1, TaxPercentage: 20}, true, false},
+		{"remove with a negative rate", TaxCalc{LineInc: maxCents, LineQty: 1, TaxPercentage: -50}, true, true},
+	}
+
+	for _, test := range tests {
+		saturated := test.tx
+		var err error
+		if test.remove {
+			err = test.tx.RemoveTaxChecked()
+			saturated.RemoveTax()
+		} else {
+			err = test.tx.AddTaxChecked()
+			saturated.AddTax()
+		}
+
+		if test.expectErr && !errors.Is(err, ErrOverflow) {
+			t.Errorf("Testing %s. Expected ErrOverflow; got %v", test.name, err)
+		}
+		if !test.expectErr && err != nil {
+			t.Errorf("Testing %s. Unexpected error: %s", test.name, err)
+		}
+
+		// The unchecked version gives the same saturated amounts, rather than wrapping round
+		if saturated.LineEx != test.tx.LineEx || saturated.LineTax != test.tx.LineTax || saturated.LineInc != test.tx.LineInc {
+			t.Errorf("Testing %s. Expected the same amounts checked and unchecked; got %v and %v", test.name, test.tx, saturated)
+		}
+
+		if test.expectErr && !test.remove && test.tx.LineInc < 0 {
+			t.Errorf("Testing %s. Expected the inc to saturate, not wrap round; got %v", test.name, test.tx.LineInc)
+		}
+	}
+}
+
+func TestCentDictChecked(t *testing.T) {
+	cd := CentDict{"GBP": maxCents - 1}
+	if err := cd.AddToKeyChecked("GBP", 1); err != nil || cd["GBP"] != maxCents {
+		t.Errorf("Testing AddToKeyChecked. Expected %v; got %v (%v)", maxCents, cd["GBP"], err)
+	}
+
+	if err := cd.AddToKeyChecked("GBP", 1); !errors.Is(err, ErrOverflow) || cd["GBP"] != maxCents {
+		t.Errorf("Testing AddToKeyChecked overflow. Expected %v and ErrOverflow; got %v (%v)", maxCents, cd["GBP"], err)
+	}
+
+	cd.AddToKey("GBP", 1)
+	if cd["GBP"] != maxCents {
+		t.Errorf("Testing AddToKey saturates. Expected %v; got %v", maxCents, cd["GBP"])
+	}
+
+	err := cd.MergeWithChecked(CentDict{"GBP": 5, "EUR": 5})
+	if !errors.Is(err, ErrOverflow) || cd["EUR"] != 5 {
+		t.Errorf("Testing MergeWithChecked. Expected ErrOverflow with EUR merged; got %v (%v)", cd, err)
+	}
+
+	kcd := KeyedCentDict{}
+	kcd.AddToKey("SKU", "GBP", minCents)
+	kcd.AddToKey("SKU", "GBP", -1)
+	if kcd["SKU"]["GBP"] != minCents {
+		t.Errorf("Testing KeyedCentDict saturates. Expected %v; got %v", minCents, kcd["SKU"]["GBP"])
+	}
+
+	if Cents(maxCents).ByQty(2) != maxCents || Cents(maxCents).ByQty(-2) != minCents {
+		t.Errorf("Testing ByQty saturates. Got %v and %v", Cents(maxCents).ByQty(2), Cents(maxCents).ByQty(-2))
+	}
+}
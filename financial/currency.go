@@ -0,0 +1,101 @@
+package financial
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Currency describes an ISO 4217 currency and the number of digits
+// after the decimal point in its minor unit (its 'exponent').
+// Cents is always held in the minor unit, so for JPY (exponent 0) 1 Cent is 1 Yen,
+// and for KWD (exponent 3) 1000 Cents is 1 Dinar.
+type Currency struct {
+	Code     string
+	Exponent int
+}
+
+const defaultCurrencyExponent = 2
+
+// currencies is the list of ISO 4217 currencies we know about.  Anything with an
+// exponent of 2 doesn't strictly need to be here, but it means we can reject
+// codes that are clearly junk.
+var currencies = map[string]Currency{
+	"AED": {"AED", 2},
+	"ARS": {"ARS", 2},
+	"AUD": {"AUD", 2},
+	"BGN": {"BGN", 2},
+	"BHD": {"BHD", 3},
+	"BIF": {"BIF", 0},
+	"BRL": {"BRL", 2},
+	"CAD": {"CAD", 2},
+	"CHF": {"CHF", 2},
+	"CLF": {"CLF", 4},
+	"CLP": {"CLP", 0},
+	"CNY": {"CNY", 2},
+	"CZK": {"CZK", 2},
+	"DJF": {"DJF", 0},
+	"DKK": {"DKK", 2},
+	"EUR": {"EUR", 2},
+	"GBP": {"GBP", 2},
+	"GNF": {"GNF", 0},
+	"HKD": {"HKD", 2},
+	"HUF": {"HUF", 2},
+	"IDR": {"IDR", 2},
+	"ILS": {"ILS", 2},
+	"INR": {"INR", 2},
+	"IQD": {"IQD", 3},
+	"ISK": {"ISK", 0},
+	"JOD": {"JOD", 3},
+	"JPY": {"JPY", 0},
+	"KMF": {"KMF", 0},
+	"KRW": {"KRW", 0},
+	"KWD": {"KWD", 3},
+	"LYD": {"LYD", 3},
+	"MXN": {"MXN", 2},
+	"MYR": {"MYR", 2},
+	"NOK": {"NOK", 2},
+	"NZD": {"NZD", 2},
+	"OMR": {"OMR", 3},
+	"PHP": {"PHP", 2},
+	"PLN": {"PLN", 2},
+	"PYG": {"PYG", 0},
+	"RON": {"RON", 2},
+	"RWF": {"RWF", 0},
+	"SAR": {"SAR", 2},
+	"SEK": {"SEK", 2},
+	"SGD": {"SGD", 2},
+	"THB": {"THB", 2},
+	"TND": {"TND", 3},
+	"TRY": {"TRY", 2},
+	"TWD": {"TWD", 2},
+	"UGX": {"UGX", 0},
+	"USD": {"USD", 2},
+	"UYI": {"UYI", 0},
+	"UYW": {"UYW", 4},
+	"VND": {"VND", 0},
+	"VUV": {"VUV", 0},
+	"XAF": {"XAF", 0},
+	"XOF": {"XOF", 0},
+	"XPF": {"XPF", 0},
+	"ZAR": {"ZAR", 2},
+}
+
+// LookupCurrency finds a currency by its ISO 4217 code.  The code is case insensitive.
+func LookupCurrency(code string) (Currency, error) {
+	c, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, fmt.Errorf("unknown currency code: %q", code)
+	}
+
+	return c, nil
+}
+
+// CurrencyExponent returns the minor unit exponent for a currency code,
+// falling back to 2 for codes we don't know about
+func CurrencyExponent(code string) int {
+	if c, ok := currencies[strings.ToUpper(code)]; ok {
+		return c.Exponent
+	}
+
+	return defaultCurrencyExponent
+}
@@ -0,0 +1,156 @@
+package financial
+
+import (
+	"math/big"
+	"strconv"
+)
+
+// RoundingMode decides which way an amount that falls between two whole cents goes.
+// Not to be confused with TaxRoundingMethod, which decides *when* tax is rounded.
+type RoundingMode uint
+
+const (
+	// RoundHalfUp rounds halves away from zero, so 0.5 -> 1 and -0.5 -> -1.
+	// This is the same as math.Round, which is what we have always used, so it is the default.
+	RoundHalfUp RoundingMode = iota
+	// RoundHalfEven rounds halves to the nearest even number, also known as banker's rounding
+	RoundHalfEven
+	// RoundHalfDown rounds halves towards zero
+	RoundHalfDown
+	// RoundFloor always rounds down, towards negative infinity
+	RoundFloor
+	// RoundCeiling always rounds up, towards positive infinity
+	RoundCeiling
+)
+
+// Percentages and multipliers arrive as float64, but what people mean by 7.7 or 8.875 is the
+// decimal, not the nearest binary float.  So all of the arithmetic is done with big.Rat, starting
+// from the shortest decimal that represents the float, which is exactly what was typed.
+
+// decimalRat converts a float to the exact decimal it was written as.  NaN and infinities are zero.
+func decimalRat(f float64) *big.Rat {
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'f', -1, 64))
+	if !ok {
+		return new(big.Rat)
+	}
+
+	return r
+}
+
+var hundred = big.NewRat(100, 1)
+
+// percentageRat is the exact decimal multiplier for a percentage, e.g. 17.5 -> 0.175
+func percentageRat(pc float64) *big.Rat {
+	return new(big.Rat).Quo(decimalRat(pc), hundred)
+}
+
+// percentageIncrementerRat is the exact multiplier to add a percentage, e.g. 17.5 -> 1.175
+func percentageIncrementerRat(pc float64) *big.Rat {
+	return new(big.Rat).Add(big.NewRat(1, 1), percentageRat(pc))
+}
+
+func centsRat(c Cents) *big.Rat {
+	return new(big.Rat).SetInt64(int64(c))
+}
+
+// ratFloat converts back to a float, for the few places that need to return one
+func ratFloat(r *big.Rat) float64 {
+	f, _ := r.Float64()
+	return f
+}
+
+// roundRat rounds an exact amount to whole cents, saturating if it doesn't fit
+func roundRat(r *big.Rat, mode RoundingMode) Cents {
+	res, _ := roundRatChecked(r, mode)
+	return res
+}
+
+// roundRatChecked rounds an exact amount to whole cents, returning ErrOverflow if it doesn't fit
+func roundRatChecked(r *big.Rat, mode RoundingMode) (Cents, error) {
+	q, m := new(big.Int).QuoRem(r.Num(), r.Denom(), new(big.Int))
+	if m.Sign() == 0 {
+		return centsFromBigInt(q)
+	}
+
+	sign := r.Sign()
+
+	// Compare twice the remainder to the denominator to see if we are below, at or above a half
+	twice := new(big.Int).Abs(m)
+	twice.Lsh(twice, 1)
+	half := twice.Cmp(r.Denom())
+
+	awayFromZero := false
+	switch mode {
+	case RoundFloor:
+		awayFromZero = sign < 0
+	case RoundCeiling:
+		awayFromZero = sign > 0
+	case RoundHalfDown:
+		awayFromZero = half > 0
+	case RoundHalfEven:
+		awayFromZero = half > 0 || (half == 0 && q.Bit(0) == 1)
+	default:
+		awayFromZero = half >= 0
+	}
+
+	if awayFromZero {
+		q.Add(q, big.NewInt(int64(sign)))
+	}
+
+	return centsFromBigInt(q)
+}
+
+// ByFloatRounded multiplies by the exact decimal value of the multiplier
+func (c Cents) ByFloatRounded(multiplier float64, mode RoundingMode) Cents {
+	return roundRat(new(big.Rat).Mul(centsRat(c), decimalRat(multiplier)), mode)
+}
+
+func (c Cents) ByPercentageRounded(pc float64, mode RoundingMode) Cents {
+	res, _ := c.byPercentageChecked(pc, mode)
+	return res
+}
+
+func (c Cents) byPercentageChecked(pc float64, mode RoundingMode) (Cents, error) {
+	return roundRatChecked(new(big.Rat).Mul(centsRat(c), percentageRat(pc)), mode)
+}
+
+// RemovePercentageRounded works back from an amount that has had a percentage added
+func (c Cents) RemovePercentageRounded(pc float64, mode RoundingMode) Cents {
+	res, _ := c.removePercentageChecked(pc, mode)
+	return res
+}
+
+func (c Cents) removePercentageChecked(pc float64, mode RoundingMode) (Cents, error) {
+	return divideRoundedChecked(centsRat(c), percentageIncrementerRat(pc), mode)
+}
+
+func (c Cents) DivideByQtyRounded(qty int, mode RoundingMode) Cents {
+	return divideRounded(centsRat(c), new(big.Rat).SetInt64(int64(qty)), mode)
+}
+
+// RemoveTaxableSurchargeRounded works backwards from a net total that might include a surcharge which might itself be taxable
+func (c Cents) RemoveTaxableSurchargeRounded(surchargePercentage, taxPercentage float64, mode RoundingMode) Cents {
+	if surchargePercentage == 0 {
+		return c
+	}
+
+	// net = amount * (1 + surcharge% * (1 + tax%))
+	divisor := new(big.Rat).Mul(percentageRat(surchargePercentage), percentageIncrementerRat(taxPercentage))
+	divisor.Add(divisor, big.NewRat(1, 1))
+
+	return divideRounded(centsRat(c), divisor, mode)
+}
+
+// divideRounded divides and rounds, treating division by zero as zero rather than panicking
+func divideRounded(a, b *big.Rat, mode RoundingMode) Cents {
+	res, _ := divideRoundedChecked(a, b, mode)
+	return res
+}
+
+func divideRoundedChecked(a, b *big.Rat, mode RoundingMode) (Cents, error) {
+	if b.Sign() == 0 {
+		return 0, nil
+	}
+
+	return roundRatChecked(new(big.Rat).Quo(a, b), mode)
+}
@@ -0,0 +1,143 @@
+package financial
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRoundingModes(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        Cents
+		pc       float64
+		mode     RoundingMode
+		expected Cents
+	}{
+		// 180 * 17.5% is exactly 31.5, but as a float it is 31.499999999999996
+		{"half up, no float drift", 180, 17.5, RoundHalfUp, 32},
+		{"half even, up to even", 180, 17.5, RoundHalfEven, 32},
+		{"half even, down to even", 100, 12.5, RoundHalfEven, 12},
+		{"half even, 13.5", 300, 4.5, RoundHalfEven, 14},
+		{"half down", 180, 17.5, RoundHalfDown, 31},
+		{"floor", 199, 10, RoundFloor, 19},
+		{"ceiling", 191, 10, RoundCeiling, 20},
+		{"exact is never rounded", 200, 10, RoundCeiling, 20},
+
+		{"negative half up", -180, 17.5, RoundHalfUp, -32},
+		{"negative half down", -180, 17.5, RoundHalfDown, -31},
+		{"negative half even", -100, 12.5, RoundHalfEven, -12},
+		{"negative floor", -191, 10, RoundFloor, -20},
+		{"negative ceiling", -199, 10, RoundCeiling, -19},
+
+		{"7.7%", 500, 7.7, RoundHalfUp, 39},
+		{"7.7% banker's", 500, 7.7, RoundHalfEven, 38},
+		{"8.875%", 400, 8.875, RoundHalfEven, 36},
+		{"8.875% floor", 400, 8.875, RoundFloor, 35},
+		{"NaN is zero", 400, math.NaN(), RoundHalfUp, 0},
+	}
+
+	for _, test := range tests {
+		res := test.c.ByPercentageRounded(test.pc, test.mode)
+		if res != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+	}
+}
+
+func TestRemovePercentageRounded(t *testing.T) {
+	tests := []struct {
+		name     string
+		c        Cents
+		pc       float64
+		mode     RoundingMode
+		expected Cents
+	}{
+		{"exact", 120, 20, RoundHalfUp, 100},
+		{"half up", 105, 5, RoundHalfUp, 100},
+		{"100/1.2 = 83.33", 100, 20, RoundCeiling, 84},
+		{"100/1.2 = 83.33", 100, 20, RoundHalfUp, 83},
+		{"minus 100% is zero, not a panic", 100, -100, RoundHalfUp, 0},
+	}
+
+	for _, test := range tests {
+		res := test.c.RemovePercentageRounded(test.pc, test.mode)
+		if res != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+	}
+}
+
+func TestDivideByQtyRounded(t *testing.T) {
+	tests := []struct {
+		c        Cents
+		qty      int
+		mode     RoundingMode
+		expected Cents
+	}{
+		{5, 2, RoundHalfUp, 3},
+		{5, 2, RoundHalfEven, 2},
+		{7, 2, RoundHalfEven, 4},
+		{5, 2, RoundHalfDown, 2},
+		{-5, 2, RoundHalfUp, -3},
+		{100, 3, RoundCeiling, 34},
+		{100, 0, RoundHalfUp, 0},
+	}
+
+	for _, test := range tests {
+		res := test.c.DivideByQtyRounded(test.qty, test.mode)
+		if res != test.expected {
+			t.Errorf("Testing %v / %v mode %v. Expected %v; got %v", test.c, test.qty, test.mode, test.expected, res)
+		}
+	}
+}
+
+func TestTaxCalcRoundingMode(t *testing.T) {
+	tx := TaxCalc{UnitEx: 250, LineQty: 1, TaxPercentage: 5, RoundingMode: RoundHalfEven}
+	tx.AddTax()
+
+	if tx.LineTax != 12 {
+		t.Errorf("Expected banker's rounding of 12.5 to give 12; got %v", tx.LineTax)
+	}
+
+	tx.RoundingMode = RoundHalfUp
+	tx.AddTax()
+
+	if tx.LineTax != 13 {
+		t.Errorf("Expected half up rounding of 12.5 to give 13; got %v", tx.LineTax)
+	}
+}
+
+func TestCalcAggTaxRate(t *testing.T) {
+	tests := []struct {
+		name     string
+		txs      TaxCalcs
+		expected float64
+	}{
+		{"none", nil, 0},
+		{"zero ex", TaxCalcs{{LineQty: 1, TaxPercentage: 20}}, 0},
+		{
+			name: "same rate many lines doesn't drift",
+			txs: TaxCalcs{
+				{UnitEx: 333, LineQty: 3, TaxPercentage: 7.7},
+				{UnitEx: 1, LineQty: 7, TaxPercentage: 7.7},
+				{UnitEx: 99999, LineQty: 11, TaxPercentage: 7.7},
+			},
+			expected: 7.7,
+		},
+		{
+			name: "mixed",
+			txs: TaxCalcs{
+				{UnitEx: 100, LineQty: 1, TaxPercentage: 20},
+				{UnitEx: 100, LineQty: 1, TaxPercentage: 5},
+			},
+			expected: 12.5,
+		},
+	}
+
+	for _, test := range tests {
+		res := test.txs.CalcAggTaxRate()
+		if res != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+	}
+}
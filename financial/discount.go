@@ -0,0 +1,305 @@
+package financial
+
+import "sort"
+
+type DiscountType uint
+
+const (
+	// DiscountTypePercentage takes Percentage off the matching lines
+	DiscountTypePercentage DiscountType = iota
+	// DiscountTypeFixedAmount takes Amount off, spread across the matching lines in proportion to their value
+	DiscountTypeFixedAmount
+	// DiscountTypeBuyXGetY makes GetQty units free for every BuyQty + GetQty units on each matching line
+	DiscountTypeBuyXGetY
+	// DiscountTypeTiered takes off the Percentage of the highest tier reached by the total qty of the matching lines
+	DiscountTypeTiered
+)
+
+// DiscountTier is a quantity break: buy at least MinQty to get Percentage off
+type DiscountTier struct {
+	MinQty     int
+	Percentage float64
+}
+
+// DiscountRule is a single discount or promotion.  Rules are applied in order of Priority
+// (lowest first, keeping the given order for ties) and each one works on the amounts left
+// by the rules before it, so they stack.
+type DiscountRule struct {
+	Name     string
+	Type     DiscountType
+	Priority int
+
+	Percentage     float64
+	Amount         Cents
+	BuyQty, GetQty int
+	Tiers          []DiscountTier
+
+	// Cap is the most the rule can take off in total and Floor the least, as long as
+	// the rule applies at all.  Zero means no limit.
+	Cap, Floor Cents
+
+	// AfterTax applies the discount to the inc amounts, with the tax worked back out afterwards,
+	// rather than to the ex amounts before tax is added
+	AfterTax bool
+
+	// Lines restricts the rule to the lines with these indexes.  Nil means all lines.
+	Lines []int
+}
+
+// DiscountAuditEntry records how much a rule took off a single line
+type DiscountAuditEntry struct {
+	Rule      string
+	LineIndex int
+	AfterTax  bool
+	Before    Cents
+	Amount    Cents
+}
+
+// DiscountResult is the discounted lines along with the trail of which rule took off what
+type DiscountResult struct {
+	Lines TaxCalcs
+	Audit []DiscountAuditEntry
+
+	// TotalDiscount is the total of all discounts, on whichever basis (ex or inc) they were applied
+	TotalDiscount Cents
+	ByRule        CentDict
+}
+
+// ApplyDiscounts calculates the tax on each of the lines, using each line's own rounding method,
+// and then applies the rules.  The lines passed in are not changed.
+func ApplyDiscounts(lines TaxCalcs, rules []DiscountRule) DiscountResult {
+	res := DiscountResult{
+		Lines:  make(TaxCalcs, len(lines)),
+		ByRule: CentDict{},
+	}
+
+	for i, line := range lines {
+		line.AddTax()
+		res.Lines[i] = line
+	}
+
+	ordered := make([]DiscountRule, len(rules))
+	copy(ordered, rules)
+	sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Priority < ordered[j].Priority })
+
+	for _, rule := range ordered {
+		res.applyRule(rule)
+	}
+
+	return res
+}
+
+// matchingLines are the indexes of the lines the rule applies to
+func (rule DiscountRule) matchingLines(lines TaxCalcs) (res []int) {
+	if rule.Lines == nil {
+		for i := range lines {
+			res = append(res, i)
+		}
+		return
+	}
+
+	for _, i := range rule.Lines {
+		if i >= 0 && i < len(lines) {
+			res = append(res, i)
+		}
+	}
+
+	return
+}
+
+func (rule DiscountRule) base(tx TaxCalc) Cents {
+	if rule.AfterTax {
+		return tx.LineInc
+	}
+
+	return tx.LineEx
+}
+
+// tierPercentage finds the percentage for the highest tier reached
+func (rule DiscountRule) tierPercentage(qty int) (pc float64) {
+	best := -1
+	for _, tier := range rule.Tiers {
+		if qty >= tier.MinQty && tier.MinQty > best {
+			best = tier.MinQty
+			pc = tier.Percentage
+		}
+	}
+
+	return
+}
+
+// rawDiscounts is what the rule would take off each line before any limits
+func (rule DiscountRule) rawDiscounts(lines TaxCalcs, indexes []int, bases []Cents) []Cents {
+	res := make([]Cents, len(indexes))
+
+	var totalBase Cents
+	var totalQty int
+	ratios := make([]int, len(indexes))
+	for j, i := range indexes {
+		totalBase = totalBase.SaturatingAdd(bases[j])
+		totalQty += lines[i].LineQty
+		ratios[j] = int(bases[j])
+	}
+
+	switch rule.Type {
+	case DiscountTypePercentage:
+		return totalBase.ByPercentage(rule.Percentage).AllocateByRatios(ratios)
+	case DiscountTypeTiered:
+		return totalBase.ByPercentage(rule.tierPercentage(totalQty)).AllocateByRatios(ratios)
+	case DiscountTypeFixedAmount:
+		return rule.Amount.LimitTo(totalBase).AllocateByRatios(ratios)
+	case DiscountTypeBuyXGetY:
+		groupSize := rule.BuyQty + rule.GetQty
+		if rule.GetQty <= 0 || groupSize <= 0 {
+			return res
+		}
+
+		for j, i := range indexes {
+			qty := lines[i].LineQty
+			if qty <= 0 {
+				continue
+			}
+
+			freeQty := (qty / groupSize) * rule.GetQty
+			res[j] = bases[j].ByQty(freeQty).DivideByQty(qty)
+		}
+	}
+
+	return res
+}
+
+// limit applies the cap and floor to the rule's total, and makes sure a rule never takes
+// a line below zero, re-spreading the total across the lines if it had to change
+func (rule DiscountRule) limit(raw, bases []Cents) []Cents {
+	var total, totalBase Cents
+	for j := range raw {
+		total = total.SaturatingAdd(raw[j])
+		totalBase = totalBase.SaturatingAdd(bases[j])
+	}
+
+	limited := total
+	if rule.Cap > 0 {
+		limited = limited.LimitTo(rule.Cap)
+	}
+	if rule.Floor > 0 && limited > 0 {
+		limited = limited.Min(rule.Floor)
+	}
+	limited = limited.LimitTo(totalBase)
+
+	if limited == total {
+		return raw
+	}
+
+	// Spread in proportion to what the rule wanted to take off each line,
+	// unless it didn't want to take anything off, in which case use the line values
+	ratios := make([]int, len(raw))
+	for j := range raw {
+		ratios[j] = int(raw[j])
+		if total == 0 {
+			ratios[j] = int(bases[j])
+		}
+	}
+
+	return limited.AllocateByRatios(ratios)
+}
+
+func (res *DiscountResult) applyRule(rule DiscountRule) {
+	indexes := rule.matchingLines(res.Lines)
+	if len(indexes) == 0 {
+		return
+	}
+
+	bases := make([]Cents, len(indexes))
+	for j, i := range indexes {
+		bases[j] = rule.base(res.Lines[i])
+	}
+
+	discounts := rule.limit(rule.rawDiscounts(res.Lines, indexes, bases), bases)
+
+	for j, i := range indexes {
+		amount := discounts[j]
+		if amount == 0 {
+			continue
+		}
+
+		line := &res.Lines[i]
+		if rule.AfterTax {
+			line.discountInc(amount)
+		} else {
+			line.discountEx(amount)
+		}
+
+		res.Audit = append(res.Audit, DiscountAuditEntry{
+			Rule:      rule.Name,
+			LineIndex: i,
+			AfterTax:  rule.AfterTax,
+			Before:    bases[j],
+			Amount:    amount,
+		})
+		res.TotalDiscount = res.TotalDiscount.SaturatingAdd(amount)
+		res.ByRule.AddToKey(rule.Name, amount)
+	}
+}
+
+// discountEx takes the discount off the line ex and recalculates the tax on what's left, using the
+// line's rounding method.  Lines that aren't charged tax stay that way, whatever their TaxPercentage.
+func (tx *TaxCalc) discountEx(amount Cents) {
+	if !tx.Treatment.ChargesTax() {
+		tx.LineInc = tx.LineEx.SaturatingSub(amount)
+		tx.removeNoTax()
+		return
+	}
+
+	tx.LineEx = tx.LineEx.SaturatingSub(amount)
+	tx.LineTax = tx.byRoundingMethod(tx.LineEx, func(c Cents) Cents {
+		return c.ByPercentageRounded(tx.TaxPercentage, tx.RoundingMode)
+	})
+	tx.LineInc = tx.LineEx.SaturatingAdd(tx.LineTax)
+	tx.afterLineDiscount()
+}
+
+// discountInc takes the discount off the line inc and works the tax back out of what's left,
+// using the line's rounding method
+func (tx *TaxCalc) discountInc(amount Cents) {
+	tx.LineInc = tx.LineInc.SaturatingSub(amount)
+	if !tx.Treatment.ChargesTax() {
+		tx.removeNoTax()
+		return
+	}
+
+	tx.LineEx = tx.byRoundingMethod(tx.LineInc, func(c Cents) Cents {
+		return c.RemovePercentageRounded(tx.TaxPercentage, tx.RoundingMode)
+	})
+	tx.LineTax = tx.LineInc.SaturatingSub(tx.LineEx)
+	tx.afterLineDiscount()
+}
+
+// byRoundingMethod applies f to a line amount, or for the unit method, to each unit's share of it and
+// adds them up.  A discounted line doesn't always divide evenly by its qty, so the units left over
+// after sharing it out evenly each take one more minor unit, the way AllocateByRatios would.
+func (tx *TaxCalc) byRoundingMethod(line Cents, f func(Cents) Cents) Cents {
+	if tx.RoundingMethod != TaxRoundingMethodUnit || tx.LineQty <= 0 {
+		return f(line)
+	}
+
+	unit := line / Cents(tx.LineQty)
+	remainder := int(line - unit*Cents(tx.LineQty))
+	step := Cents(1)
+	if remainder < 0 {
+		remainder, step = -remainder, -1
+	}
+
+	return f(unit + step).SaturatingMulQty(remainder).SaturatingAdd(f(unit).SaturatingMulQty(tx.LineQty - remainder))
+}
+
+// afterLineDiscount keeps the unit price and any tax components in step with the discounted line
+func (tx *TaxCalc) afterLineDiscount() {
+	if tx.LineQty != 0 {
+		tx.UnitEx = tx.LineEx / Cents(tx.LineQty)
+	}
+
+	if len(tx.Components) > 0 {
+		lineTaxes := tx.Components.splitComponentTaxes(tx.LineTax)
+		tx.setComponentTaxes(divideByQty(lineTaxes, tx.LineQty), lineTaxes)
+	}
+}
@@ -0,0 +1,239 @@
+package financial
+
+import "testing"
+
+func discountTestLines() TaxCalcs {
+	return TaxCalcs{
+		{UnitEx: 1000, LineQty: 2, TaxPercentage: 20, RoundingMethod: TaxRoundingMethodLine},
+		{UnitEx: 500, LineQty: 3, TaxPercentage: 20, RoundingMethod: TaxRoundingMethodLine},
+		{UnitEx: 250, LineQty: 1, TaxPercentage: 0, RoundingMethod: TaxRoundingMethodLine},
+	}
+}
+
+func TestApplyDiscounts(t *testing.T) {
+	tests := []struct {
+		name              string
+		rules             []DiscountRule
+		expectedDiscounts []Cents
+		expectedLineEx    []Cents
+		expectedLineInc   []Cents
+	}{
+		{
+			name:              "no rules",
+			expectedDiscounts: []Cents{},
+			expectedLineEx:    []Cents{2000, 1500, 250},
+			expectedLineInc:   []Cents{2400, 1800, 250},
+		},
+		{
+			name:              "percentage before tax",
+			rules:             []DiscountRule{{Name: "10off", Type: DiscountTypePercentage, Percentage: 10}},
+			expectedDiscounts: []Cents{200, 150, 25},
+			expectedLineEx:    []Cents{1800, 1350, 225},
+			expectedLineInc:   []Cents{2160, 1620, 225},
+		},
+		{
+			name:              "fixed amount after tax on some lines",
+			rules:             []DiscountRule{{Name: "tenner", Type: DiscountTypeFixedAmount, Amount: 1000, AfterTax: true, Lines: []int{0, 1}}},
+			expectedDiscounts: []Cents{571, 429},
+			expectedLineEx:    []Cents{1524, 1143, 250},
+			expectedLineInc:   []Cents{1829, 1371, 250},
+		},
+		{
+			name:              "fixed amount can't take the lines below zero",
+			rules:             []DiscountRule{{Name: "huge", Type: DiscountTypeFixedAmount, Amount: 100000, Lines: []int{2}}},
+			expectedDiscounts: []Cents{250},
+			expectedLineEx:    []Cents{2000, 1500, 0},
+			expectedLineInc:   []Cents{2400, 1800, 0},
+		},
+		{
+			name:              "buy 2 get 1 free",
+			rules:             []DiscountRule{{Name: "3for2", Type: DiscountTypeBuyXGetY, BuyQty: 2, GetQty: 1}},
+			expectedDiscounts: []Cents{500},
+			expectedLineEx:    []Cents{2000, 1000, 250},
+			expectedLineInc:   []Cents{2400, 1200, 250},
+		},
+		{
+			name: "tiered by total qty",
+			rules: []DiscountRule{{Name: "bulk", Type: DiscountTypeTiered, Tiers: []DiscountTier{
+				{MinQty: 3, Percentage: 5},
+				{MinQty: 5, Percentage: 10},
+				{MinQty: 10, Percentage: 20},
+			}}},
+			expectedDiscounts: []Cents{200, 150, 25},
+			expectedLineEx:    []Cents{1800, 1350, 225},
+			expectedLineInc:   []Cents{2160, 1620, 225},
+		},
+		{
+			name: "tier not reached",
+			rules: []DiscountRule{{Name: "bulk", Type: DiscountTypeTiered, Floor: 100, Tiers: []DiscountTier{
+				{MinQty: 10, Percentage: 20},
+			}}},
+			expectedDiscounts: []Cents{},
+			expectedLineEx:    []Cents{2000, 1500, 250},
+			expectedLineInc:   []Cents{2400, 1800, 250},
+		},
+		{
+			name:              "capped",
+			rules:             []DiscountRule{{Name: "10off", Type: DiscountTypePercentage, Percentage: 10, Cap: 100}},
+			expectedDiscounts: []Cents{53, 40, 7},
+			expectedLineEx:    []Cents{1947, 1460, 243},
+			expectedLineInc:   []Cents{2336, 1752, 243},
+		},
+		{
+			name:              "floor",
+			rules:             []DiscountRule{{Name: "1off", Type: DiscountTypePercentage, Percentage: 1, Floor: 50, Lines: []int{0, 1}}},
+			expectedDiscounts: []Cents{29, 21},
+			expectedLineEx:    []Cents{1971, 1479, 250},
+			expectedLineInc:   []Cents{2365, 1775, 250},
+		},
+		{
+			name: "stacking follows priority",
+			rules: []DiscountRule{
+				{Name: "10off", Type: DiscountTypePercentage, Percentage: 10, Priority: 2},
+				{Name: "freebie", Type: DiscountTypeFixedAmount, Amount: 250, Priority: 1, Lines: []int{2}},
+			},
+			expectedDiscounts: []Cents{250, 200, 150},
+			expectedLineEx:    []Cents{1800, 1350, 0},
+			expectedLineInc:   []Cents{2160, 1620, 0},
+		},
+	}
+
+	for _, test := range tests {
+		lines := discountTestLines()
+		res := ApplyDiscounts(lines, test.rules)
+
+		if len(res.Audit) != len(test.expectedDiscounts) {
+			t.Fatalf("Testing %s. Expected %d audit entries; got %v", test.name, len(test.expectedDiscounts), res.Audit)
+		}
+
+		var total Cents
+		for i, entry := range res.Audit {
+			if entry.Amount != test.expectedDiscounts[i] {
+				t.Errorf("Testing %s. Audit entry %d: expected %v; got %v", test.name, i, test.expectedDiscounts[i], entry)
+			}
+			total += entry.Amount
+		}
+
+		if total != res.TotalDiscount {
+			t.Errorf("Testing %s. Audit adds up to %v, but total discount is %v", test.name, total, res.TotalDiscount)
+		}
+
+		for i, line := range res.Lines {
+			if line.LineEx != test.expectedLineEx[i] || line.LineInc != test.expectedLineInc[i] {
+				t.Errorf("Testing %s. Line %d: expected ex %v inc %v; got %v", test.name, i, test.expectedLineEx[i], test.expectedLineInc[i], line)
+			}
+
+			if line.LineEx+line.LineTax != line.LineInc {
+				t.Errorf("Testing %s. Line %d doesn't add up: %v", test.name, i, line)
+			}
+		}
+
+		// The original lines are left alone
+		if lines[0].LineEx != 0 {
+			t.Errorf("Testing %s. Original lines were changed: %v", test.name, lines)
+		}
+	}
+}
+
+func TestApplyDiscountsUnitMethod(t *testing.T) {
+	line := TaxCalc{UnitEx: 333, LineQty: 3, TaxPercentage: 20}
+
+	tests := []struct {
+		name            string
+		rule            DiscountRule
+		expectedLineEx  Cents
+		expectedLineTax Cents
+	}{
+		// 3.32 a unit rounds to 0.66 tax, where the line method would give 1.99 on 9.96
+		{"before tax, even", DiscountRule{Name: "3p", Type: DiscountTypeFixedAmount, Amount: 3}, 996, 198},
+		// 3.33, 3.33 and 3.32
+		{"before tax, uneven", DiscountRule{Name: "1p", Type: DiscountTypeFixedAmount, Amount: 1}, 998, 200},
+		// 3.99 inc a unit is 3.33 ex and 0.66 tax, where the line method would give 9.98 and 1.99 on 11.97
+		{"after tax", DiscountRule{Name: "3p", Type: DiscountTypeFixedAmount, Amount: 3, AfterTax: true}, 999, 198},
+		{"nothing off", DiscountRule{Name: "none", Type: DiscountTypeFixedAmount, Amount: 0}, 999, 201},
+	}
+
+	for _, test := range tests {
+		res := ApplyDiscounts(TaxCalcs{line}, []DiscountRule{test.rule})
+		got := res.Lines[0]
+		if got.LineEx != test.expectedLineEx || got.LineTax != test.expectedLineTax || got.LineEx+got.LineTax != got.LineInc {
+			t.Errorf("Testing %s. Expected ex %v tax %v; got %v", test.name, test.expectedLineEx, test.expectedLineTax, got)
+		}
+	}
+
+	// A discount that leaves each unit a whole amount matches AddTax on the discounted unit price
+	res := ApplyDiscounts(TaxCalcs{line}, []DiscountRule{{Name: "3p", Type: DiscountTypeFixedAmount, Amount: 3}})
+	expected := TaxCalc{UnitEx: 332, LineQty: 3, TaxPercentage: 20}
+	expected.AddTax()
+	if got := res.Lines[0]; got.LineEx != expected.LineEx || got.LineTax != expected.LineTax || got.LineInc != expected.LineInc {
+		t.Errorf("Testing unit method against AddTax. Expected %v; got %v", expected, got)
+	}
+}
+
+func TestApplyDiscountsSaturate(t *testing.T) {
+	lines := TaxCalcs{
+		{UnitEx: maxCents, LineQty: 1, RoundingMethod: TaxRoundingMethodLine},
+		{UnitEx: maxCents, LineQty: 1, RoundingMethod: TaxRoundingMethodLine},
+	}
+
+	res := ApplyDiscounts(lines, []DiscountRule{{Name: "all", Type: DiscountTypePercentage, Percentage: 100}})
+	if res.TotalDiscount < 0 {
+		t.Errorf("Testing a discount past the largest amount. Expected it to saturate; got %v", res.TotalDiscount)
+	}
+
+	for i, line := range res.Lines {
+		if line.LineEx < 0 || line.LineInc < 0 {
+			t.Errorf("Testing a discount past the largest amount. Line %d went below zero: %v", i, line)
+		}
+	}
+}
+
+func TestApplyDiscountsTreatments(t *testing.T) {
+	lines := TaxCalcs{
+		{UnitEx: 1000, LineQty: 1, TaxPercentage: 20, Treatment: TaxTreatmentExempt},
+		{UnitEx: 1000, LineQty: 1, TaxPercentage: 0, Treatment: TaxTreatmentZeroRated},
+		{UnitEx: 1000, LineQty: 1, TaxPercentage: 20, Treatment: TaxTreatmentReverseCharge},
+		{UnitEx: 1000, LineQty: 1, TaxPercentage: 20},
+	}
+
+	tests := []struct {
+		name            string
+		afterTax        bool
+		expectedLineEx  []Cents
+		expectedLineTax []Cents
+	}{
+		{"before tax", false, []Cents{900, 900, 900, 900}, []Cents{0, 0, 0, 180}},
+		{"after tax", true, []Cents{900, 900, 900, 900}, []Cents{0, 0, 0, 180}},
+	}
+
+	for _, test := range tests {
+		res := ApplyDiscounts(lines, []DiscountRule{{Name: "10off", Type: DiscountTypePercentage, Percentage: 10, AfterTax: test.afterTax}})
+
+		for i, line := range res.Lines {
+			if line.LineEx != test.expectedLineEx[i] || line.LineTax != test.expectedLineTax[i] || line.LineEx+line.LineTax != line.LineInc {
+				t.Errorf("Testing %s. Line %d: expected ex %v tax %v; got %v", test.name, i, test.expectedLineEx[i], test.expectedLineTax[i], line)
+			}
+		}
+
+		if res := res.Lines[2].ReverseChargeTax(); res != 180 {
+			t.Errorf("Testing %s. Expected reverse charge tax %v; got %v", test.name, 180, res)
+		}
+	}
+}
+
+func TestApplyDiscountsAuditByRule(t *testing.T) {
+	res := ApplyDiscounts(discountTestLines(), []DiscountRule{
+		{Name: "freebie", Type: DiscountTypeFixedAmount, Amount: 250, Lines: []int{2}},
+		{Name: "10off", Type: DiscountTypePercentage, Percentage: 10, Priority: 1},
+	})
+
+	expected := CentDict{"freebie": 250, "10off": 350}
+	if !res.ByRule.Compare(expected) {
+		t.Errorf("Expected %v; got %v", expected, res.ByRule)
+	}
+
+	first := res.Audit[0]
+	if first.Rule != "freebie" || first.LineIndex != 2 || first.Before != 250 || first.Amount != 250 {
+		t.Errorf("Incorrect audit entry: %v", first)
+	}
+}
@@ -0,0 +1,599 @@
+package financial
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// WireFormat decides how amounts are written out in JSON, BSON and SQL.
+// Whatever the format, reading accepts all of them, so that stored integers
+// from before a switch to decimals still decode.
+type WireFormat uint
+
+const (
+	// WireFormatMinorUnits is a plain integer number of minor units, e.g. 1234.
+	// This is what Cents have always been written as, so it is the default.
+	WireFormatMinorUnits WireFormat = iota
+	// WireFormatDecimalString is a decimal string in major units, e.g. "12.34"
+	WireFormatDecimalString
+	// WireFormatDecimal128 is a MongoDB Decimal128 in major units.  Outside of BSON,
+	// where there is no such type, it is the same as WireFormatDecimalString.
+	WireFormatDecimal128
+)
+
+// CentDictLayout decides how a CentDict is written out in JSON
+type CentDictLayout uint
+
+const (
+	// CentDictLayoutObject is a JSON object keyed by currency, e.g. {"GBP":1234}
+	CentDictLayoutObject CentDictLayout = iota
+	// CentDictLayoutMoneyList is a list of amounts with their currency, sorted by currency,
+	// e.g. [{"amount":1234,"currency":"GBP"}]
+	CentDictLayoutMoneyList
+)
+
+// WireEncoding is a choice of how to write amounts out.  Cents, Money and CentDict always write
+// themselves with the zero value, which is integer minor units and an object keyed by currency.
+// Anything else, such as decimal strings for a public API, is chosen where the amounts are written,
+// by wrapping them with the Cents, Money and CentDict methods, so that each endpoint or partner can
+// have its own.
+type WireEncoding struct {
+	Format         WireFormat
+	CentDictLayout CentDictLayout
+}
+
+// EncodedCents are Cents that are written in a WireEncoding.  They read any format.
+type EncodedCents struct {
+	Amount   Cents
+	Encoding WireEncoding
+}
+
+// EncodedMoney is Money that is written in a WireEncoding.  It reads any format.
+type EncodedMoney struct {
+	Money    Money
+	Encoding WireEncoding
+}
+
+// EncodedCentDict is a CentDict that is written in a WireEncoding.  It reads any format and layout.
+type EncodedCentDict struct {
+	CentDict CentDict
+	Encoding WireEncoding
+}
+
+func (we WireEncoding) Cents(c Cents) EncodedCents {
+	return EncodedCents{Amount: c, Encoding: we}
+}
+
+func (we WireEncoding) Money(m Money) EncodedMoney {
+	return EncodedMoney{Money: m, Encoding: we}
+}
+
+func (we WireEncoding) CentDict(cd CentDict) EncodedCentDict {
+	return EncodedCentDict{CentDict: cd, Encoding: we}
+}
+
+var (
+	ErrFractionalMinorUnits = errors.New("amount has a fraction of a minor unit")
+	ErrUnsupportedWireType  = errors.New("unsupported type for an amount")
+)
+
+// Amounts are read and written in the minor units of a currency, which for Cents on their own
+// is the default of 2 decimal places.  Money passes its own currency.
+
+// ratMinorUnits converts an exact amount to minor units, as long as nothing is lost.
+// An exponent of 0 means the amount is already in minor units.
+func ratMinorUnits(r *big.Rat, exponent int) (Cents, error) {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)
+	r = new(big.Rat).Mul(r, new(big.Rat).SetInt(scale))
+
+	if !r.IsInt() {
+		return 0, ErrFractionalMinorUnits
+	}
+
+	if !r.Num().IsInt64() || r.Num().Int64() > int64(maxCents) || r.Num().Int64() < -int64(maxCents) {
+		return 0, ErrPriceOverflow
+	}
+
+	return Cents(r.Num().Int64()), nil
+}
+
+// JSON
+
+func marshalAmountJSON(c Cents, currency string, format WireFormat) ([]byte, error) {
+	if format == WireFormatMinorUnits {
+		return []byte(strconv.FormatInt(int64(c), 10)), nil
+	}
+
+	return json.Marshal(formatMinorUnits(c, CurrencyExponent(currency)))
+}
+
+// unmarshalAmountJSON accepts a number of minor units, a decimal string in major units, null,
+// or an object with an amount in either form
+func unmarshalAmountJSON(data []byte, currency string) (Cents, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return 0, nil
+	}
+
+	switch data[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return 0, err
+		}
+		return parsePrice(s, Locale{}, currency)
+	case '{':
+		var obj struct {
+			Amount   json.RawMessage `json:"amount"`
+			Currency string          `json:"currency"`
+		}
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return 0, err
+		}
+		if obj.Currency != "" {
+			currency = obj.Currency
+		}
+		return unmarshalAmountJSON(obj.Amount, currency)
+	}
+
+	// A bare number is always minor units, as that is how they have always been written
+	r, ok := new(big.Rat).SetString(string(data))
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrUnsupportedWireType, data)
+	}
+
+	return ratMinorUnits(r, 0)
+}
+
+func (c Cents) MarshalJSON() ([]byte, error) {
+	return marshalAmountJSON(c, "", WireFormatMinorUnits)
+}
+
+func (c *Cents) UnmarshalJSON(data []byte) error {
+	res, err := unmarshalAmountJSON(data, "")
+	if err != nil {
+		return err
+	}
+
+	*c = res
+	return nil
+}
+
+func (ec EncodedCents) MarshalJSON() ([]byte, error) {
+	return marshalAmountJSON(ec.Amount, "", ec.Encoding.Format)
+}
+
+func (ec *EncodedCents) UnmarshalJSON(data []byte) error {
+	return ec.Amount.UnmarshalJSON(data)
+}
+
+// moneyJSON is how Money looks on the wire, with the amount written according to the currency
+type moneyJSON struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+func marshalMoneyJSON(m Money, format WireFormat) ([]byte, error) {
+	amount, err := marshalAmountJSON(m.Amount, m.Currency, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(moneyJSON{Amount: amount, Currency: m.Currency})
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return marshalMoneyJSON(m, WireFormatMinorUnits)
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var raw moneyJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	amount, err := unmarshalAmountJSON(raw.Amount, raw.Currency)
+	if err != nil {
+		return err
+	}
+
+	m.Amount, m.Currency = amount, raw.Currency
+	return nil
+}
+
+func (em EncodedMoney) MarshalJSON() ([]byte, error) {
+	return marshalMoneyJSON(em.Money, em.Encoding.Format)
+}
+
+func (em *EncodedMoney) UnmarshalJSON(data []byte) error {
+	return em.Money.UnmarshalJSON(data)
+}
+
+// marshalCentDictJSON writes each amount according to the currency it is keyed by
+func marshalCentDictJSON(cd CentDict, we WireEncoding) ([]byte, error) {
+	if cd == nil {
+		return []byte("null"), nil
+	}
+
+	if we.CentDictLayout == CentDictLayoutMoneyList {
+		list := make([]json.RawMessage, 0, len(cd))
+		for _, m := range cd.moneyList() {
+			b, err := marshalMoneyJSON(m, we.Format)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, b)
+		}
+
+		return json.Marshal(list)
+	}
+
+	obj := make(map[string]json.RawMessage, len(cd))
+	for currency, amount := range cd {
+		b, err := marshalAmountJSON(amount, currency, we.Format)
+		if err != nil {
+			return nil, err
+		}
+		obj[currency] = b
+	}
+
+	return json.Marshal(obj)
+}
+
+// moneyList is the CentDict as a list of Money, sorted by currency
+func (cd CentDict) moneyList() []Money {
+	res := make([]Money, 0, len(cd))
+	for k, amount := range cd {
+		res = append(res, Money{Amount: amount, Currency: k})
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Currency < res[j].Currency })
+	return res
+}
+
+func (cd CentDict) MarshalJSON() ([]byte, error) {
+	return marshalCentDictJSON(cd, WireEncoding{})
+}
+
+// UnmarshalJSON accepts either layout, and replaces anything already in the CentDict
+func (cd *CentDict) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*cd = nil
+		return nil
+	}
+
+	res := CentDict{}
+	if len(data) > 0 && data[0] == '[' {
+		var list []Money
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+
+		for _, m := range list {
+			res.AddToKey(m.Currency, m.Amount)
+		}
+
+		*cd = res
+		return nil
+	}
+
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+
+	for currency, raw := range obj {
+		amount, err := unmarshalAmountJSON(raw, currency)
+		if err != nil {
+			return fmt.Errorf("%s: %w", currency, err)
+		}
+		res[currency] = amount
+	}
+
+	*cd = res
+	return nil
+}
+
+func (ecd EncodedCentDict) MarshalJSON() ([]byte, error) {
+	return marshalCentDictJSON(ecd.CentDict, ecd.Encoding)
+}
+
+func (ecd *EncodedCentDict) UnmarshalJSON(data []byte) error {
+	return ecd.CentDict.UnmarshalJSON(data)
+}
+
+// BSON
+
+func marshalAmountBSONValue(c Cents, currency string, format WireFormat) (bsontype.Type, []byte, error) {
+	switch format {
+	case WireFormatDecimalString:
+		return bsontype.String, bsoncore.AppendString(nil, formatMinorUnits(c, CurrencyExponent(currency))), nil
+	case WireFormatDecimal128:
+		d, ok := primitive.ParseDecimal128FromBigInt(big.NewInt(int64(c)), -CurrencyExponent(currency))
+		if !ok {
+			return 0, nil, ErrPriceOverflow
+		}
+		return bsontype.Decimal128, bsoncore.AppendDecimal128(nil, d), nil
+	}
+
+	return bsontype.Int64, bsoncore.AppendInt64(nil, int64(c)), nil
+}
+
+// unmarshalAmountBSONValue accepts any of the formats, along with the int32 and double
+// that other writers may have used for minor units
+func unmarshalAmountBSONValue(t bsontype.Type, data []byte, currency string) (Cents, error) {
+	v := bsoncore.Value{Type: t, Data: data}
+
+	switch t {
+	case bsontype.Null, bsontype.Undefined:
+		return 0, nil
+	case bsontype.Int32:
+		return Cents(v.Int32()), nil
+	case bsontype.Int64:
+		return Cents(v.Int64()), nil
+	case bsontype.Double:
+		f := v.Double()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return 0, fmt.Errorf("%w: %v", ErrUnsupportedWireType, f)
+		}
+		return ratMinorUnits(new(big.Rat).SetFloat64(f), 0)
+	case bsontype.String:
+		return parsePrice(v.StringValue(), Locale{}, currency)
+	case bsontype.Decimal128:
+		bi, exp, err := v.Decimal128().BigInt()
+		if err != nil {
+			return 0, err
+		}
+
+		r := new(big.Rat).SetInt(bi)
+		scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(exp))), nil))
+		if exp >= 0 {
+			r.Mul(r, scale)
+		} else {
+			r.Quo(r, scale)
+		}
+		return ratMinorUnits(r, CurrencyExponent(currency))
+	}
+
+	return 0, fmt.Errorf("%w: BSON %s", ErrUnsupportedWireType, t)
+}
+
+func (c Cents) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return marshalAmountBSONValue(c, "", WireFormatMinorUnits)
+}
+
+func (c *Cents) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	res, err := unmarshalAmountBSONValue(t, data, "")
+	if err != nil {
+		return err
+	}
+
+	*c = res
+	return nil
+}
+
+func (ec EncodedCents) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return marshalAmountBSONValue(ec.Amount, "", ec.Encoding.Format)
+}
+
+func (ec *EncodedCents) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	return ec.Amount.UnmarshalBSONValue(t, data)
+}
+
+// moneyBSON is how Money looks in MongoDB, with the amount written according to the currency
+type moneyBSON struct {
+	Amount   bson.RawValue `bson:"amount"`
+	Currency string        `bson:"currency"`
+}
+
+func marshalMoneyBSON(m Money, format WireFormat) ([]byte, error) {
+	t, data, err := marshalAmountBSONValue(m.Amount, m.Currency, format)
+	if err != nil {
+		return nil, err
+	}
+
+	return bson.Marshal(moneyBSON{Amount: bson.RawValue{Type: t, Value: data}, Currency: m.Currency})
+}
+
+func (m Money) MarshalBSON() ([]byte, error) {
+	return marshalMoneyBSON(m, WireFormatMinorUnits)
+}
+
+func (m *Money) UnmarshalBSON(data []byte) error {
+	var raw moneyBSON
+	if err := bson.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	amount, err := unmarshalAmountBSONValue(raw.Amount.Type, raw.Amount.Value, raw.Currency)
+	if err != nil {
+		return err
+	}
+
+	m.Amount, m.Currency = amount, raw.Currency
+	return nil
+}
+
+func (em EncodedMoney) MarshalBSON() ([]byte, error) {
+	return marshalMoneyBSON(em.Money, em.Encoding.Format)
+}
+
+func (em *EncodedMoney) UnmarshalBSON(data []byte) error {
+	return em.Money.UnmarshalBSON(data)
+}
+
+// A CentDict is written to BSON by the driver as a document of minor units, using the Cents
+// methods above for each amount.  It is always a document, whatever the CentDictLayout.
+
+// UnmarshalBSON reads each amount according to the currency it is keyed by, and replaces
+// anything already in the CentDict
+func (cd *CentDict) UnmarshalBSON(data []byte) error {
+	elements, err := bson.Raw(data).Elements()
+	if err != nil {
+		return err
+	}
+
+	res := CentDict{}
+	for _, e := range elements {
+		v := e.Value()
+		amount, err := unmarshalAmountBSONValue(v.Type, v.Value, e.Key())
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.Key(), err)
+		}
+		res[e.Key()] = amount
+	}
+
+	*cd = res
+	return nil
+}
+
+func (ecd EncodedCentDict) MarshalBSON() ([]byte, error) {
+	index, doc := bsoncore.AppendDocumentStart(nil)
+	for _, m := range ecd.CentDict.moneyList() {
+		t, data, err := marshalAmountBSONValue(m.Amount, m.Currency, ecd.Encoding.Format)
+		if err != nil {
+			return nil, err
+		}
+		doc = bsoncore.AppendValueElement(doc, m.Currency, bsoncore.Value{Type: t, Data: data})
+	}
+
+	return bsoncore.AppendDocumentEnd(doc, index)
+}
+
+func (ecd *EncodedCentDict) UnmarshalBSON(data []byte) error {
+	return ecd.CentDict.UnmarshalBSON(data)
+}
+
+// SQL
+
+func amountValue(c Cents, format WireFormat) (driver.Value, error) {
+	if format == WireFormatMinorUnits {
+		return int64(c), nil
+	}
+
+	return c.FormatAsPrice(), nil
+}
+
+// scanAmount reads integers as minor units, and floats and text in the format, so minor units or
+// decimals in major units.  Text protocol drivers, such as MySQL's, return a BIGINT as text, so
+// text in minor units has to be read as such, or it would come back 100 times too large.
+func scanAmount(src interface{}, format WireFormat) (Cents, error) {
+	switch v := src.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		return Cents(v), nil
+	case float64:
+		exponent := defaultCurrencyExponent
+		if format == WireFormatMinorUnits {
+			exponent = 0
+		}
+		return ratMinorUnits(decimalRat(v), exponent)
+	case []byte:
+		return scanAmountText(string(v), format)
+	case string:
+		return scanAmountText(v, format)
+	}
+
+	return 0, fmt.Errorf("%w: %T", ErrUnsupportedWireType, src)
+}
+
+func scanAmountText(s string, format WireFormat) (Cents, error) {
+	if format != WireFormatMinorUnits {
+		return ParseCentsFromPriceString(s)
+	}
+
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedWireType, s)
+	}
+
+	return ratMinorUnits(r, 0)
+}
+
+// Value writes Cents as a BIGINT of minor units
+func (c Cents) Value() (driver.Value, error) {
+	return amountValue(c, WireFormatMinorUnits)
+}
+
+// Scan reads minor units, as Value writes them, whether they come as integers, floats or text.
+// Use EncodedCents with WireFormatDecimalString to read a NUMERIC column in major units.
+func (c *Cents) Scan(src interface{}) error {
+	res, err := scanAmount(src, WireFormatMinorUnits)
+	if err != nil {
+		return err
+	}
+
+	*c = res
+	return nil
+}
+
+// Value writes the Cents as a BIGINT of minor units, or a decimal string for a NUMERIC column
+func (ec EncodedCents) Value() (driver.Value, error) {
+	return amountValue(ec.Amount, ec.Encoding.Format)
+}
+
+// Scan is the same as for Cents, except that floats and text are taken to be in the Encoding's format
+func (ec *EncodedCents) Scan(src interface{}) error {
+	res, err := scanAmount(src, ec.Encoding.Format)
+	if err != nil {
+		return err
+	}
+
+	ec.Amount = res
+	return nil
+}
+
+func centDictValue(cd CentDict, we WireEncoding) (driver.Value, error) {
+	if cd == nil {
+		return nil, nil
+	}
+
+	b, err := marshalCentDictJSON(cd, we)
+	if err != nil {
+		return nil, err
+	}
+
+	return string(b), nil
+}
+
+// Value writes a CentDict as JSON, for a JSON or text column
+func (cd CentDict) Value() (driver.Value, error) {
+	return centDictValue(cd, WireEncoding{})
+}
+
+func (cd *CentDict) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*cd = nil
+		return nil
+	case []byte:
+		return cd.UnmarshalJSON(v)
+	case string:
+		return cd.UnmarshalJSON([]byte(v))
+	}
+
+	return fmt.Errorf("%w: %T", ErrUnsupportedWireType, src)
+}
+
+func (ecd EncodedCentDict) Value() (driver.Value, error) {
+	return centDictValue(ecd.CentDict, ecd.Encoding)
+}
+
+func (ecd *EncodedCentDict) Scan(src interface{}) error {
+	return ecd.CentDict.Scan(src)
+}
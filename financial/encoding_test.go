@@ -0,0 +1,347 @@
+package financial
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type encodingTestDoc struct {
+	Price  Cents    `json:"price" bson:"price"`
+	Totals CentDict `json:"totals" bson:"totals"`
+	Paid   Money    `json:"paid" bson:"paid"`
+}
+
+type encodedTestDoc struct {
+	Price  EncodedCents    `json:"price" bson:"price"`
+	Totals EncodedCentDict `json:"totals" bson:"totals"`
+	Paid   EncodedMoney    `json:"paid" bson:"paid"`
+}
+
+var encodingTestValue = encodingTestDoc{
+	Price:  -1234,
+	Totals: CentDict{"GBP": 100, "EUR": 5, "JPY": 1234},
+	Paid:   Money{Amount: 1234, Currency: "JPY"},
+}
+
+func encodedTestValue(we WireEncoding) encodedTestDoc {
+	return encodedTestDoc{
+		Price:  we.Cents(encodingTestValue.Price),
+		Totals: we.CentDict(encodingTestValue.Totals),
+		Paid:   we.Money(encodingTestValue.Paid),
+	}
+}
+
+func TestCentsJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding WireEncoding
+		expected string
+	}{
+		{"minor units", WireEncoding{},
+			`{"price":-1234,"totals":{"EUR":5,"GBP":100,"JPY":1234},"paid":{"amount":1234,"currency":"JPY"}}`},
+		{"decimal strings", WireEncoding{Format: WireFormatDecimalString},
+			`{"price":"-12.34","totals":{"EUR":"0.05","GBP":"1.00","JPY":"1234"},"paid":{"amount":"1234","currency":"JPY"}}`},
+		{"money list", WireEncoding{CentDictLayout: CentDictLayoutMoneyList},
+			`{"price":-1234,"totals":[{"amount":5,"currency":"EUR"},{"amount":100,"currency":"GBP"},{"amount":1234,"currency":"JPY"}],"paid":{"amount":1234,"currency":"JPY"}}`},
+		{"decimal money list", WireEncoding{Format: WireFormatDecimalString, CentDictLayout: CentDictLayoutMoneyList},
+			`{"price":"-12.34","totals":[{"amount":"0.05","currency":"EUR"},{"amount":"1.00","currency":"GBP"},{"amount":"1234","currency":"JPY"}],"paid":{"amount":"1234","currency":"JPY"}}`},
+	}
+
+	for _, test := range tests {
+		b, err := json.Marshal(encodedTestValue(test.encoding))
+		if err != nil {
+			t.Fatalf("Testing %s. Unexpected error: %s", test.name, err)
+		}
+
+		if string(b) != test.expected {
+			t.Errorf("Testing %s. Expected %s; got %s", test.name, test.expected, b)
+		}
+
+		// Reading doesn't depend on the format, with or without the wrappers
+		var res encodingTestDoc
+		if err := json.Unmarshal([]byte(test.expected), &res); err != nil {
+			t.Fatalf("Testing %s. Unexpected error: %s", test.name, err)
+		}
+
+		if !reflect.DeepEqual(res, encodingTestValue) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, encodingTestValue, res)
+		}
+
+		var encoded encodedTestDoc
+		if err := json.Unmarshal([]byte(test.expected), &encoded); err != nil {
+			t.Fatalf("Testing %s. Unexpected error: %s", test.name, err)
+		}
+
+		if !reflect.DeepEqual(encoded, encodedTestValue(WireEncoding{})) {
+			t.Errorf("Testing %s wrapped. Expected %v; got %v", test.name, encodedTestValue(WireEncoding{}), encoded)
+		}
+	}
+
+	// The plain types always write minor units
+	b, err := json.Marshal(encodingTestValue)
+	if err != nil || string(b) != tests[0].expected {
+		t.Errorf("Testing plain types. Expected %s; got %s (%v)", tests[0].expected, b, err)
+	}
+}
+
+func TestCentDictUnmarshalJSONReplaces(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected CentDict
+	}{
+		{"object", `{"GBP":5}`, CentDict{"GBP": 5}},
+		{"money list", `[{"amount":7,"currency":"EUR"}]`, CentDict{"EUR": 7}},
+		{"null", `null`, nil},
+	}
+
+	for _, test := range tests {
+		cd := CentDict{"GBP": 100, "USD": 1}
+		if err := json.Unmarshal([]byte(test.json), &cd); err != nil {
+			t.Fatalf("Testing %s. Unexpected error: %s", test.name, err)
+		}
+
+		if !reflect.DeepEqual(cd, test.expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, cd)
+		}
+	}
+}
+
+func TestCentsUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name      string
+		json      string
+		expected  Cents
+		expectErr error
+	}{
+		{"integer", `1234`, 1234, nil},
+		{"integer as float", `1234.0`, 1234, nil},
+		{"decimal string", `"12.34"`, 1234, nil},
+		{"whole string", `"12"`, 1200, nil},
+		{"negative string", `"-0.05"`, -5, nil},
+		{"object", `{"amount":1234,"currency":"GBP"}`, 1234, nil},
+		{"object with string", `{"amount":"12.34","currency":"GBP"}`, 1234, nil},
+		{"null", `null`, 0, nil},
+		{"fraction of a cent", `12.5`, 0, ErrFractionalMinorUnits},
+		{"too many decimals", `"12.345"`, 0, ErrTooManyDecimalPlaces},
+		{"bool", `true`, 0, ErrUnsupportedWireType},
+	}
+
+	for _, test := range tests {
+		var res Cents
+		err := json.Unmarshal([]byte(test.json), &res)
+		if test.expectErr != nil {
+			if !errors.Is(err, test.expectErr) {
+				t.Errorf("Testing %s. Expected error %v; got %v", test.name, test.expectErr, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Testing %s. Unexpected error: %s", test.name, err)
+		}
+		if res != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+	}
+}
+
+func TestCentsBSON(t *testing.T) {
+	for _, format := range []WireFormat{WireFormatMinorUnits, WireFormatDecimalString, WireFormatDecimal128} {
+		b, err := bson.Marshal(encodedTestValue(WireEncoding{Format: format}))
+		if err != nil {
+			t.Fatalf("Testing format %d. Unexpected error: %s", format, err)
+		}
+
+		raw := bson.Raw(b)
+		price := raw.Lookup("price")
+		paid := raw.Lookup("paid", "amount")
+		jpy := raw.Lookup("totals", "JPY")
+		switch format {
+		case WireFormatMinorUnits:
+			if price.Int64() != -1234 || paid.Int64() != 1234 || jpy.Int64() != 1234 {
+				t.Errorf("Testing minor units. Got %v, %v and %v", price, paid, jpy)
+			}
+		case WireFormatDecimalString:
+			if price.StringValue() != "-12.34" || paid.StringValue() != "1234" || jpy.StringValue() != "1234" {
+				t.Errorf("Testing decimal string. Got %v, %v and %v", price, paid, jpy)
+			}
+		case WireFormatDecimal128:
+			if price.Decimal128().String() != "-12.34" || paid.Decimal128().String() != "1234" || jpy.Decimal128().String() != "1234" {
+				t.Errorf("Testing Decimal128. Got %v, %v and %v", price, paid, jpy)
+			}
+		}
+
+		var res encodingTestDoc
+		if err := bson.Unmarshal(b, &res); err != nil {
+			t.Fatalf("Testing format %d. Unexpected error: %s", format, err)
+		}
+
+		if !reflect.DeepEqual(res, encodingTestValue) {
+			t.Errorf("Testing format %d. Expected %v; got %v", format, encodingTestValue, res)
+		}
+	}
+
+	// The plain types always write minor units
+	b, err := bson.Marshal(encodingTestValue)
+	if err != nil || bson.Raw(b).Lookup("price").Int64() != -1234 {
+		t.Errorf("Testing plain types. Got %v (%v)", bson.Raw(b), err)
+	}
+
+	var res encodingTestDoc
+	if err := bson.Unmarshal(b, &res); err != nil || !reflect.DeepEqual(res, encodingTestValue) {
+		t.Errorf("Testing plain types. Expected %v; got %v (%v)", encodingTestValue, res, err)
+	}
+}
+
+func TestCentsUnmarshalBSONExisting(t *testing.T) {
+	d128, _ := primitive.ParseDecimal128("12.340")
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected Cents
+	}{
+		{"stored int32", int32(1234), 1234},
+		{"stored int64", int64(1234), 1234},
+		{"stored plain int", 1234, 1234},
+		{"stored double", float64(1234), 1234},
+		{"stored string", "12.34", 1234},
+		{"stored Decimal128 with trailing zero", d128, 1234},
+	}
+
+	for _, test := range tests {
+		b, err := bson.Marshal(bson.M{"price": test.value})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var res struct {
+			Price Cents `bson:"price"`
+		}
+		if err := bson.Unmarshal(b, &res); err != nil {
+			t.Errorf("Testing %s. Unexpected error: %s", test.name, err)
+		}
+
+		if res.Price != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res.Price)
+		}
+	}
+}
+
+func TestCentsSQL(t *testing.T) {
+	decimal := WireEncoding{Format: WireFormatDecimalString}
+	v, err := decimal.Cents(-1234).Value()
+	if err != nil || v != "-12.34" {
+		t.Errorf("Testing decimal Value. Expected %v; got %v (%v)", "-12.34", v, err)
+	}
+
+	ec := decimal.Cents(0)
+	if err := ec.Scan(float64(12.34)); err != nil || ec.Amount != 1234 {
+		t.Errorf("Testing decimal Scan of a float. Expected 1234; got %v (%v)", ec.Amount, err)
+	}
+
+	v, err = Cents(-1234).Value()
+	if err != nil || v != int64(-1234) {
+		t.Errorf("Testing integer Value. Expected %v; got %v (%v)", -1234, v, err)
+	}
+
+	tests := []struct {
+		name     string
+		format   WireFormat
+		src      interface{}
+		expected Cents
+	}{
+		{"bigint", WireFormatMinorUnits, int64(1234), 1234},
+		{"bigint as bytes", WireFormatMinorUnits, []byte("1234"), 1234},
+		{"bigint as string", WireFormatMinorUnits, "-1234", -1234},
+		{"float minor units", WireFormatMinorUnits, float64(1234), 1234},
+		{"null", WireFormatMinorUnits, nil, 0},
+		{"numeric as bytes", WireFormatDecimalString, []byte("12.34"), 1234},
+		{"numeric as string", WireFormatDecimalString, "12.3400", 1234},
+		{"bigint in a decimal encoding", WireFormatDecimalString, int64(1234), 1234},
+	}
+
+	for _, test := range tests {
+		ec := WireEncoding{Format: test.format}.Cents(99)
+		if err := ec.Scan(test.src); err != nil || ec.Amount != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v (%v)", test.name, test.expected, ec.Amount, err)
+		}
+
+		if test.format != WireFormatMinorUnits {
+			continue
+		}
+
+		res := Cents(99)
+		if err := res.Scan(test.src); err != nil || res != test.expected {
+			t.Errorf("Testing %s with Cents. Expected %v; got %v (%v)", test.name, test.expected, res, err)
+		}
+	}
+
+	var c Cents
+	if err := c.Scan(true); !errors.Is(err, ErrUnsupportedWireType) {
+		t.Errorf("Testing bool. Expected %v; got %v", ErrUnsupportedWireType, err)
+	}
+
+	if err := c.Scan([]byte("12.34")); !errors.Is(err, ErrFractionalMinorUnits) {
+		t.Errorf("Testing a decimal as minor units. Expected %v; got %v", ErrFractionalMinorUnits, err)
+	}
+
+	cdValue, err := CentDict{"GBP": 100}.Value()
+	if err != nil || cdValue != `{"GBP":100}` {
+		t.Errorf("Testing CentDict Value. Expected %v; got %v (%v)", `{"GBP":100}`, cdValue, err)
+	}
+
+	cdValue, err = decimal.CentDict(CentDict{"GBP": 100, "JPY": 5}).Value()
+	if err != nil || cdValue != `{"GBP":"1.00","JPY":"5"}` {
+		t.Errorf("Testing decimal CentDict Value. Expected %v; got %v (%v)", `{"GBP":"1.00","JPY":"5"}`, cdValue, err)
+	}
+
+	cd := CentDict{"USD": 1}
+	if err := cd.Scan([]byte(`{"GBP":"1.00","EUR":5,"JPY":"5"}`)); err != nil || !cd.Compare(CentDict{"GBP": 100, "EUR": 5, "JPY": 5}) {
+		t.Errorf("Testing CentDict Scan. Got %v (%v)", cd, err)
+	}
+}
+
+func TestCentsSQLRoundTrip(t *testing.T) {
+	// Text protocol drivers hand back whatever was written as text
+	asText := map[string]func(v driver.Value) interface{}{
+		"as written": func(v driver.Value) interface{} { return v },
+		"bytes":      func(v driver.Value) interface{} { return []byte(fmt.Sprint(v)) },
+		"string":     func(v driver.Value) interface{} { return fmt.Sprint(v) },
+	}
+
+	for _, amount := range []Cents{0, 1234, -5, maxCents} {
+		for name, read := range asText {
+			v, err := amount.Value()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var c Cents
+			if err := c.Scan(read(v)); err != nil || c != amount {
+				t.Errorf("Testing Cents %v %s. Expected %v; got %v (%v)", amount, name, amount, c, err)
+			}
+
+			for _, format := range []WireFormat{WireFormatMinorUnits, WireFormatDecimalString} {
+				we := WireEncoding{Format: format}
+				v, err := we.Cents(amount).Value()
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				ec := we.Cents(0)
+				if err := ec.Scan(read(v)); err != nil || ec.Amount != amount {
+					t.Errorf("Testing format %d %v %s. Expected %v; got %v (%v)", format, amount, name, amount, ec.Amount, err)
+				}
+			}
+		}
+	}
+}
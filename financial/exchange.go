@@ -0,0 +1,338 @@
+package financial
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrRateNotFound is returned by an ExchangeRateProvider that has no rate for the currencies and date
+var ErrRateNotFound = errors.New("exchange rate not found")
+
+const rateDateFormat = "2006-01-02"
+
+// ExchangeRate is the number of units of To that one unit of From buys on Date
+type ExchangeRate struct {
+	From string    `json:"from" bson:"from"`
+	To   string    `json:"to" bson:"to"`
+	Date time.Time `json:"date" bson:"date"`
+	Rate float64   `json:"rate" bson:"rate"`
+}
+
+// ExchangeRateProvider finds the exchange rate between two currencies on a date
+type ExchangeRateProvider interface {
+	Rate(from, to string, date time.Time) (ExchangeRate, error)
+}
+
+// StaticRateProvider is an in-memory ExchangeRateProvider, holding rates against a single base
+// currency, just as the ECB publishes them against EUR.  Rates between any other two currencies
+// are crossed through the base.  If there is no rate on the day asked for, the most recent
+// rate before it is used, because rates aren't published at weekends or on holidays.
+type StaticRateProvider struct {
+	Base string
+
+	// dates are kept sorted, and rates are keyed by date then currency
+	dates []string
+	rates map[string]map[string]float64
+}
+
+func NewStaticRateProvider(base string) *StaticRateProvider {
+	return &StaticRateProvider{
+		Base:  strings.ToUpper(base),
+		rates: map[string]map[string]float64{},
+	}
+}
+
+// SetRate sets the number of units of the currency that one unit of the base currency buys on the date
+func (p *StaticRateProvider) SetRate(date time.Time, currency string, rate float64) {
+	day := date.Format(rateDateFormat)
+
+	if _, ok := p.rates[day]; !ok {
+		p.rates[day] = map[string]float64{}
+		i := sort.SearchStrings(p.dates, day)
+		p.dates = append(p.dates, "")
+		copy(p.dates[i+1:], p.dates[i:])
+		p.dates[i] = day
+	}
+
+	p.rates[day][strings.ToUpper(currency)] = rate
+}
+
+// baseRate finds the rate for a single currency against the base, on or before the date
+func (p *StaticRateProvider) baseRate(currency string, date time.Time) (float64, time.Time, error) {
+	if currency == p.Base {
+		return 1, date, nil
+	}
+
+	day := date.Format(rateDateFormat)
+
+	// Latest date that is on or before the one we want
+	for i := sort.SearchStrings(p.dates, day+"~") - 1; i >= 0; i-- {
+		if rate, ok := p.rates[p.dates[i]][currency]; ok {
+			found, _ := time.Parse(rateDateFormat, p.dates[i])
+			return rate, found, nil
+		}
+	}
+
+	return 0, time.Time{}, fmt.Errorf("%w: %s/%s on %s", ErrRateNotFound, p.Base, currency, day)
+}
+
+func (p *StaticRateProvider) Rate(from, to string, date time.Time) (ExchangeRate, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	res := ExchangeRate{From: from, To: to, Date: date, Rate: 1}
+
+	if from == to {
+		return res, nil
+	}
+
+	fromRate, fromDate, err := p.baseRate(from, date)
+	if err != nil {
+		return res, err
+	}
+
+	toRate, toDate, err := p.baseRate(to, date)
+	if err != nil {
+		return res, err
+	}
+
+	if fromRate == 0 {
+		return res, fmt.Errorf("%w: %s has a zero rate", ErrRateNotFound, from)
+	}
+
+	// Record the date of the rates actually used, the older of the two if they differ
+	res.Date = fromDate
+	if toDate.Before(fromDate) {
+		res.Date = toDate
+	}
+
+	res.Rate = ratFloat(new(big.Rat).Quo(decimalRat(toRate), decimalRat(fromRate)))
+	return res, nil
+}
+
+// ECB reference rate files
+
+type ecbEnvelope struct {
+	Days []ecbDay `xml:"Cube>Cube"`
+}
+
+type ecbDay struct {
+	Time  string    `xml:"time,attr"`
+	Rates []ecbRate `xml:"Cube"`
+}
+
+type ecbRate struct {
+	Currency string `xml:"currency,attr"`
+	Rate     string `xml:"rate,attr"`
+}
+
+const ecbBase = "EUR"
+
+// LoadECBXML reads the ECB's euro foreign exchange reference rates in their XML format,
+// either the daily file or the historical ones (eurofxref-daily.xml, eurofxref-hist.xml)
+func LoadECBXML(r io.Reader) (*StaticRateProvider, error) {
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, err
+	}
+
+	p := NewStaticRateProvider(ecbBase)
+	for _, day := range envelope.Days {
+		date, err := time.Parse(rateDateFormat, day.Time)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, rate := range day.Rates {
+			f, err := strconv.ParseFloat(rate.Rate, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rate for %s on %s: %w", rate.Currency, day.Time, err)
+			}
+			p.SetRate(date, rate.Currency, f)
+		}
+	}
+
+	return p, nil
+}
+
+// ecbCSVDateFormats are the formats used by the historical and daily CSV files respectively
+var ecbCSVDateFormats = []string{rateDateFormat, "2 January 2006"}
+
+func parseECBCSVDate(s string) (t time.Time, err error) {
+	for _, format := range ecbCSVDateFormats {
+		if t, err = time.Parse(format, s); err == nil {
+			return
+		}
+	}
+
+	return
+}
+
+// LoadECBCSV reads the ECB's euro foreign exchange reference rates in their CSV format,
+// which is a header row of currencies followed by a row per date.  Blank and N/A rates are skipped.
+func LoadECBCSV(r io.Reader) (*StaticRateProvider, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, err
+	}
+
+	p := NewStaticRateProvider(ecbBase)
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(row) == 0 || strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+
+		date, err := parseECBCSVDate(strings.TrimSpace(row[0]))
+		if err != nil {
+			return nil, err
+		}
+
+		for i := 1; i < len(row) && i < len(header); i++ {
+			currency, value := strings.TrimSpace(header[i]), strings.TrimSpace(row[i])
+			if currency == "" || value == "" || value == "N/A" {
+				continue
+			}
+
+			f, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid rate for %s on %s: %w", currency, row[0], err)
+			}
+			p.SetRate(date, currency, f)
+		}
+	}
+
+	return p, nil
+}
+
+// LoadRatesFile loads an ECB style rate snapshot from disk, choosing the format from the file extension
+func LoadRatesFile(path string) (*StaticRateProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".xml":
+		return LoadECBXML(f)
+	case ".csv":
+		return LoadECBCSV(f)
+	}
+
+	return nil, fmt.Errorf("unsupported exchange rate file: %s", path)
+}
+
+// Conversion
+
+// convertAmount converts an amount in the minor units of one currency to the minor units of another
+func convertAmount(c Cents, rate ExchangeRate, mode RoundingMode) Cents {
+	r := new(big.Rat).Mul(centsRat(c), decimalRat(rate.Rate))
+
+	// Shift for any difference in minor units, e.g. GBP has 2 but JPY has none
+	shift := CurrencyExponent(rate.To) - CurrencyExponent(rate.From)
+	scale := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(shift))), nil))
+	if shift >= 0 {
+		r.Mul(r, scale)
+	} else {
+		r.Quo(r, scale)
+	}
+
+	return roundRat(r, mode)
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+
+	return i
+}
+
+// ConvertTo converts the Money to another currency, returning the rate it used
+func (m Money) ConvertTo(currency string, provider ExchangeRateProvider, date time.Time) (Money, ExchangeRate, error) {
+	return m.ConvertToRounded(currency, provider, date, RoundHalfUp)
+}
+
+func (m Money) ConvertToRounded(currency string, provider ExchangeRateProvider, date time.Time, mode RoundingMode) (Money, ExchangeRate, error) {
+	rate, err := provider.Rate(m.Currency, currency, date)
+	if err != nil {
+		return Money{}, rate, err
+	}
+
+	return Money{Amount: convertAmount(m.Amount, rate, mode), Currency: strings.ToUpper(currency)}, rate, nil
+}
+
+// Conversion is the result of converting a CentDict of currency balances into a single currency.
+// Each balance is converted and rounded on its own, and then they are added up.
+type Conversion struct {
+	Currency     string
+	RoundingMode RoundingMode
+	Total        Cents
+
+	// Converted is each of the original balances in the new currency, keyed by the original currency
+	Converted CentDict
+	// Rates are the rates that were used, in currency order
+	Rates []ExchangeRate
+}
+
+// ConvertTo converts each of the currency balances in the CentDict to the one currency,
+// rounding half up, and records the rates that were used
+func (cd CentDict) ConvertTo(currency string, provider ExchangeRateProvider, date time.Time) (Conversion, error) {
+	return cd.ConvertToRounded(currency, provider, date, RoundHalfUp)
+}
+
+func (cd CentDict) ConvertToRounded(currency string, provider ExchangeRateProvider, date time.Time, mode RoundingMode) (Conversion, error) {
+	currency = strings.ToUpper(currency)
+	res := Conversion{
+		Currency:     currency,
+		RoundingMode: mode,
+		Converted:    CentDict{},
+	}
+
+	keys := make([]string, 0, len(cd))
+	for k := range cd {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		amount := cd[k]
+		if strings.EqualFold(k, currency) {
+			res.Converted.AddToKey(k, amount)
+			res.Total += amount
+			continue
+		}
+
+		rate, err := provider.Rate(k, currency, date)
+		if err != nil {
+			return res, err
+		}
+
+		converted := convertAmount(amount, rate, mode)
+		res.Converted.AddToKey(k, converted)
+		res.Rates = append(res.Rates, rate)
+		res.Total += converted
+	}
+
+	return res, nil
+}
@@ -0,0 +1,166 @@
+package financial
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func rateDate(s string) time.Time {
+	t, _ := time.Parse(rateDateFormat, s)
+	return t
+}
+
+func TestLoadECBRates(t *testing.T) {
+	for _, path := range []string{"testdata/eurofxref-hist.xml", "testdata/eurofxref-hist.csv", "testdata/eurofxref.csv"} {
+		p, err := LoadRatesFile(path)
+		if err != nil {
+			t.Fatalf("Testing %s. Loading failed: %s", path, err)
+		}
+
+		rate, err := p.Rate("EUR", "GBP", rateDate("2021-01-29"))
+		if err != nil {
+			t.Errorf("Testing %s. Unexpected error: %s", path, err)
+		}
+		if rate.Rate != 0.88525 {
+			t.Errorf("Testing %s. Expected %v; got %v", path, 0.88525, rate.Rate)
+		}
+
+		if _, err := p.Rate("EUR", "ISK", rateDate("2021-01-29")); !errors.Is(err, ErrRateNotFound) {
+			t.Errorf("Testing %s. Expected ErrRateNotFound for a N/A rate; got %v", path, err)
+		}
+	}
+
+	if _, err := LoadRatesFile("testdata/eurofxref.json"); err == nil {
+		t.Errorf("Testing unsupported file. Expected an error but didn't get one")
+	}
+}
+
+func TestStaticRateProvider(t *testing.T) {
+	p, err := LoadRatesFile("testdata/eurofxref-hist.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		from, to     string
+		date         string
+		expectedDate string
+		expected     float64
+	}{
+		{"direct", "EUR", "USD", "2021-01-28", "2021-01-28", 1.2093},
+		{"lower case", "eur", "usd", "2021-01-28", "2021-01-28", 1.2093},
+		{"same currency", "GBP", "GBP", "2021-01-29", "2021-01-29", 1},
+		{"weekend uses the Friday rate", "EUR", "JPY", "2021-01-31", "2021-01-29", 127.05},
+		{"inverse", "CHF", "EUR", "2021-01-29", "2021-01-29", 1 / 1.0802},
+		{"cross", "GBP", "CHF", "2021-01-29", "2021-01-29", 1.0802 / 0.88525},
+	}
+
+	for _, test := range tests {
+		res, err := p.Rate(test.from, test.to, rateDate(test.date))
+		if err != nil {
+			t.Errorf("Testing %s. Unexpected error: %s", test.name, err)
+			continue
+		}
+
+		if res.Rate != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res.Rate)
+		}
+
+		if !res.Date.Equal(rateDate(test.expectedDate)) {
+			t.Errorf("Testing %s. Expected date %v; got %v", test.name, test.expectedDate, res.Date)
+		}
+	}
+
+	if _, err := p.Rate("EUR", "GBP", rateDate("2021-01-27")); !errors.Is(err, ErrRateNotFound) {
+		t.Errorf("Testing before first date. Expected ErrRateNotFound; got %v", err)
+	}
+
+	if _, err := p.Rate("XXX", "GBP", rateDate("2021-01-29")); !errors.Is(err, ErrRateNotFound) {
+		t.Errorf("Testing unknown currency. Expected ErrRateNotFound; got %v", err)
+	}
+}
+
+func TestMoneyConvertTo(t *testing.T) {
+	p, err := LoadRatesFile("testdata/eurofxref-hist.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	date := rateDate("2021-01-29")
+
+	tests := []struct {
+		name     string
+		m        Money
+		currency string
+		mode     RoundingMode
+		expected Money
+	}{
+		{"half up", Money{10000, "EUR"}, "GBP", RoundHalfUp, Money{8853, "GBP"}},
+		{"half even", Money{10000, "EUR"}, "GBP", RoundHalfEven, Money{8852, "GBP"}},
+		{"negative", Money{-10000, "EUR"}, "GBP", RoundHalfUp, Money{-8853, "GBP"}},
+		{"inverse", Money{10000, "GBP"}, "EUR", RoundHalfUp, Money{11296, "EUR"}},
+		{"to currency without minor units", Money{10000, "EUR"}, "JPY", RoundHalfUp, Money{12705, "JPY"}},
+		{"from currency without minor units", Money{12705, "JPY"}, "EUR", RoundHalfUp, Money{10000, "EUR"}},
+	}
+
+	for _, test := range tests {
+		res, rate, err := test.m.ConvertToRounded(test.currency, p, date, test.mode)
+		if err != nil {
+			t.Errorf("Testing %s. Unexpected error: %s", test.name, err)
+			continue
+		}
+
+		if res != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+
+		if rate.From != test.m.Currency || rate.To != test.currency {
+			t.Errorf("Testing %s. Wrong rate recorded: %v", test.name, rate)
+		}
+	}
+}
+
+func TestCentDictConvertTo(t *testing.T) {
+	p, err := LoadRatesFile("testdata/eurofxref-hist.csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cd := CentDict{"EUR": 10000, "GBP": 10000, "USD": 10000}
+	res, err := cd.ConvertTo("gbp", p, rateDate("2021-01-30"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := CentDict{"EUR": 8853, "GBP": 10000, "USD": 7294}
+	if len(res.Converted) != len(expected) {
+		t.Errorf("Testing converted. Expected %v; got %v", expected, res.Converted)
+	}
+	for k, v := range expected {
+		if res.Converted[k] != v {
+			t.Errorf("Testing converted %s. Expected %v; got %v", k, v, res.Converted[k])
+		}
+	}
+
+	if res.Total != 26147 {
+		t.Errorf("Testing total. Expected %v; got %v", 26147, res.Total)
+	}
+
+	if res.Currency != "GBP" {
+		t.Errorf("Testing currency. Expected %v; got %v", "GBP", res.Currency)
+	}
+
+	// The rates used, in currency order, but not the GBP balance, which didn't need one
+	if len(res.Rates) != 2 || res.Rates[0].From != "EUR" || res.Rates[1].From != "USD" {
+		t.Fatalf("Testing rates. Expected EUR and USD rates; got %v", res.Rates)
+	}
+	if !res.Rates[0].Date.Equal(rateDate("2021-01-29")) {
+		t.Errorf("Testing rate date. Expected %v; got %v", "2021-01-29", res.Rates[0].Date)
+	}
+
+	if _, err := (CentDict{"ISK": 100}).ConvertTo("GBP", p, rateDate("2021-01-29")); !errors.Is(err, ErrRateNotFound) {
+		t.Errorf("Testing missing rate. Expected ErrRateNotFound; got %v", err)
+	}
+}
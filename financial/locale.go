@@ -0,0 +1,330 @@
+package financial
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Characters used by CLDR in number and currency patterns which
+// are easy to confuse with ordinary spaces, quotes and hyphens
+const (
+	nbsp           = "\u00a0" // no-break space
+	narrowNbsp     = "\u202f" // narrow no-break space
+	rightQuote     = "\u2019" // right single quotation mark, used for grouping in Switzerland
+	unicodeMinus   = "\u2212" // minus sign
+	hyphenMinus    = "-"
+	accountingOpen = "("
+	accountingEnd  = ")"
+)
+
+// Locale holds the number and currency formatting conventions for a locale.
+// The data for the bundled locales is taken from the CLDR (https://cldr.unicode.org)
+// so that no lookups are needed at runtime.  A Locale can also be constructed by hand
+// for anything not bundled.
+type Locale struct {
+	Tag string
+
+	Decimal string
+	Group   string
+	Minus   string
+
+	// Grouping sizes, e.g. 3 and 3 for 1,234,567 or 3 and 2 for the Indian 12,34,567.
+	// MinGroupingDigits is the CLDR setting which stops, e.g., Spanish grouping four digit numbers.
+	PrimaryGrouping, SecondaryGrouping, MinGroupingDigits int
+
+	// SymbolBefore places the currency symbol before the number and SymbolSpace
+	// is anything that should come between the symbol and the number
+	SymbolBefore bool
+	SymbolSpace  string
+
+	// MinusAfterSymbol is for locales such as nl-NL that write € -1.234,56
+	// and MinusReplacesSymbolSpace for those like de-CH that write CHF-1’234.56
+	MinusAfterSymbol, MinusReplacesSymbolSpace bool
+
+	// AccountingParens is set where the CLDR accounting format shows negatives in parentheses
+	AccountingParens bool
+
+	// Symbols are the locale's own currency symbols, which take priority over the defaults
+	Symbols map[string]string
+}
+
+// FormatOptions changes the output of a locale formatted price
+type FormatOptions struct {
+	// Accounting shows negatives as (1,234.56) in locales that support it
+	Accounting bool
+	// HideSymbol leaves out the currency symbol altogether
+	HideSymbol bool
+}
+
+// defaultCurrencySymbols are the CLDR root symbols, used if the locale doesn't have its own
+var defaultCurrencySymbols = map[string]string{
+	"AUD": "A$",
+	"BRL": "R$",
+	"CAD": "CA$",
+	"CNY": "CN¥",
+	"EUR": "€",
+	"GBP": "£",
+	"HKD": "HK$",
+	"ILS": "₪",
+	"INR": "₹",
+	"JPY": "JP¥",
+	"KRW": "₩",
+	"MXN": "MX$",
+	"NZD": "NZ$",
+	"TWD": "NT$",
+	"USD": "US$",
+	"VND": "₫",
+}
+
+var englishGrouping = Locale{
+	Decimal:           ".",
+	Group:             ",",
+	Minus:             hyphenMinus,
+	PrimaryGrouping:   3,
+	SecondaryGrouping: 3,
+	MinGroupingDigits: 1,
+	SymbolBefore:      true,
+	AccountingParens:  true,
+}
+
+func withTag(l Locale, tag string, symbols map[string]string) Locale {
+	l.Tag = tag
+	l.Symbols = symbols
+	return l
+}
+
+var continentalGrouping = Locale{
+	Decimal:           ",",
+	Group:             ".",
+	Minus:             hyphenMinus,
+	PrimaryGrouping:   3,
+	SecondaryGrouping: 3,
+	MinGroupingDigits: 1,
+	SymbolSpace:       nbsp,
+}
+
+var swissGrouping = Locale{
+	Decimal:           ".",
+	Group:             rightQuote,
+	Minus:             hyphenMinus,
+	PrimaryGrouping:   3,
+	SecondaryGrouping: 3,
+	MinGroupingDigits: 1,
+	SymbolBefore:      true,
+	SymbolSpace:       nbsp,
+	MinusAfterSymbol:  true,
+
+	MinusReplacesSymbolSpace: true,
+}
+
+var locales = map[string]Locale{
+	"en-GB": withTag(englishGrouping, "en-GB", map[string]string{}),
+	"en-IE": withTag(englishGrouping, "en-IE", map[string]string{}),
+	"en-US": withTag(englishGrouping, "en-US", map[string]string{"USD": "$", "JPY": "¥"}),
+	"en-AU": withTag(englishGrouping, "en-AU", map[string]string{"AUD": "$", "USD": "USD"}),
+	"en-CA": withTag(englishGrouping, "en-CA", map[string]string{"CAD": "$"}),
+	"en-IN": func() Locale {
+		l := withTag(englishGrouping, "en-IN", map[string]string{"USD": "$"})
+		l.SecondaryGrouping = 2
+		return l
+	}(),
+	"ja-JP": withTag(englishGrouping, "ja-JP", map[string]string{"JPY": "￥", "USD": "$"}),
+
+	"de-DE": withTag(continentalGrouping, "de-DE", map[string]string{"USD": "$", "JPY": "¥"}),
+	"it-IT": withTag(continentalGrouping, "it-IT", map[string]string{"USD": "USD", "JPY": "JPY"}),
+	"es-ES": func() Locale {
+		l := withTag(continentalGrouping, "es-ES", map[string]string{"USD": "US$", "JPY": "JPY"})
+		l.MinGroupingDigits = 2
+		return l
+	}(),
+	"nl-NL": func() Locale {
+		l := withTag(continentalGrouping, "nl-NL", map[string]string{"USD": "US$", "JPY": "JP¥"})
+		l.SymbolBefore = true
+		l.MinusAfterSymbol = true
+		l.AccountingParens = true
+		return l
+	}(),
+	"fr-FR": func() Locale {
+		l := withTag(continentalGrouping, "fr-FR", map[string]string{"USD": "$US", "JPY": "JPY", "GBP": "£GB"})
+		l.Group = narrowNbsp
+		l.AccountingParens = true
+		return l
+	}(),
+	"fr-CA": func() Locale {
+		l := withTag(continentalGrouping, "fr-CA", map[string]string{"CAD": "$", "USD": "$" + nbsp + "US"})
+		l.Group = nbsp
+		l.AccountingParens = true
+		return l
+	}(),
+	"sv-SE": func() Locale {
+		l := withTag(continentalGrouping, "sv-SE", map[string]string{"SEK": "kr", "USD": "US$"})
+		l.Group = nbsp
+		l.Minus = unicodeMinus
+		return l
+	}(),
+	"pl-PL": func() Locale {
+		l := withTag(continentalGrouping, "pl-PL", map[string]string{"PLN": "zł", "USD": "USD"})
+		l.Group = nbsp
+		l.MinGroupingDigits = 2
+		return l
+	}(),
+
+	"de-CH": withTag(swissGrouping, "de-CH", map[string]string{"USD": "$"}),
+	"it-CH": withTag(swissGrouping, "it-CH", map[string]string{"USD": "USD"}),
+}
+
+// normaliseLocaleTag turns en_gb, EN-GB etc into en-GB
+func normaliseLocaleTag(tag string) string {
+	parts := strings.Split(strings.Replace(strings.TrimSpace(tag), "_", "-", -1), "-")
+	parts[0] = strings.ToLower(parts[0])
+	if len(parts) > 1 {
+		parts[1] = strings.ToUpper(parts[1])
+	}
+
+	return strings.Join(parts, "-")
+}
+
+// LookupLocale finds one of the bundled locales by its BCP 47 tag, e.g. "en-GB" or "de_CH"
+func LookupLocale(tag string) (Locale, error) {
+	l, ok := locales[normaliseLocaleTag(tag)]
+	if !ok {
+		return Locale{}, fmt.Errorf("unsupported locale: %q", tag)
+	}
+
+	return l, nil
+}
+
+// CurrencySymbol is the symbol the locale uses for the currency, falling back
+// to the CLDR default and then to the currency code itself
+func (l Locale) CurrencySymbol(currency string) string {
+	currency = strings.ToUpper(currency)
+	if s, ok := l.Symbols[currency]; ok {
+		return s
+	}
+
+	if s, ok := defaultCurrencySymbols[currency]; ok {
+		return s
+	}
+
+	return currency
+}
+
+// groupDigits inserts the group separators into a string of integer digits
+func (l Locale) groupDigits(digits string) string {
+	primary, secondary := l.PrimaryGrouping, l.SecondaryGrouping
+	if secondary <= 0 {
+		secondary = primary
+	}
+
+	minGrouping := l.MinGroupingDigits
+	if minGrouping < 1 {
+		minGrouping = 1
+	}
+
+	if primary <= 0 || len(digits) < primary+minGrouping {
+		return digits
+	}
+
+	groups := []string{digits[len(digits)-primary:]}
+	digits = digits[:len(digits)-primary]
+
+	for len(digits) > secondary {
+		groups = append([]string{digits[len(digits)-secondary:]}, groups...)
+		digits = digits[:len(digits)-secondary]
+	}
+	groups = append([]string{digits}, groups...)
+
+	return strings.Join(groups, l.Group)
+}
+
+// formatNumber formats the amount using the locale's separators but without any currency symbol,
+// returning the sign separately
+func (l Locale) formatNumber(c Cents, exponent int) (string, bool) {
+	plain := formatMinorUnits(c, exponent)
+	isNegative := strings.HasPrefix(plain, "-")
+	plain = strings.TrimPrefix(plain, "-")
+
+	whole, frac := plain, ""
+	if i := strings.IndexByte(plain, '.'); i >= 0 {
+		whole, frac = plain[:i], plain[i+1:]
+	}
+
+	res := l.groupDigits(whole)
+	if frac != "" {
+		res = res + l.Decimal + frac
+	}
+
+	return res, isNegative
+}
+
+// symbolSpacing follows the CLDR currency spacing rule: if the symbol doesn't itself end
+// (or start, if it comes after the number) with a symbol character, such as "CHF" or "kr",
+// a no-break space is put between it and the digits
+func (l Locale) symbolSpacing(symbol string) string {
+	if l.SymbolSpace != "" || symbol == "" {
+		return l.SymbolSpace
+	}
+
+	var r rune
+	if l.SymbolBefore {
+		r, _ = utf8.DecodeLastRuneInString(symbol)
+	} else {
+		r, _ = utf8.DecodeRuneInString(symbol)
+	}
+
+	if unicode.IsSymbol(r) {
+		return ""
+	}
+
+	return nbsp
+}
+
+// FormatPrice formats an amount of the currency according to the locale
+func (l Locale) FormatPrice(c Cents, currency string, opts FormatOptions) string {
+	number, isNegative := l.formatNumber(c, CurrencyExponent(currency))
+
+	symbol := ""
+	if !opts.HideSymbol {
+		symbol = l.CurrencySymbol(currency)
+	}
+	space := l.symbolSpacing(symbol)
+
+	useParens := isNegative && opts.Accounting && l.AccountingParens
+
+	minus := ""
+	if isNegative && !useParens {
+		minus = l.Minus
+	}
+
+	var res string
+	switch {
+	case symbol == "":
+		res = minus + number
+	case l.SymbolBefore && l.MinusAfterSymbol && minus != "" && l.MinusReplacesSymbolSpace:
+		res = symbol + minus + number
+	case l.SymbolBefore && l.MinusAfterSymbol:
+		res = symbol + space + minus + number
+	case l.SymbolBefore:
+		res = minus + symbol + space + number
+	default:
+		res = minus + number + space + symbol
+	}
+
+	if useParens {
+		res = accountingOpen + res + accountingEnd
+	}
+
+	return res
+}
+
+// FormatLocale formats the amount according to a locale, e.g. "1.234,56 €" for de-DE
+func (c Cents) FormatLocale(l Locale, currency string, opts FormatOptions) string {
+	return l.FormatPrice(c, currency, opts)
+}
+
+// FormatLocale formats the Money according to a locale, e.g. "CHF 1’234.56" for de-CH
+func (m Money) FormatLocale(l Locale, opts FormatOptions) string {
+	return l.FormatPrice(m.Amount, m.Currency, opts)
+}
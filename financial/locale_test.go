@@ -0,0 +1,111 @@
+package financial
+
+import "testing"
+
+func TestFormatLocale(t *testing.T) {
+	// Expected strings spell out the no-break and narrow no-break spaces, the Swiss grouping
+	// quote and the minus sign as escapes so they can't be mistaken for plain ASCII
+	tests := []struct {
+		locale   string
+		currency string
+		c        Cents
+		opts     FormatOptions
+		expected string
+	}{
+		// English style
+		{"en-GB", "GBP", 123456, FormatOptions{}, "£1,234.56"},
+		{"en-GB", "GBP", -123456, FormatOptions{}, "-£1,234.56"},
+		{"en-GB", "GBP", -123456, FormatOptions{Accounting: true}, "(£1,234.56)"},
+		{"en-GB", "GBP", 123456, FormatOptions{HideSymbol: true}, "1,234.56"},
+		{"en-GB", "GBP", 5, FormatOptions{}, "£0.05"},
+		{"en-GB", "GBP", 123456789, FormatOptions{}, "£1,234,567.89"},
+		{"en-GB", "EUR", 123456, FormatOptions{}, "€1,234.56"},
+		{"en-GB", "USD", 123456, FormatOptions{}, "US$1,234.56"},
+		{"en-GB", "CHF", 123456, FormatOptions{}, "CHF\u00a01,234.56"},
+		{"en-IE", "EUR", 99999, FormatOptions{}, "€999.99"},
+		{"en-US", "USD", 123456, FormatOptions{}, "$1,234.56"},
+		{"en-US", "USD", -123456, FormatOptions{Accounting: true}, "($1,234.56)"},
+		{"en-US", "JPY", 123456, FormatOptions{}, "¥123,456"},
+		{"en-AU", "AUD", 123456, FormatOptions{}, "$1,234.56"},
+		{"en-CA", "CAD", 123456, FormatOptions{}, "$1,234.56"},
+		{"en-IN", "INR", 1234567890, FormatOptions{}, "₹1,23,45,678.90"},
+		{"ja-JP", "JPY", 1234567, FormatOptions{}, "￥1,234,567"},
+
+		// Continental
+		{"de-DE", "EUR", 123456, FormatOptions{}, "1.234,56\u00a0€"},
+		{"de-DE", "EUR", -123456, FormatOptions{}, "-1.234,56\u00a0€"},
+		{"de-DE", "EUR", -123456, FormatOptions{Accounting: true}, "-1.234,56\u00a0€"},
+		{"de-DE", "USD", 123456, FormatOptions{}, "1.234,56\u00a0$"},
+		{"it-IT", "EUR", 123456789, FormatOptions{}, "1.234.567,89\u00a0€"},
+		{"es-ES", "EUR", 123456, FormatOptions{}, "1234,56\u00a0€"},
+		{"es-ES", "EUR", 1234567, FormatOptions{}, "12.345,67\u00a0€"},
+		{"nl-NL", "EUR", 123456, FormatOptions{}, "€\u00a01.234,56"},
+		{"nl-NL", "EUR", -123456, FormatOptions{}, "€\u00a0-1.234,56"},
+		{"nl-NL", "EUR", -123456, FormatOptions{Accounting: true}, "(€\u00a01.234,56)"},
+		{"fr-FR", "EUR", 123456, FormatOptions{}, "1\u202f234,56\u00a0€"},
+		{"fr-FR", "EUR", -123456, FormatOptions{Accounting: true}, "(1\u202f234,56\u00a0€)"},
+		{"fr-FR", "GBP", 100, FormatOptions{}, "1,00\u00a0£GB"},
+		{"fr-CA", "CAD", 123456, FormatOptions{}, "1\u00a0234,56\u00a0$"},
+		{"sv-SE", "SEK", 123456, FormatOptions{}, "1\u00a0234,56\u00a0kr"},
+		{"sv-SE", "SEK", -123456, FormatOptions{}, "\u22121\u00a0234,56\u00a0kr"},
+		{"pl-PL", "PLN", 123456, FormatOptions{}, "1234,56\u00a0zł"},
+		{"pl-PL", "PLN", 1234567, FormatOptions{}, "12\u00a0345,67\u00a0zł"},
+
+		// Switzerland
+		{"de-CH", "CHF", 123456, FormatOptions{}, "CHF\u00a01\u2019234.56"},
+		{"de-CH", "CHF", -123456, FormatOptions{}, "CHF-1\u2019234.56"},
+		{"de-CH", "EUR", 123456, FormatOptions{}, "€\u00a01\u2019234.56"},
+		{"it-CH", "CHF", 123456789, FormatOptions{}, "CHF\u00a01\u2019234\u2019567.89"},
+
+		// Minor units
+		{"en-GB", "KWD", 1234567, FormatOptions{}, "KWD\u00a01,234.567"},
+		{"de-DE", "JPY", 1234, FormatOptions{}, "1.234\u00a0¥"},
+	}
+
+	for _, test := range tests {
+		l, err := LookupLocale(test.locale)
+		if err != nil {
+			t.Fatalf("Testing %s. Unexpected error: %s", test.locale, err)
+		}
+
+		res := test.c.FormatLocale(l, test.currency, test.opts)
+		if res != test.expected {
+			t.Errorf("Testing %s %s %d. Expected %q; got %q", test.locale, test.currency, test.c, test.expected, res)
+		}
+	}
+}
+
+func TestLookupLocale(t *testing.T) {
+	tests := []struct {
+		tag       string
+		expected  string
+		expectErr bool
+	}{
+		{"en-GB", "en-GB", false},
+		{"en_gb", "en-GB", false},
+		{"DE-ch", "de-CH", false},
+		{"xx-YY", "", true},
+		{"", "", true},
+	}
+
+	for _, test := range tests {
+		l, err := LookupLocale(test.tag)
+		if test.expectErr && err == nil {
+			t.Errorf("Testing %q. Expected an error but didn't get one", test.tag)
+		}
+		if !test.expectErr && err != nil {
+			t.Errorf("Testing %q. Not expecting an error but got: %s", test.tag, err)
+		}
+		if l.Tag != test.expected {
+			t.Errorf("Testing %q. Expected tag %q; got %q", test.tag, test.expected, l.Tag)
+		}
+	}
+}
+
+func TestMoneyFormatLocale(t *testing.T) {
+	l, _ := LookupLocale("de-CH")
+	res := Money{Amount: 500, Currency: "CHF"}.FormatLocale(l, FormatOptions{})
+	if res != "CHF\u00a05.00" {
+		t.Errorf("Expected %q; got %q", "CHF\u00a05.00", res)
+	}
+}
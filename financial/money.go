@@ -0,0 +1,196 @@
+package financial
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCurrencyMismatch is returned when trying to combine Money in two different currencies
+var ErrCurrencyMismatch = errors.New("currency mismatch")
+
+// Money pairs an amount in minor units with the ISO 4217 code of its currency,
+// so that, unlike bare Cents, amounts in different currencies can't be mixed up
+type Money struct {
+	Amount   Cents  `json:"amount" bson:"amount"`
+	Currency string `json:"currency" bson:"currency"`
+}
+
+// NewMoney validates the currency code and returns a Money
+func NewMoney(amount Cents, currency string) (Money, error) {
+	c, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: amount, Currency: c.Code}, nil
+}
+
+// ParseMoney parses a plain decimal string such as "1234.56" in the given currency.
+// The number of decimal places cannot exceed the exponent of the currency.
+// Use ParseMoneyLocale for anything written for humans, with thousands separators.
+func ParseMoney(s, currency string) (Money, error) {
+	c, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	amount, err := parsePrice(s, Locale{}, c.Code)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: amount, Currency: c.Code}, nil
+}
+
+func currencyMismatch(a, b string) error {
+	return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, a, b)
+}
+
+// Exponent is the number of minor unit digits in the Money's currency
+func (m Money) Exponent() int {
+	return CurrencyExponent(m.Currency)
+}
+
+func (m Money) withAmount(amount Cents) Money {
+	return Money{Amount: amount, Currency: m.Currency}
+}
+
+// SameCurrency is true if both amounts are in the same currency
+func (m Money) SameCurrency(o Money) bool {
+	return strings.EqualFold(m.Currency, o.Currency)
+}
+
+func (m Money) checkCurrency(o Money) error {
+	if !m.SameCurrency(o) {
+		return currencyMismatch(m.Currency, o.Currency)
+	}
+
+	return nil
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func (m Money) Negate() Money {
+	return m.withAmount(-m.Amount)
+}
+
+// Add is checked like Cents.Add, so on overflow it returns ErrOverflow along with the saturated amount
+func (m Money) Add(o Money) (Money, error) {
+	if err := m.checkCurrency(o); err != nil {
+		return Money{}, err
+	}
+
+	amount, err := m.Amount.Add(o.Amount)
+	return m.withAmount(amount), err
+}
+
+// Sub is checked like Cents.Sub, so on overflow it returns ErrOverflow along with the saturated amount
+func (m Money) Sub(o Money) (Money, error) {
+	if err := m.checkCurrency(o); err != nil {
+		return Money{}, err
+	}
+
+	amount, err := m.Amount.Sub(o.Amount)
+	return m.withAmount(amount), err
+}
+
+// Compare returns -1, 0 or 1 if m is less than, equal to or greater than o
+func (m Money) Compare(o Money) (int, error) {
+	if err := m.checkCurrency(o); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case m.Amount < o.Amount:
+		return -1, nil
+	case m.Amount > o.Amount:
+		return 1, nil
+	}
+
+	return 0, nil
+}
+
+func (m Money) ByQty(qty int) Money {
+	return m.withAmount(m.Amount.ByQty(qty))
+}
+
+func (m Money) ByPercentage(pc float64) Money {
+	return m.withAmount(m.Amount.ByPercentage(pc))
+}
+
+func (m Money) RemovePercentage(pc float64) Money {
+	return m.withAmount(m.Amount.RemovePercentage(pc))
+}
+
+// Format formats the amount as a plain decimal with the correct number of
+// decimal places for the currency, e.g. "1234.56", "1234" for JPY or "1.234" for KWD
+func (m Money) Format() string {
+	return formatMinorUnits(m.Amount, m.Exponent())
+}
+
+func (m Money) String() string {
+	return m.Currency + " " + m.Format()
+}
+
+// MoneyTaxCalc is a TaxCalc that knows its currency
+type MoneyTaxCalc struct {
+	Currency string
+	TaxCalc
+}
+
+func (m Money) AddTax(taxPercentage float64) MoneyTaxCalc {
+	return MoneyTaxCalc{
+		Currency: m.Currency,
+		TaxCalc:  m.Amount.AddTax(taxPercentage),
+	}
+}
+
+func (m Money) RemoveTax(taxPercentage float64) MoneyTaxCalc {
+	return MoneyTaxCalc{
+		Currency: m.Currency,
+		TaxCalc:  m.Amount.RemoveTax(taxPercentage),
+	}
+}
+
+func (mtx MoneyTaxCalc) Ex() Money {
+	return Money{Amount: mtx.LineEx, Currency: mtx.Currency}
+}
+
+func (mtx MoneyTaxCalc) Tax() Money {
+	return Money{Amount: mtx.LineTax, Currency: mtx.Currency}
+}
+
+func (mtx MoneyTaxCalc) Inc() Money {
+	return Money{Amount: mtx.LineInc, Currency: mtx.Currency}
+}
+
+// SumMoney adds up a list of Money, all of which must be in the same currency
+func SumMoney(currency string, ms ...Money) (Money, error) {
+	total := Money{Currency: strings.ToUpper(currency)}
+	for _, m := range ms {
+		var err error
+		if total, err = total.Add(m); err != nil {
+			return Money{}, err
+		}
+	}
+
+	return total, nil
+}
+
+// AddMoney adds the Money to the CentDict, keyed by its currency
+func (cd CentDict) AddMoney(m Money) {
+	cd.AddToKey(strings.ToUpper(m.Currency), m.Amount)
+}
+
+// Money returns the balance for a single currency in the CentDict
+func (cd CentDict) Money(currency string) Money {
+	currency = strings.ToUpper(currency)
+	return Money{Amount: cd[currency], Currency: currency}
+}
@@ -0,0 +1,134 @@
+package financial
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMoneyFormatAndParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		m        Money
+		expected string
+	}{
+		{"GBP zero", Money{0, "GBP"}, "0.00"},
+		{"GBP", Money{123456, "GBP"}, "1234.56"},
+		{"GBP negative", Money{-5, "GBP"}, "-0.05"},
+		{"JPY has no minor unit", Money{1234, "JPY"}, "1234"},
+		{"JPY negative", Money{-1234, "JPY"}, "-1234"},
+		{"KWD has 3 decimal places", Money{1234, "KWD"}, "1.234"},
+		{"KWD small", Money{5, "KWD"}, "0.005"},
+		{"CLF has 4 decimal places", Money{12345, "CLF"}, "1.2345"},
+	}
+
+	for _, test := range tests {
+		res := test.m.Format()
+		if res != test.expected {
+			t.Errorf("Testing %s. Formatting failed. Expected %v; got %v", test.name, test.expected, res)
+		}
+
+		parsed, err := ParseMoney(test.expected, test.m.Currency)
+		if err != nil {
+			t.Errorf("Testing %s. Parsing failed: %s", test.name, err)
+		}
+
+		if parsed != test.m {
+			t.Errorf("Testing %s. Parsing failed. Expected %v; got %v", test.name, test.m, parsed)
+		}
+	}
+}
+
+func TestParseMoneyErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		currency string
+	}{
+		{"unknown currency", "1.00", "XXY"},
+		{"blank", "", "GBP"},
+		{"too many decimals", "1.005", "GBP"},
+		{"decimals on JPY", "1.5", "JPY"},
+		{"exponent", "1e3", "GBP"},
+		{"two points", "1.2.3", "GBP"},
+		{"overflow", "999999999999999999999999", "GBP"},
+	}
+
+	for _, test := range tests {
+		if _, err := ParseMoney(test.s, test.currency); err == nil {
+			t.Errorf("Testing %s. Expected an error but didn't get one", test.name)
+		}
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	gbp := Money{1000, "GBP"}
+	eur := Money{1000, "EUR"}
+
+	sum, err := gbp.Add(Money{250, "gbp"})
+	if err != nil {
+		t.Fatalf("Unexpected error adding same currency: %s", err)
+	}
+	if sum != (Money{1250, "GBP"}) {
+		t.Errorf("Incorrect sum. Expected GBP 12.50; got %v", sum)
+	}
+
+	if _, err := gbp.Add(eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Expected currency mismatch adding GBP to EUR; got %v", err)
+	}
+
+	if _, err := gbp.Sub(eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Expected currency mismatch subtracting EUR from GBP; got %v", err)
+	}
+
+	if _, err := gbp.Compare(eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Expected currency mismatch comparing GBP to EUR; got %v", err)
+	}
+
+	if _, err := SumMoney("GBP", gbp, gbp, eur); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Expected currency mismatch summing mixed currencies; got %v", err)
+	}
+
+	if res := gbp.ByPercentage(17.5); res != (Money{175, "GBP"}) {
+		t.Errorf("Incorrect percentage. Expected GBP 1.75; got %v", res)
+	}
+
+	if res, err := (Money{maxCents, "GBP"}).Add(gbp); !errors.Is(err, ErrOverflow) || res != (Money{maxCents, "GBP"}) {
+		t.Errorf("Expected overflow adding to the largest amount; got %v (%v)", res, err)
+	}
+
+	if res, err := (Money{minCents, "GBP"}).Sub(gbp); !errors.Is(err, ErrOverflow) || res != (Money{minCents, "GBP"}) {
+		t.Errorf("Expected overflow subtracting from the smallest amount; got %v (%v)", res, err)
+	}
+
+	if _, err := SumMoney("GBP", Money{maxCents, "GBP"}, gbp); !errors.Is(err, ErrOverflow) {
+		t.Errorf("Expected overflow summing past the largest amount; got %v", err)
+	}
+}
+
+func TestMoneyAddTax(t *testing.T) {
+	tx := Money{1000, "JPY"}.AddTax(10)
+
+	if tx.Ex() != (Money{1000, "JPY"}) || tx.Tax() != (Money{100, "JPY"}) || tx.Inc() != (Money{1100, "JPY"}) {
+		t.Errorf("Incorrect tax calculation. Got ex %v, tax %v, inc %v", tx.Ex(), tx.Tax(), tx.Inc())
+	}
+
+	tx = Money{1200, "GBP"}.RemoveTax(20)
+	if tx.Ex() != (Money{1000, "GBP"}) || tx.Tax() != (Money{200, "GBP"}) {
+		t.Errorf("Incorrect tax removal. Got ex %v, tax %v", tx.Ex(), tx.Tax())
+	}
+}
+
+func TestCentDictMoney(t *testing.T) {
+	cd := CentDict{}
+	cd.AddMoney(Money{100, "gbp"})
+	cd.AddMoney(Money{200, "GBP"})
+	cd.AddMoney(Money{50, "EUR"})
+
+	if res := cd.Money("GBP"); res != (Money{300, "GBP"}) {
+		t.Errorf("Incorrect GBP balance. Expected GBP 3.00; got %v", res)
+	}
+
+	if res := cd.Money("eur"); res != (Money{50, "EUR"}) {
+		t.Errorf("Incorrect EUR balance. Expected EUR 0.50; got %v", res)
+	}
+}
@@ -0,0 +1,338 @@
+package financial
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Errors that can be returned when parsing a price.  They are always wrapped
+// in a PriceParseError, so use errors.Is to check for them
+var (
+	ErrEmptyPrice              = errors.New("empty price")
+	ErrInvalidCharacter        = errors.New("invalid character")
+	ErrMultipleSigns           = errors.New("more than one sign")
+	ErrTooManyDecimalPlaces    = errors.New("too many decimal places")
+	ErrAmbiguousSeparator      = errors.New("ambiguous separator")
+	ErrMisplacedGroupSeparator = errors.New("misplaced thousands separator")
+	ErrPriceOverflow           = errors.New("price out of range")
+)
+
+// PriceParseError records the input that failed to parse, along with the reason
+type PriceParseError struct {
+	Input string
+	Err   error
+}
+
+func (e *PriceParseError) Error() string {
+	return fmt.Sprintf("parsing price %q: %s", e.Input, e.Err)
+}
+
+func (e *PriceParseError) Unwrap() error {
+	return e.Err
+}
+
+// ParsePrice parses a price string as written in the locale, e.g. "1.234,56 €" for de-DE,
+// or "(£1,234.56)" for en-GB.  It accepts the locale's and the default currency symbols, as well as
+// the currency code, along with a leading or trailing sign or accounting parentheses.
+// The currency is also used to find the number of decimal places.
+// No floats are involved so the result is exact, and anything that can't be parsed exactly,
+// such as too many decimal places, is an error rather than being rounded.
+func (l Locale) ParsePrice(s, currency string) (Cents, error) {
+	return parsePrice(s, l, currency)
+}
+
+// ParseMoneyLocale parses a price string as written in the locale into Money
+func ParseMoneyLocale(s string, l Locale, currency string) (Money, error) {
+	c, err := LookupCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	amount, err := parsePrice(s, l, c.Code)
+	if err != nil {
+		return Money{}, err
+	}
+
+	return Money{Amount: amount, Currency: c.Code}, nil
+}
+
+// priceSymbols are all the symbols we will strip from a price string, longest first
+// so that, e.g., "US$" is tried before "$"
+func priceSymbols(l Locale, currency string) (res []string) {
+	if currency == "" {
+		return
+	}
+
+	currency = strings.ToUpper(currency)
+	res = append(res, currency, l.CurrencySymbol(currency))
+	if s, ok := defaultCurrencySymbols[currency]; ok {
+		res = append(res, s)
+	}
+
+	sort.SliceStable(res, func(i, j int) bool { return len(res[i]) > len(res[j]) })
+	return
+}
+
+func isMinus(r rune) bool {
+	return r == '-' || r == '\u2212'
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
+
+func hasSuffixFold(s, suffix string) bool {
+	return len(s) >= len(suffix) && strings.EqualFold(s[len(s)-len(suffix):], suffix)
+}
+
+// priceAffixes strips signs and currency symbols from both ends of the string,
+// returning what's left along with the sign
+type priceAffixes struct {
+	symbols    []string
+	signs      int
+	isNegative bool
+	hasSymbol  bool
+}
+
+func (pa *priceAffixes) sign(r rune) {
+	pa.signs++
+	pa.isNegative = isMinus(r)
+}
+
+func (pa *priceAffixes) strip(s string) (string, error) {
+	for {
+		s = strings.TrimFunc(s, unicode.IsSpace)
+		stripped := false
+
+		if r, size := utf8.DecodeRuneInString(s); r == '+' || isMinus(r) {
+			pa.sign(r)
+			s = s[size:]
+			stripped = true
+		} else if r, size := utf8.DecodeLastRuneInString(s); r == '+' || isMinus(r) {
+			pa.sign(r)
+			s = s[:len(s)-size]
+			stripped = true
+		}
+
+		for _, symbol := range pa.symbols {
+			if hasPrefixFold(s, symbol) {
+				s = s[len(symbol):]
+			} else if hasSuffixFold(s, symbol) {
+				s = s[:len(s)-len(symbol)]
+			} else {
+				continue
+			}
+
+			if pa.hasSymbol {
+				return s, ErrInvalidCharacter
+			}
+			pa.hasSymbol = true
+			stripped = true
+			break
+		}
+
+		if !stripped {
+			return s, nil
+		}
+	}
+}
+
+func parsePrice(s string, l Locale, currency string) (Cents, error) {
+	input := s
+	fail := func(err error) (Cents, error) {
+		return 0, &PriceParseError{Input: input, Err: err}
+	}
+
+	s = strings.TrimFunc(s, unicode.IsSpace)
+	if s == "" {
+		return fail(ErrEmptyPrice)
+	}
+
+	// Accounting negatives
+	hasParens := strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")")
+	if hasParens {
+		s = s[1 : len(s)-1]
+	}
+
+	affixes := priceAffixes{symbols: priceSymbols(l, currency)}
+	s, err := affixes.strip(s)
+	if err != nil {
+		return fail(err)
+	}
+
+	if affixes.signs > 1 || (hasParens && affixes.signs > 0) {
+		return fail(ErrMultipleSigns)
+	}
+
+	if s == "" {
+		return fail(ErrEmptyPrice)
+	}
+
+	var whole, frac string
+	if l.Decimal == "" {
+		whole, frac, err = splitMachinePrice(s)
+	} else {
+		whole, frac, err = l.splitPrice(s)
+	}
+
+	if err != nil {
+		return fail(err)
+	}
+
+	// Extra decimal places are fine as long as they are zeros, because nothing is lost
+	exponent := CurrencyExponent(currency)
+	if len(frac) > exponent {
+		if strings.Trim(frac[exponent:], "0") != "" {
+			return fail(ErrTooManyDecimalPlaces)
+		}
+		frac = frac[:exponent]
+	}
+
+	digits := whole + frac + strings.Repeat("0", exponent-len(frac))
+
+	var n Cents
+	for _, r := range digits {
+		d := Cents(r - '0')
+		if n > (maxCents-d)/10 {
+			return fail(ErrPriceOverflow)
+		}
+		n = n*10 + d
+	}
+
+	if hasParens || affixes.isNegative {
+		n = -n
+	}
+
+	return n, nil
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// splitMachinePrice is the lenient, machine format, where either "." or "," is the decimal
+// separator but there are no thousands separators, so having both, or two of either, is ambiguous
+func splitMachinePrice(s string) (whole, frac string, err error) {
+	separator := -1
+	for i, r := range s {
+		switch {
+		case isDigit(r):
+		case r == '.' || r == ',':
+			if separator >= 0 {
+				return "", "", ErrAmbiguousSeparator
+			}
+			separator = i
+		default:
+			return "", "", ErrInvalidCharacter
+		}
+	}
+
+	if separator < 0 {
+		return s, "", nil
+	}
+
+	whole, frac = s[:separator], s[separator+1:]
+	if whole == "" && frac == "" {
+		return "", "", ErrEmptyPrice
+	}
+
+	return whole, frac, nil
+}
+
+// isGroupSeparator also allows for the way people actually type group separators, so any space
+// where the locale uses some form of space, and a plain apostrophe where the locale uses a quote
+func (l Locale) isGroupSeparator(r rune) bool {
+	g, _ := utf8.DecodeRuneInString(l.Group)
+	switch {
+	case r == g:
+		return true
+	case unicode.IsSpace(g):
+		return unicode.IsSpace(r)
+	case g == '\u2019':
+		return r == '\''
+	}
+
+	return false
+}
+
+// splitPrice splits the price into whole and fractional digits, checking that
+// the group separators are in the right places
+func (l Locale) splitPrice(s string) (whole, frac string, err error) {
+	decimal, _ := utf8.DecodeRuneInString(l.Decimal)
+
+	var groups []string
+	current := strings.Builder{}
+	seenDecimal := false
+
+	for _, r := range s {
+		switch {
+		case isDigit(r):
+			current.WriteRune(r)
+		case r == decimal:
+			if seenDecimal {
+				return "", "", ErrAmbiguousSeparator
+			}
+			seenDecimal = true
+			groups = append(groups, current.String())
+			current.Reset()
+		case l.isGroupSeparator(r):
+			if seenDecimal {
+				return "", "", ErrMisplacedGroupSeparator
+			}
+			groups = append(groups, current.String())
+			current.Reset()
+		case r == '.' || r == ',' || r == '\'' || r == '\u2019':
+			// A separator from some other locale, so we can't be sure what was meant
+			return "", "", ErrAmbiguousSeparator
+		default:
+			return "", "", ErrInvalidCharacter
+		}
+	}
+
+	if seenDecimal {
+		frac = current.String()
+	} else {
+		groups = append(groups, current.String())
+	}
+
+	if err := l.checkGroups(groups); err != nil {
+		return "", "", err
+	}
+
+	whole = strings.Join(groups, "")
+	if whole == "" && frac == "" {
+		return "", "", ErrEmptyPrice
+	}
+
+	return whole, frac, nil
+}
+
+// checkGroups makes sure that, e.g., 1,234,567 is allowed but 12,34 isn't
+func (l Locale) checkGroups(groups []string) error {
+	if len(groups) <= 1 {
+		return nil
+	}
+
+	primary, secondary := l.PrimaryGrouping, l.SecondaryGrouping
+	if secondary <= 0 {
+		secondary = primary
+	}
+
+	last := len(groups) - 1
+	for i, g := range groups {
+		switch {
+		case i == last && len(g) != primary:
+			return ErrMisplacedGroupSeparator
+		case i == 0 && (len(g) == 0 || len(g) > secondary):
+			return ErrMisplacedGroupSeparator
+		case i != 0 && i != last && len(g) != secondary:
+			return ErrMisplacedGroupSeparator
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package financial
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseCentsFromPriceString(t *testing.T) {
+	tests := []struct {
+		s           string
+		expected    Cents
+		expectedErr error
+	}{
+		{"0", 0, nil},
+		{"12", 1200, nil},
+		{"12.3", 1230, nil},
+		{"12,34", 1234, nil},
+		{".5", 50, nil},
+		{"-12.34", -1234, nil},
+		{"12.34-", -1234, nil},
+		{"+12.34", 1234, nil},
+		{"(12.34)", -1234, nil},
+		{" 12.34 ", 1234, nil},
+		{"1.500", 150, nil},
+		{"92233720368547758.07", 9223372036854775807, nil},
+
+		{"", 0, ErrEmptyPrice},
+		{"-", 0, ErrEmptyPrice},
+		{".", 0, ErrEmptyPrice},
+		{"1e3", 0, ErrInvalidCharacter},
+		{"£12.34", 0, ErrInvalidCharacter},
+		{"1 234.56", 0, ErrInvalidCharacter},
+		{"1.005", 0, ErrTooManyDecimalPlaces},
+		{"1,234.56", 0, ErrAmbiguousSeparator},
+		{"1.234.567", 0, ErrAmbiguousSeparator},
+		{"--12", 0, ErrMultipleSigns},
+		{"-(12)", 0, ErrInvalidCharacter},
+		{"(12.34", 0, ErrInvalidCharacter},
+		{"92233720368547758.08", 0, ErrPriceOverflow},
+		{"99999999999999999999999999", 0, ErrPriceOverflow},
+	}
+
+	for _, test := range tests {
+		res, err := ParseCentsFromPriceString(test.s)
+		if !errors.Is(err, test.expectedErr) {
+			t.Errorf("Testing %q. Expected error %v; got %v", test.s, test.expectedErr, err)
+		}
+
+		if err != nil {
+			var parseErr *PriceParseError
+			if !errors.As(err, &parseErr) || parseErr.Input != test.s {
+				t.Errorf("Testing %q. Expected a PriceParseError recording the input; got %v", test.s, err)
+			}
+		}
+
+		if res != test.expected {
+			t.Errorf("Testing %q. Expected %v; got %v", test.s, test.expected, res)
+		}
+	}
+}
+
+func TestMustParseCentsFromPriceStringPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected a panic parsing an invalid price")
+		}
+	}()
+
+	MustParseCentsFromPriceString("twelve")
+}
+
+func TestLocaleParsePrice(t *testing.T) {
+	tests := []struct {
+		locale      string
+		currency    string
+		s           string
+		expected    Cents
+		expectedErr error
+	}{
+		{"en-GB", "GBP", "£1,234.56", 123456, nil},
+		{"en-GB", "GBP", "-£1,234.56", -123456, nil},
+		{"en-GB", "GBP", "(£1,234.56)", -123456, nil},
+		{"en-GB", "GBP", "£1,234.56-", -123456, nil},
+		{"en-GB", "GBP", "GBP 1234.56", 123456, nil},
+		{"en-GB", "GBP", "1234.56 gbp", 123456, nil},
+		{"en-GB", "GBP", "1,234,567", 123456700, nil},
+		{"en-GB", "JPY", "JP¥1,234", 1234, nil},
+		{"en-IN", "INR", "₹1,23,45,678.90", 1234567890, nil},
+		{"de-DE", "EUR", "1.234,56 €", 123456, nil},
+		{"de-DE", "EUR", "-1.234,56 €", -123456, nil},
+		{"de-DE", "EUR", "1234,56", 123456, nil},
+		{"fr-FR", "EUR", "1 234,56 €", 123456, nil},
+		{"fr-FR", "EUR", "1 234,56 €", 123456, nil},
+		{"de-CH", "CHF", "CHF 1’234.56", 123456, nil},
+		{"de-CH", "CHF", "CHF-1'234.56", -123456, nil},
+		{"nl-NL", "EUR", "€ -1.234,56", -123456, nil},
+		{"sv-SE", "SEK", "−1 234,56 kr", -123456, nil},
+		{"en-GB", "KWD", "1,234.567", 1234567, nil},
+
+		{"en-GB", "GBP", "£1.005", 0, ErrTooManyDecimalPlaces},
+		{"en-GB", "JPY", "¥1.5", 0, ErrInvalidCharacter},
+		{"en-GB", "JPY", "1.5", 0, ErrTooManyDecimalPlaces},
+		{"en-GB", "GBP", "1.234,56", 0, ErrMisplacedGroupSeparator},
+		{"en-GB", "GBP", "12,34", 0, ErrMisplacedGroupSeparator},
+		{"en-GB", "GBP", "1,2345", 0, ErrMisplacedGroupSeparator},
+		{"en-GB", "GBP", ",234", 0, ErrMisplacedGroupSeparator},
+		{"en-GB", "GBP", "1.2.3", 0, ErrAmbiguousSeparator},
+		{"de-DE", "EUR", "1,234.56", 0, ErrMisplacedGroupSeparator},
+		{"de-CH", "CHF", "1,234.56", 0, ErrAmbiguousSeparator},
+		{"en-GB", "GBP", "£12 abc", 0, ErrInvalidCharacter},
+		{"en-GB", "GBP", "££12", 0, ErrInvalidCharacter},
+		{"en-GB", "GBP", "(-£12)", 0, ErrMultipleSigns},
+	}
+
+	for _, test := range tests {
+		l, err := LookupLocale(test.locale)
+		if err != nil {
+			t.Fatalf("Testing %s. Unexpected error: %s", test.locale, err)
+		}
+
+		res, err := l.ParsePrice(test.s, test.currency)
+		if !errors.Is(err, test.expectedErr) {
+			t.Errorf("Testing %s %q. Expected error %v; got %v", test.locale, test.s, test.expectedErr, err)
+		}
+
+		if res != test.expected {
+			t.Errorf("Testing %s %q. Expected %v; got %v", test.locale, test.s, test.expected, res)
+		}
+	}
+}
+
+func TestParseFormatRoundTrip(t *testing.T) {
+	amounts := []Cents{0, 1, -1, 99, 100, 123456, -123456, 123456789012}
+
+	for tag, l := range locales {
+		for _, currency := range []string{"GBP", "EUR", "CHF", "JPY"} {
+			for _, c := range amounts {
+				for _, opts := range []FormatOptions{{}, {Accounting: true}} {
+					s := c.FormatLocale(l, currency, opts)
+					res, err := l.ParsePrice(s, currency)
+					if err != nil {
+						t.Errorf("Testing %s %q. Unexpected error: %s", tag, s, err)
+					}
+					if res != c {
+						t.Errorf("Testing %s %q. Expected %v; got %v", tag, s, c, res)
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package financial
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var ErrPivotKeys = errors.New("wrong number of keys for the pivot")
+
+// Pivot aggregates amounts keyed by any number of dimensions, e.g. channel -> SKU -> month -> currency,
+// where KeyedCentDict and KeyedCentDict2 are fixed at two and three.  Every level keeps a running
+// subtotal, so rolling up to any level is cheap.
+//
+// If one of the dimensions is the currency, use NewCurrencyPivot, so that the subtotals above it are
+// kept for each currency rather than added together.  The zero value is a Pivot with no dimensions.
+type Pivot struct {
+	Dimensions []string
+
+	// currencyLevel is the index of the currency dimension plus one, so that the zero value has none
+	currencyLevel int
+	root          *pivotNode
+}
+
+type pivotNode struct {
+	// totals are keyed by currency, or by "" if the Pivot doesn't have a currency dimension
+	totals   CentDict
+	children map[string]*pivotNode
+}
+
+func newPivotNode() *pivotNode {
+	return &pivotNode{totals: CentDict{}, children: map[string]*pivotNode{}}
+}
+
+// sortedKeys are the child keys in order
+func (n *pivotNode) sortedKeys() []string {
+	res := make([]string, 0, len(n.children))
+	for k := range n.children {
+		res = append(res, k)
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+func sortedCurrencies(cd CentDict) []string {
+	res := make([]string, 0, len(cd))
+	for currency := range cd {
+		res = append(res, currency)
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// total is the node's total, as long as it is all in one currency
+func (n *pivotNode) total() (Cents, error) {
+	if len(n.totals) > 1 {
+		return 0, fmt.Errorf("%w: %s", ErrCurrencyMismatch, strings.Join(sortedCurrencies(n.totals), ", "))
+	}
+
+	for _, amount := range n.totals {
+		return amount, nil
+	}
+
+	return 0, nil
+}
+
+func copyKeys(keys []string) []string {
+	res := make([]string, len(keys))
+	copy(res, keys)
+	return res
+}
+
+// PivotRow is one row of a flattened Pivot.  A subtotal row has fewer keys than there are dimensions,
+// and in a currency Pivot there is a subtotal row for each currency under the keys.
+type PivotRow struct {
+	Keys       []string
+	Currency   string
+	Amount     Cents
+	IsSubtotal bool
+}
+
+func NewPivot(dimensions ...string) *Pivot {
+	return &Pivot{
+		Dimensions: dimensions,
+		root:       newPivotNode(),
+	}
+}
+
+// NewCurrencyPivot is a Pivot where one of the dimensions is the currency of the amounts
+func NewCurrencyPivot(currencyDimension string, dimensions ...string) (*Pivot, error) {
+	for i, d := range dimensions {
+		if d == currencyDimension {
+			p := NewPivot(dimensions...)
+			p.currencyLevel = i + 1
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: there is no %q dimension", ErrPivotKeys, currencyDimension)
+}
+
+// CurrencyLevel is the level (counting from 0) of the currency dimension, or -1 if there isn't one
+func (p *Pivot) CurrencyLevel() int {
+	return p.currencyLevel - 1
+}
+
+// empty is a new Pivot with the same currency dimension
+func (p *Pivot) empty() *Pivot {
+	res := NewPivot(p.Dimensions...)
+	res.currencyLevel = p.currencyLevel
+	return res
+}
+
+// Add adds the amount at the full set of keys, one for each dimension
+func (p *Pivot) Add(keys []string, amount Cents) error {
+	if len(keys) != len(p.Dimensions) {
+		return fmt.Errorf("%w: expected %d; got %d", ErrPivotKeys, len(p.Dimensions), len(keys))
+	}
+
+	if p.root == nil {
+		p.root = newPivotNode()
+	}
+
+	currency := ""
+	if p.currencyLevel > 0 {
+		currency = keys[p.currencyLevel-1]
+	}
+
+	node := p.root
+	node.totals.AddToKey(currency, amount)
+	for _, k := range keys {
+		child, ok := node.children[k]
+		if !ok {
+			child = newPivotNode()
+			node.children[k] = child
+		}
+
+		child.totals.AddToKey(currency, amount)
+		node = child
+	}
+
+	return nil
+}
+
+// AddKeys is Add for when the keys are known at compile time, e.g. p.AddKeys(100, "web", "SKU1", "GBP")
+func (p *Pivot) AddKeys(amount Cents, keys ...string) error {
+	return p.Add(keys, amount)
+}
+
+func (p *Pivot) find(keys []string) *pivotNode {
+	node := p.root
+	if node == nil {
+		return nil
+	}
+
+	for _, k := range keys {
+		child, ok := node.children[k]
+		if !ok {
+			return nil
+		}
+		node = child
+	}
+
+	return node
+}
+
+// Total is the subtotal for a partial set of keys, e.g. Total("web") is everything sold on the web,
+// and Total() is the grand total.  Keys that aren't there have a total of 0.  If the subtotal is in
+// more than one currency, it returns ErrCurrencyMismatch; use Totals instead.
+func (p *Pivot) Total(keys ...string) (Cents, error) {
+	if node := p.find(keys); node != nil {
+		return node.total()
+	}
+
+	return 0, nil
+}
+
+// Totals is the subtotal for a partial set of keys in each currency, or under "" if the Pivot
+// doesn't have a currency dimension
+func (p *Pivot) Totals(keys ...string) CentDict {
+	if node := p.find(keys); node != nil {
+		return node.totals.Copy()
+	}
+
+	return CentDict{}
+}
+
+// Keys are the sorted keys at the next level down from the partial set of keys,
+// so Keys() is the top level keys and Keys("web") the SKUs sold on the web
+func (p *Pivot) Keys(keys ...string) []string {
+	if node := p.find(keys); node != nil {
+		return node.sortedKeys()
+	}
+
+	return nil
+}
+
+// LevelKeys are all of the distinct keys at a level (counting from 0), sorted
+func (p *Pivot) LevelKeys(level int) []string {
+	seen := map[string]bool{}
+
+	var walk func(n *pivotNode, depth int)
+	walk = func(n *pivotNode, depth int) {
+		for k, child := range n.children {
+			if depth == level {
+				seen[k] = true
+				continue
+			}
+			walk(child, depth+1)
+		}
+	}
+	if p.root != nil {
+		walk(p.root, 0)
+	}
+
+	res := make([]string, 0, len(seen))
+	for k := range seen {
+		res = append(res, k)
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// CentDict is the subtotals at the next level down from the partial set of keys, which is handy
+// when the next level is the currency.  If any of the subtotals is in more than one currency,
+// it returns ErrCurrencyMismatch.
+func (p *Pivot) CentDict(keys ...string) (CentDict, error) {
+	res := CentDict{}
+	if node := p.find(keys); node != nil {
+		for k, child := range node.children {
+			amount, err := child.total()
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			res[k] = amount
+		}
+	}
+
+	return res, nil
+}
+
+// Rollup returns a new Pivot with only the first n dimensions, adding together everything below.
+// The currency dimension is always kept, after the others, as amounts in different currencies
+// can't be added together.
+func (p *Pivot) Rollup(n int) *Pivot {
+	if n < 0 {
+		n = 0
+	}
+	if n > len(p.Dimensions) {
+		n = len(p.Dimensions)
+	}
+
+	currencyLevel := p.CurrencyLevel()
+	if currencyLevel < n {
+		res := NewPivot(copyKeys(p.Dimensions[:n])...)
+		res.currencyLevel = p.currencyLevel
+		for _, row := range p.Subtotals(n) {
+			res.Add(row.Keys, row.Amount)
+		}
+
+		return res
+	}
+
+	res := NewPivot(append(copyKeys(p.Dimensions[:n]), p.Dimensions[currencyLevel])...)
+	res.currencyLevel = n + 1
+	for _, row := range p.Rows() {
+		res.Add(append(copyKeys(row.Keys[:n]), row.Currency), row.Amount)
+	}
+
+	return res
+}
+
+// Filter returns a new Pivot with only the full rows that keep returns true for
+func (p *Pivot) Filter(keep func(keys []string, amount Cents) bool) *Pivot {
+	res := p.empty()
+	for _, row := range p.Rows() {
+		if keep(row.Keys, row.Amount) {
+			res.Add(row.Keys, row.Amount)
+		}
+	}
+
+	return res
+}
+
+// FilterLevel returns a new Pivot keeping only the given keys at a level, e.g. FilterLevel(3, "GBP")
+// to keep just the GBP amounts when the currency is the fourth dimension
+func (p *Pivot) FilterLevel(level int, keys ...string) *Pivot {
+	allowed := map[string]bool{}
+	for _, k := range keys {
+		allowed[k] = true
+	}
+
+	return p.Filter(func(rowKeys []string, amount Cents) bool {
+		return level >= 0 && level < len(rowKeys) && allowed[rowKeys[level]]
+	})
+}
+
+// nodeRows are the rows for a node, one for each currency in currency order
+func nodeRows(node *pivotNode, keys []string, isSubtotal bool) []PivotRow {
+	res := make([]PivotRow, 0, len(node.totals))
+	for _, currency := range sortedCurrencies(node.totals) {
+		res = append(res, PivotRow{
+			Keys:       copyKeys(keys),
+			Currency:   currency,
+			Amount:     node.totals[currency],
+			IsSubtotal: isSubtotal,
+		})
+	}
+
+	return res
+}
+
+// Subtotals flattens the Pivot to the subtotals at a level, i.e. with the first n keys, in key order.
+// Subtotals(len(p.Dimensions)) is the same as Rows.
+func (p *Pivot) Subtotals(n int) (res []PivotRow) {
+	var walk func(node *pivotNode, keys []string)
+	walk = func(node *pivotNode, keys []string) {
+		if len(keys) == n {
+			res = append(res, nodeRows(node, keys, n < len(p.Dimensions))...)
+			return
+		}
+
+		for _, k := range node.sortedKeys() {
+			walk(node.children[k], append(keys, k))
+		}
+	}
+
+	if p.root != nil {
+		walk(p.root, make([]string, 0, len(p.Dimensions)))
+	}
+	return
+}
+
+// Rows flattens the Pivot to a row for each full set of keys, in key order
+func (p *Pivot) Rows() []PivotRow {
+	return p.Subtotals(len(p.Dimensions))
+}
+
+// RowsWithSubtotals flattens the Pivot in key order, with subtotal rows after each group
+// at every level, and the grand totals last, as a report would show them
+func (p *Pivot) RowsWithSubtotals() (res []PivotRow) {
+	var walk func(node *pivotNode, keys []string)
+	walk = func(node *pivotNode, keys []string) {
+		if len(keys) == len(p.Dimensions) {
+			res = append(res, nodeRows(node, keys, false)...)
+			return
+		}
+
+		for _, k := range node.sortedKeys() {
+			walk(node.children[k], append(keys, k))
+		}
+
+		res = append(res, nodeRows(node, keys, true)...)
+	}
+
+	if p.root != nil {
+		walk(p.root, make([]string, 0, len(p.Dimensions)))
+	}
+	return
+}
+
+// PivotFromKeyedCentDict converts a KeyedCentDict to a two level Pivot, with the currency second
+func PivotFromKeyedCentDict(kcd KeyedCentDict, dimension1, dimension2 string) *Pivot {
+	res := NewPivot(dimension1, dimension2)
+	res.currencyLevel = 2
+	for k1, cd := range kcd {
+		for k2, amount := range cd {
+			res.AddKeys(amount, k1, k2)
+		}
+	}
+
+	return res
+}
+
+// PivotFromKeyedCentDict2 converts a KeyedCentDict2 to a three level Pivot, with the currency third
+func PivotFromKeyedCentDict2(kcd KeyedCentDict2, dimension1, dimension2, dimension3 string) *Pivot {
+	res := NewPivot(dimension1, dimension2, dimension3)
+	res.currencyLevel = 3
+	for k1, v1 := range kcd {
+		for k2, cd := range v1 {
+			for k3, amount := range cd {
+				res.AddKeys(amount, k1, k2, k3)
+			}
+		}
+	}
+
+	return res
+}
+
+// KeyedCentDict converts a two level Pivot back to a KeyedCentDict
+func (p *Pivot) KeyedCentDict() (KeyedCentDict, error) {
+	if len(p.Dimensions) != 2 {
+		return nil, fmt.Errorf("%w: a KeyedCentDict needs 2 levels; got %d", ErrPivotKeys, len(p.Dimensions))
+	}
+
+	res := KeyedCentDict{}
+	for _, row := range p.Rows() {
+		res.AddToKey(row.Keys[0], row.Keys[1], row.Amount)
+	}
+
+	return res, nil
+}
@@ -0,0 +1,236 @@
+package financial
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func testPivot() *Pivot {
+	p, _ := NewCurrencyPivot("currency", "channel", "sku", "month", "currency")
+	p.AddKeys(1000, "web", "SKU1", "2021-01", "GBP")
+	p.AddKeys(500, "web", "SKU1", "2021-01", "GBP")
+	p.AddKeys(2000, "web", "SKU1", "2021-02", "EUR")
+	p.AddKeys(300, "web", "SKU2", "2021-01", "GBP")
+	p.AddKeys(700, "shop", "SKU1", "2021-02", "GBP")
+	p.AddKeys(-100, "shop", "SKU3", "2021-02", "GBP")
+	return p
+}
+
+func TestPivotAdd(t *testing.T) {
+	p := testPivot()
+
+	if err := p.AddKeys(1, "web", "SKU1"); !errors.Is(err, ErrPivotKeys) {
+		t.Errorf("Testing too few keys. Expected %v; got %v", ErrPivotKeys, err)
+	}
+
+	if _, err := NewCurrencyPivot("currency", "channel", "sku"); !errors.Is(err, ErrPivotKeys) {
+		t.Errorf("Testing a missing currency dimension. Expected %v; got %v", ErrPivotKeys, err)
+	}
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected CentDict
+	}{
+		{"grand total", nil, CentDict{"GBP": 2400, "EUR": 2000}},
+		{"channel", []string{"web"}, CentDict{"GBP": 1800, "EUR": 2000}},
+		{"channel and sku", []string{"web", "SKU1"}, CentDict{"GBP": 1500, "EUR": 2000}},
+		{"full keys", []string{"web", "SKU1", "2021-01", "GBP"}, CentDict{"GBP": 1500}},
+		{"negative", []string{"shop"}, CentDict{"GBP": 600}},
+		{"missing", []string{"phone"}, CentDict{}},
+		{"missing deeper", []string{"web", "SKU9", "2021-01"}, CentDict{}},
+	}
+
+	for _, test := range tests {
+		res := p.Totals(test.keys...)
+		if !res.Compare(test.expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+	}
+
+	// Total only adds up amounts in one currency
+	if res, err := p.Total("shop"); err != nil || res != 600 {
+		t.Errorf("Testing Total in one currency. Expected 600; got %v (%v)", res, err)
+	}
+	if res, err := p.Total("phone"); err != nil || res != 0 {
+		t.Errorf("Testing Total of missing keys. Expected 0; got %v (%v)", res, err)
+	}
+	if _, err := p.Total("web"); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Testing Total in two currencies. Expected %v; got %v", ErrCurrencyMismatch, err)
+	}
+}
+
+func TestPivotWithoutCurrency(t *testing.T) {
+	p := NewPivot("channel", "month")
+	p.AddKeys(100, "web", "2021-01")
+	p.AddKeys(50, "shop", "2021-01")
+	p.AddKeys(maxCents, "web", "2021-02")
+
+	if p.CurrencyLevel() != -1 {
+		t.Errorf("Testing CurrencyLevel. Expected -1; got %d", p.CurrencyLevel())
+	}
+
+	// The totals saturate rather than wrapping around
+	if res, err := p.Total("web"); err != nil || res != maxCents {
+		t.Errorf("Testing saturation. Expected %v; got %v (%v)", maxCents, res, err)
+	}
+
+	if res, err := p.Total("shop"); err != nil || res != 50 {
+		t.Errorf("Testing Total. Expected 50; got %v (%v)", res, err)
+	}
+
+	expected := []PivotRow{
+		{Keys: []string{"shop"}, Amount: 50, IsSubtotal: true},
+		{Keys: []string{"web"}, Amount: maxCents, IsSubtotal: true},
+	}
+	if res := p.Subtotals(1); !reflect.DeepEqual(res, expected) {
+		t.Errorf("Testing Subtotals. Expected %v; got %v", expected, res)
+	}
+
+	// The zero value is a Pivot with no dimensions
+	var zero Pivot
+	if res, err := zero.Total(); err != nil || res != 0 || zero.Keys() != nil || len(zero.Rows()) != 0 {
+		t.Errorf("Testing an empty zero value. Got %v (%v)", res, err)
+	}
+
+	if err := zero.AddKeys(5); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := zero.Total(); err != nil || res != 5 {
+		t.Errorf("Testing the zero value. Expected 5; got %v (%v)", res, err)
+	}
+}
+
+func TestPivotKeys(t *testing.T) {
+	p := testPivot()
+
+	tests := []struct {
+		name     string
+		res      []string
+		expected []string
+	}{
+		{"top level", p.Keys(), []string{"shop", "web"}},
+		{"next level", p.Keys("web"), []string{"SKU1", "SKU2"}},
+		{"missing", p.Keys("phone"), nil},
+		{"all skus", p.LevelKeys(1), []string{"SKU1", "SKU2", "SKU3"}},
+		{"all months", p.LevelKeys(2), []string{"2021-01", "2021-02"}},
+		{"all currencies", p.LevelKeys(3), []string{"EUR", "GBP"}},
+		{"beyond the last level", p.LevelKeys(4), []string{}},
+	}
+
+	for _, test := range tests {
+		if !reflect.DeepEqual(test.res, test.expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, test.res)
+		}
+	}
+}
+
+func TestPivotRows(t *testing.T) {
+	p, _ := NewCurrencyPivot("currency", "channel", "currency")
+	p.AddKeys(200, "web", "GBP")
+	p.AddKeys(100, "shop", "GBP")
+	p.AddKeys(50, "web", "EUR")
+
+	expected := []PivotRow{
+		{Keys: []string{"shop", "GBP"}, Currency: "GBP", Amount: 100},
+		{Keys: []string{"web", "EUR"}, Currency: "EUR", Amount: 50},
+		{Keys: []string{"web", "GBP"}, Currency: "GBP", Amount: 200},
+	}
+	if res := p.Rows(); !reflect.DeepEqual(res, expected) {
+		t.Errorf("Testing Rows. Expected %v; got %v", expected, res)
+	}
+
+	// Subtotals over more than one currency have a row for each
+	expected = []PivotRow{
+		{Keys: []string{"shop", "GBP"}, Currency: "GBP", Amount: 100},
+		{Keys: []string{"shop"}, Currency: "GBP", Amount: 100, IsSubtotal: true},
+		{Keys: []string{"web", "EUR"}, Currency: "EUR", Amount: 50},
+		{Keys: []string{"web", "GBP"}, Currency: "GBP", Amount: 200},
+		{Keys: []string{"web"}, Currency: "EUR", Amount: 50, IsSubtotal: true},
+		{Keys: []string{"web"}, Currency: "GBP", Amount: 200, IsSubtotal: true},
+		{Keys: []string{}, Currency: "EUR", Amount: 50, IsSubtotal: true},
+		{Keys: []string{}, Currency: "GBP", Amount: 300, IsSubtotal: true},
+	}
+	if res := p.RowsWithSubtotals(); !reflect.DeepEqual(res, expected) {
+		t.Errorf("Testing RowsWithSubtotals. Expected %v; got %v", expected, res)
+	}
+
+	expected = []PivotRow{
+		{Keys: []string{"shop"}, Currency: "GBP", Amount: 100, IsSubtotal: true},
+		{Keys: []string{"web"}, Currency: "EUR", Amount: 50, IsSubtotal: true},
+		{Keys: []string{"web"}, Currency: "GBP", Amount: 200, IsSubtotal: true},
+	}
+	if res := p.Subtotals(1); !reflect.DeepEqual(res, expected) {
+		t.Errorf("Testing Subtotals. Expected %v; got %v", expected, res)
+	}
+}
+
+func TestPivotRollupAndFilter(t *testing.T) {
+	p := testPivot()
+
+	// The currency is kept when rolling up, after the other dimensions
+	rolled := p.Rollup(2)
+	if !reflect.DeepEqual(rolled.Dimensions, []string{"channel", "sku", "currency"}) || rolled.CurrencyLevel() != 2 {
+		t.Errorf("Testing Rollup dimensions. Got %v", rolled.Dimensions)
+	}
+	if !rolled.Totals("web", "SKU1").Compare(CentDict{"GBP": 1500, "EUR": 2000}) || !rolled.Totals().Compare(p.Totals()) {
+		t.Errorf("Testing Rollup. Got %v", rolled.Rows())
+	}
+
+	if all := p.Rollup(4); !reflect.DeepEqual(all.Rows(), p.Rows()) || all.CurrencyLevel() != 3 {
+		t.Errorf("Testing Rollup of every dimension. Got %v", all.Rows())
+	}
+
+	gbp := p.FilterLevel(3, "GBP")
+	if total, err := gbp.Total(); err != nil || total != 2400 || !gbp.Totals("web", "SKU1").Compare(CentDict{"GBP": 1500}) {
+		t.Errorf("Testing FilterLevel. Got %v", gbp.Rows())
+	}
+
+	positive := p.Filter(func(keys []string, amount Cents) bool { return amount > 0 })
+	if !positive.Totals().Compare(CentDict{"GBP": 2500, "EUR": 2000}) || len(positive.Keys("shop")) != 1 {
+		t.Errorf("Testing Filter. Got %v", positive.Rows())
+	}
+
+	// By currency, for a given channel, which is the usual CentDict
+	cd, err := p.Rollup(1).CentDict("web")
+	if err != nil || !cd.Compare(CentDict{"GBP": 1800, "EUR": 2000}) {
+		t.Errorf("Testing CentDict. Got %v (%v)", cd, err)
+	}
+
+	// Each channel is in more than one currency, so they can't be a CentDict
+	if _, err := p.Rollup(1).CentDict(); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Errorf("Testing CentDict across currencies. Expected %v; got %v", ErrCurrencyMismatch, err)
+	}
+}
+
+func TestPivotFromKeyedCentDicts(t *testing.T) {
+	kcd := KeyedCentDict{
+		"SKU1": CentDict{"GBP": 100, "EUR": 200},
+		"SKU2": CentDict{"GBP": 50},
+	}
+
+	p := PivotFromKeyedCentDict(kcd, "sku", "currency")
+	if !p.Totals("SKU1").Compare(CentDict{"GBP": 100, "EUR": 200}) || !p.Totals().Compare(CentDict{"GBP": 150, "EUR": 200}) {
+		t.Errorf("Testing PivotFromKeyedCentDict. Got %v", p.Rows())
+	}
+
+	back, err := p.KeyedCentDict()
+	if err != nil || !reflect.DeepEqual(back, kcd) {
+		t.Errorf("Testing KeyedCentDict round trip. Expected %v; got %v (%v)", kcd, back, err)
+	}
+
+	kcd2 := KeyedCentDict2{
+		"web":  KeyedCentDict{"SKU1": CentDict{"GBP": 100}},
+		"shop": KeyedCentDict{"SKU1": CentDict{"GBP": 10}, "SKU2": CentDict{"EUR": 5}},
+	}
+
+	p2 := PivotFromKeyedCentDict2(kcd2, "channel", "sku", "currency")
+	if !reflect.DeepEqual(p2.LevelKeys(1), []string{"SKU1", "SKU2"}) || !p2.Totals("shop").Compare(CentDict{"GBP": 10, "EUR": 5}) {
+		t.Errorf("Testing PivotFromKeyedCentDict2. Got %v", p2.Rows())
+	}
+
+	if _, err := p2.KeyedCentDict(); !errors.Is(err, ErrPivotKeys) {
+		t.Errorf("Testing KeyedCentDict from 3 levels. Expected %v; got %v", ErrPivotKeys, err)
+	}
+}
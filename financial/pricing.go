@@ -0,0 +1,347 @@
+package financial
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+)
+
+var (
+	ErrInvalidMargin     = errors.New("margin must be less than 100%")
+	ErrInvalidPrettyRule = errors.New("invalid pretty price rule")
+)
+
+// Markup is profit as a percentage of cost, and margin is profit as a percentage of price,
+// so a cost of 75 and a price of 100 is a markup of 33.33% but a margin of 25%.
+
+// MarkupToMargin converts a markup percentage to the margin percentage it gives
+func MarkupToMargin(markup float64) float64 {
+	m := decimalRat(markup)
+	return ratFloat(new(big.Rat).Quo(new(big.Rat).Mul(m, hundred), new(big.Rat).Add(m, hundred)))
+}
+
+// MarginToMarkup converts a margin percentage to the markup percentage needed to get it.
+// A margin of 100% or more can't be made with any markup.
+func MarginToMarkup(margin float64) (float64, error) {
+	if margin >= 100 {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidMargin, margin)
+	}
+
+	m := decimalRat(margin)
+	return ratFloat(new(big.Rat).Quo(new(big.Rat).Mul(m, hundred), new(big.Rat).Sub(hundred, m))), nil
+}
+
+// MarkupOn is the markup percentage of this price over the cost, or 0 if there is no cost
+func (c Cents) MarkupOn(cost Cents) float64 {
+	if cost == 0 {
+		return 0
+	}
+
+	return ratFloat(new(big.Rat).Quo(new(big.Rat).Mul(centsRat(c-cost), hundred), centsRat(cost)))
+}
+
+// MarginOn is the margin percentage of this price over the cost, or 0 if the price is 0
+func (c Cents) MarginOn(cost Cents) float64 {
+	if c == 0 {
+		return 0
+	}
+
+	return ratFloat(new(big.Rat).Quo(new(big.Rat).Mul(centsRat(c-cost), hundred), centsRat(c)))
+}
+
+// WithMarkup is the price for this cost at the markup percentage
+func (c Cents) WithMarkup(markup float64, mode RoundingMode) Cents {
+	return c + c.ByPercentageRounded(markup, mode)
+}
+
+// WithMargin is the price for this cost at the margin percentage.  It always rounds up,
+// so that the margin is at least what was asked for.
+func (c Cents) WithMargin(margin float64) (Cents, error) {
+	if margin >= 100 {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidMargin, margin)
+	}
+
+	return divideRoundedChecked(centsRat(c), new(big.Rat).Sub(big.NewRat(1, 1), percentageRat(margin)), RoundCeiling)
+}
+
+// PrettyPriceRule snaps prices to a "pretty" price: one of the prices Ending above a multiple of Step.
+// So Step 100 and Ending 99 gives 0.99, 1.99, 2.99 etc., Step 500 and Ending 0 gives the nearest 5, and
+// Step 1000 and Ending 999 gives 9.99, 19.99, 29.99 etc.  RoundingMode decides which pretty price
+// is picked: RoundCeiling always goes up, like RoundToNearestPretty, RoundFloor always goes down,
+// and the others go to the nearest, with ties going the way the mode says.
+type PrettyPriceRule struct {
+	// MinPrice is the price from which the rule applies, so that different rules can be used for low and high prices
+	MinPrice     Cents
+	Step, Ending Cents
+	RoundingMode RoundingMode
+}
+
+// PrettyEnding is a rule for prices ending in the given pence, e.g. 99 or 95
+func PrettyEnding(ending Cents, mode RoundingMode) PrettyPriceRule {
+	return PrettyPriceRule{Step: 100, Ending: ending, RoundingMode: mode}
+}
+
+// PrettyMultiple is a rule for prices that are a multiple of the step, e.g. 500 for the nearest 5
+func PrettyMultiple(step Cents, mode RoundingMode) PrettyPriceRule {
+	return PrettyPriceRule{Step: step, RoundingMode: mode}
+}
+
+func (r PrettyPriceRule) Validate() error {
+	if r.Step <= 0 || r.Ending < 0 || r.Ending >= r.Step {
+		return fmt.Errorf("%w: step %v, ending %v", ErrInvalidPrettyRule, r.Step, r.Ending)
+	}
+
+	return nil
+}
+
+// round snaps the price to a pretty price.  Prices of zero or less, and invalid rules, are left alone.
+func (r PrettyPriceRule) round(c Cents, mode RoundingMode) Cents {
+	if c <= 0 || r.Validate() != nil {
+		return c
+	}
+
+	steps := roundRat(new(big.Rat).SetFrac(big.NewInt(int64(c-r.Ending)), big.NewInt(int64(r.Step))), mode)
+	res := steps.SaturatingMulQty(int(r.Step)).SaturatingAdd(r.Ending)
+
+	// The nearest can be below the first pretty price, so go up to it
+	if res <= 0 {
+		if r.Ending > 0 {
+			return r.Ending
+		}
+		return r.Step
+	}
+
+	return res
+}
+
+func (r PrettyPriceRule) Round(c Cents) Cents {
+	return r.round(c, r.RoundingMode)
+}
+
+// PricingPolicy is how prices are made pretty and kept profitable
+type PricingPolicy struct {
+	// PrettyRules are picked by price: the rule with the highest MinPrice at or below the price is used
+	PrettyRules []PrettyPriceRule
+
+	// MinMargin is the lowest margin percentage allowed over cost.  Prices are raised to meet it,
+	// before being made pretty, and a pretty price is never allowed to take them back below it.
+	MinMargin float64
+}
+
+func (pp PricingPolicy) Validate() error {
+	if pp.MinMargin >= 100 {
+		return fmt.Errorf("%w: %v", ErrInvalidMargin, pp.MinMargin)
+	}
+
+	for _, r := range pp.PrettyRules {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// prettyRule is the rule for the price, if there is one
+func (pp PricingPolicy) prettyRule(c Cents) (PrettyPriceRule, bool) {
+	var res PrettyPriceRule
+	found := false
+	for _, r := range pp.PrettyRules {
+		if r.MinPrice <= c && (!found || r.MinPrice > res.MinPrice) {
+			res, found = r, true
+		}
+	}
+
+	return res, found
+}
+
+// MinPrice is the lowest price allowed for the cost
+func (pp PricingPolicy) MinPrice(cost Cents) (Cents, error) {
+	if cost <= 0 {
+		return 0, nil
+	}
+
+	return cost.WithMargin(pp.MinMargin)
+}
+
+// Price applies the policy to a proposed price for an item with the given cost
+func (pp PricingPolicy) Price(price, cost Cents) (Cents, error) {
+	if err := pp.Validate(); err != nil {
+		return 0, err
+	}
+
+	floor, err := pp.MinPrice(cost)
+	if err != nil {
+		return 0, err
+	}
+
+	if price < floor {
+		price = floor
+	}
+
+	r, ok := pp.prettyRule(price)
+	if !ok {
+		return price, nil
+	}
+
+	res := r.Round(price)
+	if res < floor {
+		res = r.round(floor, RoundCeiling)
+	}
+
+	return res, nil
+}
+
+// PriceListItem is an item on a price list, with its cost so that margins can be worked out
+type PriceListItem struct {
+	SKU   string
+	Cost  Cents
+	Price Cents
+}
+
+type PriceList []PriceListItem
+
+// RepriceMethod is how a Repricing sets the new prices
+type RepriceMethod uint
+
+const (
+	// RepriceKeep keeps the current prices, and just applies the policy to them
+	RepriceKeep RepriceMethod = iota
+	// RepriceAdjust changes the current prices by Percentage, e.g. 5 for a 5% rise or -10 for a 10% cut
+	RepriceAdjust
+	// RepriceMarkup sets the prices to the cost plus a markup of Percentage
+	RepriceMarkup
+	// RepriceMargin sets the prices to give a margin of Percentage over cost
+	RepriceMargin
+)
+
+// Repricing is a bulk change to a price list.  The new prices are worked out by the method
+// and then the policy is applied to them.
+type Repricing struct {
+	Method       RepriceMethod
+	Percentage   float64
+	RoundingMode RoundingMode
+	Policy       PricingPolicy
+}
+
+func (r Repricing) price(item PriceListItem) (Cents, error) {
+	switch r.Method {
+	case RepriceAdjust:
+		return item.Price + item.Price.ByPercentageRounded(r.Percentage, r.RoundingMode), nil
+	case RepriceMarkup:
+		return item.Cost.WithMarkup(r.Percentage, r.RoundingMode), nil
+	case RepriceMargin:
+		return item.Cost.WithMargin(r.Percentage)
+	}
+
+	return item.Price, nil
+}
+
+// PriceDiff is the before and after for a single item on a repriced list
+type PriceDiff struct {
+	SKU                  string
+	Cost                 Cents
+	OldPrice, NewPrice   Cents
+	OldMargin, NewMargin float64
+}
+
+func (pd PriceDiff) Change() Cents {
+	return pd.NewPrice - pd.OldPrice
+}
+
+// ChangePercentage is the change as a percentage of the old price, or 0 if there was no old price
+func (pd PriceDiff) ChangePercentage() float64 {
+	return pd.NewPrice.MarkupOn(pd.OldPrice)
+}
+
+// PriceDiffReport is the before and after for a whole repriced list, in SKU order
+type PriceDiffReport []PriceDiff
+
+// Reprice returns a new price list with the repricing applied, and the diff between the two.
+// The original list is left as it is.
+func (pl PriceList) Reprice(r Repricing) (PriceList, PriceDiffReport, error) {
+	res := make(PriceList, len(pl))
+	report := make(PriceDiffReport, 0, len(pl))
+
+	for i, item := range pl {
+		price, err := r.price(item)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", item.SKU, err)
+		}
+
+		price, err = r.Policy.Price(price, item.Cost)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", item.SKU, err)
+		}
+
+		res[i] = PriceListItem{SKU: item.SKU, Cost: item.Cost, Price: price}
+		report = append(report, PriceDiff{
+			SKU:       item.SKU,
+			Cost:      item.Cost,
+			OldPrice:  item.Price,
+			NewPrice:  price,
+			OldMargin: item.Price.MarginOn(item.Cost),
+			NewMargin: price.MarginOn(item.Cost),
+		})
+	}
+
+	sort.SliceStable(report, func(i, j int) bool { return report[i].SKU < report[j].SKU })
+	return res, report, nil
+}
+
+// Changed is just the items whose price changed
+func (pdr PriceDiffReport) Changed() PriceDiffReport {
+	var res PriceDiffReport
+	for _, pd := range pdr {
+		if pd.Change() != 0 {
+			res = append(res, pd)
+		}
+	}
+
+	return res
+}
+
+// Counts is how many prices went up, down and stayed the same
+func (pdr PriceDiffReport) Counts() (increased, decreased, unchanged int) {
+	for _, pd := range pdr {
+		switch {
+		case pd.Change() > 0:
+			increased++
+		case pd.Change() < 0:
+			decreased++
+		default:
+			unchanged++
+		}
+	}
+
+	return
+}
+
+// Table lays the report out for WriteCSV or WriteXLSX, with margins and the change
+// as percentages to 2 decimal places
+func (pdr PriceDiffReport) Table() ReportTable {
+	res := ReportTable{
+		Header:       []string{"SKU", "Cost", "Old price", "New price", "Change", "Change %", "Old margin %", "New margin %"},
+		LabelColumns: 1,
+	}
+
+	for _, pd := range pdr {
+		res.Rows = append(res.Rows, []string{
+			pd.SKU,
+			pd.Cost.FormatAsPrice(),
+			pd.OldPrice.FormatAsPrice(),
+			pd.NewPrice.FormatAsPrice(),
+			pd.Change().FormatAsPrice(),
+			formatPercentage(pd.ChangePercentage()),
+			formatPercentage(pd.OldMargin),
+			formatPercentage(pd.NewMargin),
+		})
+	}
+
+	return res
+}
+
+func formatPercentage(pc float64) string {
+	return decimalRat(pc).FloatString(2)
+}
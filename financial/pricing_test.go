@@ -0,0 +1,193 @@
+package financial
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestPrettyPriceRule(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     PrettyPriceRule
+		price    Cents
+		expected Cents
+	}{
+		{"up to .99", PrettyEnding(99, RoundCeiling), 1250, 1299},
+		{"already .99", PrettyEnding(99, RoundCeiling), 1299, 1299},
+		{"up to first .99", PrettyEnding(99, RoundCeiling), 50, 99},
+		{"down to .99", PrettyEnding(99, RoundFloor), 1250, 1199},
+		{"nearest .95 up", PrettyEnding(95, RoundHalfUp), 1250, 1295},
+		{"nearest .95 down", PrettyEnding(95, RoundHalfUp), 1240, 1195},
+		{"nearest .95 tie up", PrettyEnding(95, RoundHalfUp), 1245, 1295},
+		{"nearest .95 tie down", PrettyEnding(95, RoundHalfDown), 1245, 1195},
+		{"nearest 5 up", PrettyMultiple(500, RoundHalfUp), 12345, 12500},
+		{"nearest 5 down", PrettyMultiple(500, RoundHalfUp), 12200, 12000},
+		{"nearest 5 never zero", PrettyMultiple(500, RoundHalfUp), 200, 500},
+		{"up to x9.99", PrettyPriceRule{Step: 1000, Ending: 999, RoundingMode: RoundCeiling}, 1500, 1999},
+		{"zero price", PrettyEnding(99, RoundCeiling), 0, 0},
+		{"invalid rule", PrettyPriceRule{Ending: 99}, 1250, 1250},
+		{"up to whole pound", PrettyMultiple(100, RoundCeiling), 1250, 1300},
+	}
+
+	for _, test := range tests {
+		got := test.rule.Round(test.price)
+		if got != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, got)
+		}
+	}
+
+	if err := (PrettyPriceRule{Step: 100, Ending: 100}).Validate(); !errors.Is(err, ErrInvalidPrettyRule) {
+		t.Errorf("Testing invalid ending. Expected %v; got %v", ErrInvalidPrettyRule, err)
+	}
+}
+
+func TestMarkupAndMargin(t *testing.T) {
+	if got := MarkupToMargin(25); got != 20 {
+		t.Errorf("Testing MarkupToMargin. Expected %v; got %v", 20, got)
+	}
+
+	if got, err := MarginToMarkup(20); got != 25 || err != nil {
+		t.Errorf("Testing MarginToMarkup. Expected %v; got %v, %v", 25, got, err)
+	}
+
+	if _, err := MarginToMarkup(100); !errors.Is(err, ErrInvalidMargin) {
+		t.Errorf("Testing MarginToMarkup of 100. Expected %v; got %v", ErrInvalidMargin, err)
+	}
+
+	if got := Cents(10000).MarginOn(7500); got != 25 {
+		t.Errorf("Testing MarginOn. Expected %v; got %v", 25, got)
+	}
+
+	if got := Cents(10000).MarkupOn(7500); got < 33.333 || got > 33.334 {
+		t.Errorf("Testing MarkupOn. Expected %v; got %v", 33.333, got)
+	}
+
+	if got := Cents(10000).MarkupOn(0); got != 0 {
+		t.Errorf("Testing MarkupOn no cost. Expected %v; got %v", 0, got)
+	}
+
+	if got := Cents(7500).WithMarkup(20, RoundHalfUp); got != 9000 {
+		t.Errorf("Testing WithMarkup. Expected %v; got %v", 9000, got)
+	}
+
+	if got, _ := Cents(7500).WithMargin(25); got != 10000 {
+		t.Errorf("Testing WithMargin. Expected %v; got %v", 10000, got)
+	}
+
+	// 1000 / 0.67 is 1492.54, which rounds up so the margin is at least 33%
+	if got, _ := Cents(1000).WithMargin(33); got != 1493 {
+		t.Errorf("Testing WithMargin rounds up. Expected %v; got %v", 1493, got)
+	}
+}
+
+func TestPricingPolicy(t *testing.T) {
+	policy := PricingPolicy{
+		PrettyRules: []PrettyPriceRule{
+			PrettyEnding(99, RoundHalfUp),
+			{MinPrice: 10000, Step: 500, RoundingMode: RoundHalfUp},
+		},
+		MinMargin: 30,
+	}
+
+	tests := []struct {
+		name        string
+		price, cost Cents
+		expected    Cents
+	}{
+		{"pretty", 1240, 500, 1199},
+		{"raised to min margin and back up to pretty", 1010, 800, 1199},
+		{"high price to nearest 5", 12345, 5000, 12500},
+		{"high price rounds down", 10100, 0, 10000},
+	}
+
+	for _, test := range tests {
+		got, err := policy.Price(test.price, test.cost)
+		if err != nil || got != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v, %v", test.name, test.expected, got, err)
+		}
+	}
+
+	if _, err := (PricingPolicy{MinMargin: 100}).Price(1000, 500); !errors.Is(err, ErrInvalidMargin) {
+		t.Errorf("Testing invalid policy. Expected %v; got %v", ErrInvalidMargin, err)
+	}
+}
+
+func testPriceList() PriceList {
+	return PriceList{
+		{SKU: "B", Cost: 500, Price: 1000},
+		{SKU: "A", Cost: 800, Price: 1199},
+		{SKU: "C", Cost: 2000, Price: 2999},
+	}
+}
+
+func TestPriceListReprice(t *testing.T) {
+	pl := testPriceList()
+	repriced, report, err := pl.Reprice(Repricing{
+		Method:     RepriceAdjust,
+		Percentage: 10,
+		Policy:     PricingPolicy{PrettyRules: []PrettyPriceRule{PrettyEnding(99, RoundCeiling)}, MinMargin: 40},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := PriceList{
+		{SKU: "B", Cost: 500, Price: 1199},
+		{SKU: "A", Cost: 800, Price: 1399},
+		{SKU: "C", Cost: 2000, Price: 3399},
+	}
+	if !reflect.DeepEqual(repriced, expected) {
+		t.Errorf("Testing repriced list. Expected %v; got %v", expected, repriced)
+	}
+
+	if !reflect.DeepEqual(pl, testPriceList()) {
+		t.Errorf("Testing original list is unchanged. Expected %v; got %v", testPriceList(), pl)
+	}
+
+	skus := []string{report[0].SKU, report[1].SKU, report[2].SKU}
+	if !reflect.DeepEqual(skus, []string{"A", "B", "C"}) {
+		t.Errorf("Testing report order. Expected %v; got %v", []string{"A", "B", "C"}, skus)
+	}
+
+	if increased, decreased, unchanged := report.Counts(); increased != 3 || decreased != 0 || unchanged != 0 {
+		t.Errorf("Testing counts. Expected 3/0/0; got %v/%v/%v", increased, decreased, unchanged)
+	}
+
+	expectedRow := []string{"B", "5.00", "10.00", "11.99", "1.99", "19.90", "50.00", "58.30"}
+	if table := report.Table(); !reflect.DeepEqual(table.Rows[1], expectedRow) {
+		t.Errorf("Testing report table. Expected %v; got %v", expectedRow, table.Rows[1])
+	}
+}
+
+func TestPriceListRepriceMethods(t *testing.T) {
+	_, report, err := testPriceList().Reprice(Repricing{
+		Policy: PricingPolicy{PrettyRules: []PrettyPriceRule{PrettyEnding(99, RoundCeiling)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changed := report.Changed()
+	if len(changed) != 1 || changed[0].SKU != "B" || changed[0].NewPrice != 1099 {
+		t.Errorf("Testing keep prices. Expected only B to change to 1099; got %v", changed)
+	}
+
+	if increased, decreased, unchanged := report.Counts(); increased != 1 || decreased != 0 || unchanged != 2 {
+		t.Errorf("Testing keep counts. Expected 1/0/2; got %v/%v/%v", increased, decreased, unchanged)
+	}
+
+	repriced, _, err := testPriceList().Reprice(Repricing{Method: RepriceMargin, Percentage: 50})
+	if err != nil || repriced[0].Price != 1000 || repriced[2].Price != 4000 {
+		t.Errorf("Testing margin repricing. Expected 1000 and 4000; got %v, %v", repriced, err)
+	}
+
+	repriced, _, err = testPriceList().Reprice(Repricing{Method: RepriceMarkup, Percentage: 50})
+	if err != nil || repriced[1].Price != 1200 {
+		t.Errorf("Testing markup repricing. Expected 1200; got %v, %v", repriced, err)
+	}
+
+	if _, _, err := testPriceList().Reprice(Repricing{Method: RepriceMargin, Percentage: 100}); !errors.Is(err, ErrInvalidMargin) {
+		t.Errorf("Testing invalid margin repricing. Expected %v; got %v", ErrInvalidMargin, err)
+	}
+}
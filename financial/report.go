@@ -0,0 +1,347 @@
+package financial
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ReportTableOptions decide how a Pivot is laid out as a table.  Each level of the pivot
+// can go down the rows or across the columns; levels in neither are added together, apart from
+// the currency, which goes down the rows after the others if it isn't placed.
+// If both are left empty, the last level goes across and the rest go down, which for
+// a KeyedCentDict keyed by SKU then currency is a row per SKU and a column per currency.
+type ReportTableOptions struct {
+	RowLevels    []int
+	ColumnLevels []int
+
+	// RowTotals adds a total column on the right, and ColumnTotals a total row at the bottom.
+	// Amounts in different currencies aren't added together, so a total is left blank if it
+	// would be, e.g. the row total of a row with a column for each currency.
+	RowTotals    bool
+	ColumnTotals bool
+
+	// TotalLabel defaults to "Total"
+	TotalLabel string
+}
+
+// ReportTable is a report laid out ready for writing.  The first LabelColumns of every row are labels
+// and the rest are amounts formatted with the exponent of their currency, or blank where there is nothing.
+type ReportTable struct {
+	Header       []string
+	LabelColumns int
+	Rows         [][]string
+}
+
+const defaultTotalLabel = "Total"
+
+// keyTuples are sorted and de-duplicated sets of keys
+type keyTuples struct {
+	index  map[string]int
+	tuples [][]string
+}
+
+func (kt *keyTuples) add(tuple []string) string {
+	k := strings.Join(tuple, "\x00")
+	if kt.index == nil {
+		kt.index = map[string]int{}
+	}
+
+	if _, ok := kt.index[k]; !ok {
+		kt.index[k] = len(kt.tuples)
+		kt.tuples = append(kt.tuples, tuple)
+	}
+
+	return k
+}
+
+func (kt *keyTuples) sorted() [][]string {
+	res := make([][]string, len(kt.tuples))
+	copy(res, kt.tuples)
+
+	sort.Slice(res, func(i, j int) bool {
+		for n := range res[i] {
+			if res[i][n] != res[j][n] {
+				return res[i][n] < res[j][n]
+			}
+		}
+		return false
+	})
+
+	return res
+}
+
+// reportCell adds up the amounts for a cell, keeping track of whether they are all in one currency
+type reportCell struct {
+	currency    string
+	amount      Cents
+	seen, mixed bool
+}
+
+func (rc *reportCell) add(currency string, amount Cents) {
+	if !rc.seen {
+		rc.currency, rc.seen = currency, true
+	} else if currency != rc.currency {
+		rc.mixed = true
+	}
+
+	rc.amount = rc.amount.SaturatingAdd(amount)
+}
+
+// format is blank if there is nothing in the cell, or if it would be a sum of different currencies
+func (rc *reportCell) format() string {
+	if rc == nil || rc.mixed {
+		return ""
+	}
+
+	return formatMinorUnits(rc.amount, CurrencyExponent(rc.currency))
+}
+
+// reportCells are cells keyed by row or column
+type reportCells map[string]*reportCell
+
+func (rc reportCells) add(k, currency string, amount Cents) {
+	cell, ok := rc[k]
+	if !ok {
+		cell = &reportCell{}
+		rc[k] = cell
+	}
+
+	cell.add(currency, amount)
+}
+
+func pickKeys(keys []string, levels []int) []string {
+	res := make([]string, len(levels))
+	for i, level := range levels {
+		if level >= 0 && level < len(keys) {
+			res[i] = keys[level]
+		}
+	}
+
+	return res
+}
+
+// Table lays the Pivot out as a table, with rows and columns in key order
+func (p *Pivot) Table(opts ReportTableOptions) ReportTable {
+	rowLevels, columnLevels := opts.RowLevels, opts.ColumnLevels
+	if len(rowLevels) == 0 && len(columnLevels) == 0 && len(p.Dimensions) > 0 {
+		for i := 0; i < len(p.Dimensions)-1; i++ {
+			rowLevels = append(rowLevels, i)
+		}
+		columnLevels = []int{len(p.Dimensions) - 1}
+	}
+
+	totalLabel := opts.TotalLabel
+	if totalLabel == "" {
+		totalLabel = defaultTotalLabel
+	}
+
+	if currencyLevel := p.CurrencyLevel(); currencyLevel >= 0 && !hasLevel(rowLevels, currencyLevel) && !hasLevel(columnLevels, currencyLevel) {
+		rowLevels = append(append([]int(nil), rowLevels...), currencyLevel)
+	}
+
+	var rows, columns keyTuples
+	cells := map[string]reportCells{}
+	rowTotals, columnTotals := reportCells{}, reportCells{}
+	grandTotal := &reportCell{}
+
+	for _, row := range p.Rows() {
+		rk := rows.add(pickKeys(row.Keys, rowLevels))
+		ck := columns.add(pickKeys(row.Keys, columnLevels))
+
+		if cells[rk] == nil {
+			cells[rk] = reportCells{}
+		}
+		cells[rk].add(ck, row.Currency, row.Amount)
+		rowTotals.add(rk, row.Currency, row.Amount)
+		columnTotals.add(ck, row.Currency, row.Amount)
+		grandTotal.add(row.Currency, row.Amount)
+	}
+
+	sortedColumns := columns.sorted()
+	res := ReportTable{LabelColumns: len(rowLevels)}
+
+	for _, level := range rowLevels {
+		name := ""
+		if level >= 0 && level < len(p.Dimensions) {
+			name = p.Dimensions[level]
+		}
+		res.Header = append(res.Header, name)
+	}
+	for _, column := range sortedColumns {
+		res.Header = append(res.Header, strings.Join(column, " / "))
+	}
+	if opts.RowTotals {
+		res.Header = append(res.Header, totalLabel)
+	}
+
+	for _, row := range rows.sorted() {
+		rk := strings.Join(row, "\x00")
+		line := append([]string(nil), row...)
+		for _, column := range sortedColumns {
+			line = append(line, cells[rk][strings.Join(column, "\x00")].format())
+		}
+		if opts.RowTotals {
+			line = append(line, rowTotals[rk].format())
+		}
+		res.Rows = append(res.Rows, line)
+	}
+
+	if opts.ColumnTotals {
+		line := make([]string, len(rowLevels))
+		if len(line) > 0 {
+			line[0] = totalLabel
+		}
+		for _, column := range sortedColumns {
+			line = append(line, columnTotals[strings.Join(column, "\x00")].format())
+		}
+		if opts.RowTotals {
+			line = append(line, grandTotal.format())
+		}
+		res.Rows = append(res.Rows, line)
+	}
+
+	return res
+}
+
+func hasLevel(levels []int, level int) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ReportTable lays out a KeyedCentDict, by default with a row for each top level key
+// and a column for each second level key
+func (kcd KeyedCentDict) ReportTable(dimension1, dimension2 string, opts ReportTableOptions) ReportTable {
+	return PivotFromKeyedCentDict(kcd, dimension1, dimension2).Table(opts)
+}
+
+// ReportTable lays out a KeyedCentDict2, by default with a row for each of the first two levels of keys
+// and a column for each third level key
+func (kcd KeyedCentDict2) ReportTable(dimension1, dimension2, dimension3 string, opts ReportTableOptions) ReportTable {
+	return PivotFromKeyedCentDict2(kcd, dimension1, dimension2, dimension3).Table(opts)
+}
+
+// WriteCSV writes the header and rows as CSV
+func (t ReportTable) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(t.Header); err != nil {
+		return err
+	}
+
+	if err := cw.WriteAll(t.Rows); err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+// XLSX: just enough of the Office Open XML spreadsheet format for Excel, LibreOffice
+// and Google Sheets to open a single sheet, with labels as inline strings and amounts as numbers
+
+const xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="Report" sheetId="1" r:id="rId1"/></sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`</Relationships>`
+
+// xlsxColumn converts a 0 based column number to its letters, e.g. 0 -> A, 26 -> AA
+func xlsxColumn(n int) string {
+	res := ""
+	for n >= 0 {
+		res = string(rune('A'+n%26)) + res
+		n = n/26 - 1
+	}
+
+	return res
+}
+
+func xlsxEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+func (t ReportTable) xlsxSheet() string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n")
+	b.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+
+	writeRow := func(r int, row []string, isHeader bool) {
+		fmt.Fprintf(&b, `<row r="%d">`, r)
+		for c, value := range row {
+			if value == "" {
+				continue
+			}
+
+			ref := fmt.Sprintf("%s%d", xlsxColumn(c), r)
+			if isHeader || c < t.LabelColumns {
+				fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, xlsxEscape(value))
+			} else {
+				fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, value)
+			}
+		}
+		b.WriteString(`</row>`)
+	}
+
+	writeRow(1, t.Header, true)
+	for i, row := range t.Rows {
+		writeRow(i+2, row, false)
+	}
+
+	b.WriteString(`</sheetData></worksheet>`)
+	return b.String()
+}
+
+// WriteXLSX writes the table as a single sheet Excel workbook
+func (t ReportTable) WriteXLSX(w io.Writer) error {
+	zw := zip.NewWriter(w)
+
+	files := []struct {
+		name, content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+		{"xl/worksheets/sheet1.xml", t.xlsxSheet()},
+	}
+
+	for _, file := range files {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate})
+		if err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(f, file.content); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
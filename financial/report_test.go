@@ -0,0 +1,225 @@
+package financial
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReportTable(t *testing.T) {
+	kcd := KeyedCentDict{
+		"SKU2": CentDict{"GBP": 150},
+		"SKU1": CentDict{"GBP": 1000, "EUR": 2050},
+	}
+
+	tests := []struct {
+		name     string
+		opts     ReportTableOptions
+		expected ReportTable
+	}{
+		{
+			name: "default layout",
+			opts: ReportTableOptions{},
+			expected: ReportTable{
+				Header:       []string{"sku", "EUR", "GBP"},
+				LabelColumns: 1,
+				Rows: [][]string{
+					{"SKU1", "20.50", "10.00"},
+					{"SKU2", "", "1.50"},
+				},
+			},
+		},
+		{
+			name: "with totals",
+			opts: ReportTableOptions{RowTotals: true, ColumnTotals: true},
+			expected: ReportTable{
+				Header:       []string{"sku", "EUR", "GBP", "Total"},
+				LabelColumns: 1,
+				Rows: [][]string{
+					{"SKU1", "20.50", "10.00", ""},
+					{"SKU2", "", "1.50", "1.50"},
+					{"Total", "20.50", "11.50", ""},
+				},
+			},
+		},
+		{
+			name: "transposed",
+			opts: ReportTableOptions{RowLevels: []int{1}, ColumnLevels: []int{0}, RowTotals: true, ColumnTotals: true, TotalLabel: "All"},
+			expected: ReportTable{
+				Header:       []string{"currency", "SKU1", "SKU2", "All"},
+				LabelColumns: 1,
+				Rows: [][]string{
+					{"EUR", "20.50", "", "20.50"},
+					{"GBP", "10.00", "1.50", "11.50"},
+					{"All", "", "1.50", ""},
+				},
+			},
+		},
+		{
+			name: "currency left out",
+			opts: ReportTableOptions{RowLevels: []int{0}},
+			expected: ReportTable{
+				Header:       []string{"sku", "currency", ""},
+				LabelColumns: 2,
+				Rows: [][]string{
+					{"SKU1", "EUR", "20.50"},
+					{"SKU1", "GBP", "10.00"},
+					{"SKU2", "GBP", "1.50"},
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		res := kcd.ReportTable("sku", "currency", test.opts)
+		if !reflect.DeepEqual(res, test.expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+	}
+}
+
+func TestReportTableKeyedCentDict2(t *testing.T) {
+	kcd := KeyedCentDict2{
+		"web":  KeyedCentDict{"SKU1": CentDict{"GBP": 100}, "SKU2": CentDict{"GBP": -5}},
+		"shop": KeyedCentDict{"SKU1": CentDict{"GBP": 10, "EUR": 1}},
+	}
+
+	res := kcd.ReportTable("channel", "sku", "currency", ReportTableOptions{ColumnTotals: true})
+	expected := ReportTable{
+		Header:       []string{"channel", "sku", "EUR", "GBP"},
+		LabelColumns: 2,
+		Rows: [][]string{
+			{"shop", "SKU1", "0.01", "0.10"},
+			{"web", "SKU1", "", "1.00"},
+			{"web", "SKU2", "", "-0.05"},
+			{"Total", "", "0.01", "1.05"},
+		},
+	}
+
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("Testing KeyedCentDict2. Expected %v; got %v", expected, res)
+	}
+}
+
+func TestReportTableCurrencyExponents(t *testing.T) {
+	kcd := KeyedCentDict{
+		"SKU1": CentDict{"JPY": 1500, "KWD": 1234},
+		"SKU2": CentDict{"JPY": 25, "GBP": 99},
+	}
+
+	res := kcd.ReportTable("sku", "currency", ReportTableOptions{ColumnTotals: true})
+	expected := ReportTable{
+		Header:       []string{"sku", "GBP", "JPY", "KWD"},
+		LabelColumns: 1,
+		Rows: [][]string{
+			{"SKU1", "", "1500", "1.234"},
+			{"SKU2", "0.99", "25", ""},
+			{"Total", "0.99", "1525", "1.234"},
+		},
+	}
+
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("Testing currency exponents. Expected %v; got %v", expected, res)
+	}
+
+	// Without a currency dimension, everything is added up as one
+	p := NewPivot("channel", "month")
+	p.AddKeys(150, "web", "2021-01")
+	p.AddKeys(250, "web", "2021-02")
+
+	res = p.Table(ReportTableOptions{RowTotals: true})
+	expected = ReportTable{
+		Header:       []string{"channel", "2021-01", "2021-02", "Total"},
+		LabelColumns: 1,
+		Rows:         [][]string{{"web", "1.50", "2.50", "4.00"}},
+	}
+
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf("Testing without a currency. Expected %v; got %v", expected, res)
+	}
+}
+
+func TestReportTableWriteCSV(t *testing.T) {
+	table := KeyedCentDict{
+		"Widget, large": CentDict{"GBP": 1234},
+	}.ReportTable("sku", "currency", ReportTableOptions{RowTotals: true})
+
+	var b bytes.Buffer
+	if err := table.WriteCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "sku,GBP,Total\n\"Widget, large\",12.34,12.34\n"
+	if b.String() != expected {
+		t.Errorf("Testing WriteCSV. Expected %q; got %q", expected, b.String())
+	}
+}
+
+func TestReportTableWriteXLSX(t *testing.T) {
+	table := KeyedCentDict{
+		"<Widget> & co": CentDict{"GBP": 1234},
+		"Gadget":        CentDict{"EUR": 5},
+	}.ReportTable("sku", "currency", ReportTableOptions{})
+
+	var b bytes.Buffer
+	if err := table.WriteXLSX(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := ioutil.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("Testing WriteXLSX. Expected %s in the zip", name)
+		}
+	}
+
+	sheet := files["xl/worksheets/sheet1.xml"]
+	for _, expected := range []string{
+		`<c r="A1" t="inlineStr"><is><t>sku</t></is></c>`,
+		`<c r="A2" t="inlineStr"><is><t>&lt;Widget&gt; &amp; co</t></is></c>`,
+		`<c r="C2"><v>12.34</v></c>`,
+		`<c r="B3"><v>0.05</v></c>`,
+	} {
+		if !strings.Contains(sheet, expected) {
+			t.Errorf("Testing WriteXLSX. Expected the sheet to contain %s; got %s", expected, sheet)
+		}
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	tests := []struct {
+		n        int
+		expected string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{27, "AB"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+
+	for _, test := range tests {
+		if res := xlsxColumn(test.n); res != test.expected {
+			t.Errorf("Testing %d. Expected %v; got %v", test.n, test.expected, res)
+		}
+	}
+}
@@ -0,0 +1,376 @@
+// Package stats summarises collections of amounts: sums, means, medians, percentiles,
+// min/max and standard deviation, optionally weighted, and per currency.
+//
+// An Accumulator takes the amounts one at a time, so a large Mongo cursor can be summarised
+// without holding the documents in memory:
+//
+//	acc := stats.NewAccumulator()
+//	for cur.Next(ctx) {
+//	  var line Line
+//	  if err := cur.Decode(&line); err != nil {
+//	    return err
+//	  }
+//	  acc.AddWeighted(line.UnitPrice, line.Qty)
+//	}
+//	summary := acc.Summary()
+package stats
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/dogpakk/lib/financial"
+)
+
+var ErrWeightsMismatch = errors.New("there must be one weight for each amount")
+
+// Summary is the statistics for a single set of amounts.  Weight is the total of the weights,
+// which is the same as Count when nothing is weighted, and Sum is the total of each amount times
+// its weight, so for unit prices weighted by quantity it is the revenue and Mean is the average
+// selling price.  Amounts that aren't whole cents are rounded half up.
+type Summary struct {
+	Count  int
+	Weight int
+	Sum    financial.Cents
+
+	Min, Max financial.Cents
+	Mean     financial.Cents
+	Median   financial.Cents
+	P25, P75 financial.Cents
+	P90, P95 financial.Cents
+
+	// StdDev is the population standard deviation
+	StdDev financial.Cents
+}
+
+// DefaultPercentileBins is how many distinct amounts an Accumulator from NewAccumulator keeps
+// for the median and percentiles before it starts merging them
+const DefaultPercentileBins = 10000
+
+// Accumulator collects the amounts for a Summary.  Count, sum, min, max, mean and standard deviation
+// take the same memory however many amounts are added.  For the median and percentiles it keeps the
+// weight of each distinct amount, which is exact but grows with the number of distinct amounts, so
+// unless it is exact (see NewExactAccumulator) it is capped: once there are more than twice the bins,
+// neighbouring amounts are merged, at their weighted mean, back down to no more than the bins.  From then
+// on the percentiles are approximate, off by at most the spread of the amounts in one bin.
+// Min and max are always exact.  The zero value is not usable; use NewAccumulator.
+type Accumulator struct {
+	count, weight int
+	sum           financial.Cents
+	min, max      financial.Cents
+
+	// mean and m2 are Welford's running mean and sum of squared differences, for the standard deviation
+	mean, m2 float64
+
+	weights map[financial.Cents]int
+	bins    int
+}
+
+// NewAccumulator is an Accumulator with DefaultPercentileBins
+func NewAccumulator() *Accumulator {
+	return NewAccumulatorWithBins(DefaultPercentileBins)
+}
+
+// NewAccumulatorWithBins is an Accumulator that keeps at most twice bins distinct amounts for
+// the percentiles.  More bins are more accurate and take more memory.
+func NewAccumulatorWithBins(bins int) *Accumulator {
+	if bins < 1 {
+		bins = 1
+	}
+
+	return &Accumulator{weights: map[financial.Cents]int{}, bins: bins}
+}
+
+// NewExactAccumulator is an Accumulator with exact percentiles, which keeps every distinct amount.
+// Only use it where the number of distinct amounts is known to be small enough to hold in memory.
+func NewExactAccumulator() *Accumulator {
+	return &Accumulator{weights: map[financial.Cents]int{}}
+}
+
+func (acc *Accumulator) Add(amount financial.Cents) {
+	acc.AddWeighted(amount, 1)
+}
+
+// AddWeighted adds an amount that counts weight times, e.g. a unit price with the quantity sold.
+// Amounts with a weight of zero or less are ignored.
+func (acc *Accumulator) AddWeighted(amount financial.Cents, weight int) {
+	if weight <= 0 {
+		return
+	}
+
+	if acc.weight == 0 || amount < acc.min {
+		acc.min = amount
+	}
+	if acc.weight == 0 || amount > acc.max {
+		acc.max = amount
+	}
+
+	acc.count++
+	acc.weight += weight
+	acc.sum = acc.sum.SaturatingAdd(amount.SaturatingMulQty(weight))
+
+	delta := float64(amount) - acc.mean
+	acc.mean += delta * float64(weight) / float64(acc.weight)
+	acc.m2 += float64(weight) * delta * (float64(amount) - acc.mean)
+
+	acc.weights[amount] += weight
+	acc.compact()
+}
+
+// Merge adds everything from another Accumulator, so that separate cursors can be summarised in parallel
+func (acc *Accumulator) Merge(other *Accumulator) {
+	if other == nil || other.weight == 0 {
+		return
+	}
+
+	if acc.weight == 0 || other.min < acc.min {
+		acc.min = other.min
+	}
+	if acc.weight == 0 || other.max > acc.max {
+		acc.max = other.max
+	}
+
+	weight := acc.weight + other.weight
+	delta := other.mean - acc.mean
+	acc.m2 += other.m2 + delta*delta*float64(acc.weight)*float64(other.weight)/float64(weight)
+	acc.mean += delta * float64(other.weight) / float64(weight)
+
+	acc.count += other.count
+	acc.weight = weight
+	acc.sum = acc.sum.SaturatingAdd(other.sum)
+
+	for amount, w := range other.weights {
+		acc.weights[amount] += w
+	}
+	acc.compact()
+}
+
+// compact merges the distinct amounts down to the number of bins, once there are twice as many,
+// so that the cost of sorting is spread over many adds
+func (acc *Accumulator) compact() {
+	if acc.bins == 0 || len(acc.weights) <= 2*acc.bins {
+		return
+	}
+
+	// Neighbours are merged as long as they stay within twice the average weight of a bin, so any
+	// two bins side by side are heavier than that between them and there are at most bins of them
+	amounts := acc.sortedAmounts()
+	binWeight := 2 * ((acc.weight + acc.bins - 1) / acc.bins)
+
+	compacted := make(map[financial.Cents]int, acc.bins)
+	var total float64
+	weight := 0
+	flush := func() {
+		if weight > 0 {
+			compacted[financial.Cents(math.Round(total/float64(weight)))] += weight
+		}
+		total, weight = 0, 0
+	}
+
+	for _, amount := range amounts {
+		w := acc.weights[amount]
+		if weight+w > binWeight {
+			flush()
+		}
+		total += float64(amount) * float64(w)
+		weight += w
+	}
+	flush()
+
+	acc.weights = compacted
+}
+
+func (acc *Accumulator) sortedAmounts() []financial.Cents {
+	amounts := make([]financial.Cents, 0, len(acc.weights))
+	for amount := range acc.weights {
+		amounts = append(amounts, amount)
+	}
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i] < amounts[j] })
+
+	return amounts
+}
+
+func (acc *Accumulator) Count() int {
+	return acc.count
+}
+
+// Percentile is the amount below which p percent of the weight falls, interpolating between
+// the two nearest amounts in the same way as a spreadsheet's PERCENTILE, so the 50th percentile
+// is the median.  It is 0 if nothing has been added.
+func (acc *Accumulator) Percentile(p float64) financial.Cents {
+	return acc.Percentiles(p)[0]
+}
+
+// Percentiles is Percentile for each of ps, sorting the amounts only once
+func (acc *Accumulator) Percentiles(ps ...float64) []financial.Cents {
+	res := make([]financial.Cents, len(ps))
+	if acc.weight == 0 {
+		return res
+	}
+
+	// cumulative[i] is the weight of the amounts up to and including amounts[i]
+	amounts := acc.sortedAmounts()
+	cumulative := make([]int, len(amounts))
+	seen := 0
+	for i, amount := range amounts {
+		seen += acc.weights[amount]
+		cumulative[i] = seen
+	}
+
+	// amountAt is the amount at the 0 based rank, when each amount is repeated as many times as its weight
+	amountAt := func(rank int) financial.Cents {
+		i := sort.Search(len(cumulative), func(i int) bool { return rank < cumulative[i] })
+		if i == len(amounts) {
+			return acc.max
+		}
+
+		return amounts[i]
+	}
+
+	for i, p := range ps {
+		switch {
+		case p <= 0:
+			res[i] = acc.min
+		case p >= 100:
+			res[i] = acc.max
+		default:
+			// The rank is 0 based, over every unit of weight, and falls between the lower and upper ranks
+			rank := float64(acc.weight-1) * p / 100
+			lowerRank := int(math.Floor(rank))
+			fraction := rank - float64(lowerRank)
+
+			lower, upper := amountAt(lowerRank), amountAt(lowerRank+1)
+			res[i] = lower + (upper-lower).ByFloatRounded(fraction, financial.RoundHalfUp)
+		}
+	}
+
+	return res
+}
+
+func (acc *Accumulator) Summary() Summary {
+	if acc.weight == 0 {
+		return Summary{}
+	}
+
+	ps := acc.Percentiles(50, 25, 75, 90, 95)
+	return Summary{
+		Count:  acc.count,
+		Weight: acc.weight,
+		Sum:    acc.sum,
+		Min:    acc.min,
+		Max:    acc.max,
+		Mean:   acc.sum.DivideByQtyRounded(acc.weight, financial.RoundHalfUp),
+		Median: ps[0],
+		P25:    ps[1],
+		P75:    ps[2],
+		P90:    ps[3],
+		P95:    ps[4],
+		StdDev: financial.Cents(math.Round(math.Sqrt(acc.m2 / float64(acc.weight)))),
+	}
+}
+
+// Summarise summarises a slice of amounts
+func Summarise(amounts []financial.Cents) Summary {
+	acc := NewAccumulator()
+	for _, amount := range amounts {
+		acc.Add(amount)
+	}
+
+	return acc.Summary()
+}
+
+// SummariseWeighted summarises a slice of amounts, each with the weight at the same index
+func SummariseWeighted(amounts []financial.Cents, weights []int) (Summary, error) {
+	if len(amounts) != len(weights) {
+		return Summary{}, ErrWeightsMismatch
+	}
+
+	acc := NewAccumulator()
+	for i, amount := range amounts {
+		acc.AddWeighted(amount, weights[i])
+	}
+
+	return acc.Summary(), nil
+}
+
+// CurrencyAccumulator keeps an Accumulator for each currency.  The zero value is not usable;
+// use NewCurrencyAccumulator.
+type CurrencyAccumulator struct {
+	accumulators map[string]*Accumulator
+}
+
+func NewCurrencyAccumulator() *CurrencyAccumulator {
+	return &CurrencyAccumulator{accumulators: map[string]*Accumulator{}}
+}
+
+func (ca *CurrencyAccumulator) accumulator(currency string) *Accumulator {
+	acc, ok := ca.accumulators[currency]
+	if !ok {
+		acc = NewAccumulator()
+		ca.accumulators[currency] = acc
+	}
+
+	return acc
+}
+
+func (ca *CurrencyAccumulator) Add(currency string, amount financial.Cents) {
+	ca.accumulator(currency).Add(amount)
+}
+
+func (ca *CurrencyAccumulator) AddWeighted(currency string, amount financial.Cents, weight int) {
+	ca.accumulator(currency).AddWeighted(amount, weight)
+}
+
+func (ca *CurrencyAccumulator) AddMoney(m financial.Money) {
+	ca.Add(m.Currency, m.Amount)
+}
+
+// AddCentDict adds each amount in a CentDict keyed by currency, e.g. the total of one order
+func (ca *CurrencyAccumulator) AddCentDict(cd financial.CentDict) {
+	for currency, amount := range cd {
+		ca.Add(currency, amount)
+	}
+}
+
+func (ca *CurrencyAccumulator) Merge(other *CurrencyAccumulator) {
+	for currency, acc := range other.accumulators {
+		ca.accumulator(currency).Merge(acc)
+	}
+}
+
+// Currencies are the currencies that have had amounts added, in order
+func (ca *CurrencyAccumulator) Currencies() []string {
+	res := make([]string, 0, len(ca.accumulators))
+	for currency := range ca.accumulators {
+		res = append(res, currency)
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+// Accumulator is the Accumulator for the currency, or nil if nothing has been added in it
+func (ca *CurrencyAccumulator) Accumulator(currency string) *Accumulator {
+	return ca.accumulators[currency]
+}
+
+// Summaries is the Summary for each currency
+func (ca *CurrencyAccumulator) Summaries() map[string]Summary {
+	res := make(map[string]Summary, len(ca.accumulators))
+	for currency, acc := range ca.accumulators {
+		res[currency] = acc.Summary()
+	}
+
+	return res
+}
+
+// SummariseCentDicts summarises many CentDicts keyed by currency, such as the totals of a set of orders
+func SummariseCentDicts(cds []financial.CentDict) map[string]Summary {
+	ca := NewCurrencyAccumulator()
+	for _, cd := range cds {
+		ca.AddCentDict(cd)
+	}
+
+	return ca.Summaries()
+}
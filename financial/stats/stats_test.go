@@ -0,0 +1,162 @@
+package stats
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dogpakk/lib/financial"
+)
+
+func TestSummarise(t *testing.T) {
+	tests := []struct {
+		name     string
+		amounts  []financial.Cents
+		expected Summary
+	}{
+		{
+			name: "nothing",
+		},
+		{
+			name:     "one amount",
+			amounts:  []financial.Cents{999},
+			expected: Summary{Count: 1, Weight: 1, Sum: 999, Min: 999, Max: 999, Mean: 999, Median: 999, P25: 999, P75: 999, P90: 999, P95: 999},
+		},
+		{
+			name:    "four amounts",
+			amounts: []financial.Cents{400, 100, 300, 200},
+			expected: Summary{
+				Count: 4, Weight: 4, Sum: 1000, Min: 100, Max: 400, Mean: 250,
+				Median: 250, P25: 175, P75: 325, P90: 370, P95: 385, StdDev: 112,
+			},
+		},
+		{
+			name:    "negative amounts are included",
+			amounts: []financial.Cents{-500, 500, -500},
+			expected: Summary{
+				Count: 3, Weight: 3, Sum: -500, Min: -500, Max: 500, Mean: -167,
+				Median: -500, P25: -500, P75: 0, P90: 300, P95: 400, StdDev: 471,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		got := Summarise(test.amounts)
+		if got != test.expected {
+			t.Errorf("Testing %s. Expected %+v; got %+v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestSummariseWeighted(t *testing.T) {
+	// Average selling price: 3 sold at 10.00 and 1 at 20.00
+	got, err := SummariseWeighted([]financial.Cents{1000, 2000, 5000}, []int{3, 1, 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Summary{
+		Count: 2, Weight: 4, Sum: 5000, Min: 1000, Max: 2000, Mean: 1250,
+		Median: 1000, P25: 1000, P75: 1250, P90: 1700, P95: 1850, StdDev: 433,
+	}
+	if got != expected {
+		t.Errorf("Testing weighted. Expected %+v; got %+v", expected, got)
+	}
+
+	if _, err := SummariseWeighted([]financial.Cents{1000}, nil); !errors.Is(err, ErrWeightsMismatch) {
+		t.Errorf("Testing mismatched weights. Expected %v; got %v", ErrWeightsMismatch, err)
+	}
+}
+
+func TestAccumulatorMerge(t *testing.T) {
+	a, b := NewAccumulator(), NewAccumulator()
+	a.Add(100)
+	a.AddWeighted(200, 2)
+	b.Add(300)
+	b.Add(400)
+	a.Merge(b)
+	a.Merge(NewAccumulator())
+	a.Merge(nil)
+
+	expected, _ := SummariseWeighted([]financial.Cents{100, 200, 300, 400}, []int{1, 2, 1, 1})
+	if a.Summary() != expected {
+		t.Errorf("Testing merge. Expected %+v; got %+v", expected, a.Summary())
+	}
+
+	empty := NewAccumulator()
+	empty.Merge(b)
+	if empty.Summary() != b.Summary() {
+		t.Errorf("Testing merge into empty. Expected %+v; got %+v", b.Summary(), empty.Summary())
+	}
+
+	if a.Percentile(0) != 100 || a.Percentile(100) != 400 || NewAccumulator().Percentile(50) != 0 {
+		t.Errorf("Testing percentile limits. Expected 100, 400 and 0; got %v, %v and %v", a.Percentile(0), a.Percentile(100), NewAccumulator().Percentile(50))
+	}
+}
+
+func TestAccumulatorBins(t *testing.T) {
+	exact, binned := NewExactAccumulator(), NewAccumulatorWithBins(100)
+	for i := 100000; i > 0; i-- {
+		exact.AddWeighted(financial.Cents(i), i%3+1)
+		binned.AddWeighted(financial.Cents(i), i%3+1)
+	}
+
+	if len(exact.weights) != 100000 {
+		t.Errorf("Testing exact. Expected %d distinct amounts; got %d", 100000, len(exact.weights))
+	}
+
+	if len(binned.weights) > 200 || len(binned.weights) < 50 {
+		t.Errorf("Testing bins. Expected between 50 and %d distinct amounts; got %d", 200, len(binned.weights))
+	}
+
+	e, b := exact.Summary(), binned.Summary()
+	if b.Min != 1 || b.Max != 100000 || b.Count != e.Count || b.Weight != e.Weight || b.Sum != e.Sum || b.Mean != e.Mean || b.StdDev != e.StdDev {
+		t.Errorf("Testing bins. Expected %+v apart from the percentiles; got %+v", e, b)
+	}
+
+	for _, pair := range [][2]financial.Cents{{e.Median, b.Median}, {e.P25, b.P25}, {e.P75, b.P75}, {e.P90, b.P90}, {e.P95, b.P95}} {
+		if diff := pair[0] - pair[1]; diff > 1000 || diff < -1000 {
+			t.Errorf("Testing bins. Expected a percentile within 1%% of %v; got %v", pair[0], pair[1])
+		}
+	}
+
+	ps := exact.Percentiles(95, 5, 50)
+	if ps[0] != exact.Percentile(95) || ps[1] != exact.Percentile(5) || ps[2] != e.Median {
+		t.Errorf("Testing Percentiles. Expected %v, %v and %v; got %v", exact.Percentile(95), exact.Percentile(5), e.Median, ps)
+	}
+}
+
+func TestCurrencyAccumulator(t *testing.T) {
+	got := SummariseCentDicts([]financial.CentDict{
+		{"GBP": 1000, "EUR": 500},
+		{"GBP": 3000},
+	})
+
+	if got["GBP"].Count != 2 || got["GBP"].Mean != 2000 || got["GBP"].Median != 2000 {
+		t.Errorf("Testing GBP. Expected 2 amounts with a mean and median of 2000; got %+v", got["GBP"])
+	}
+
+	if got["EUR"].Count != 1 || got["EUR"].Sum != 500 {
+		t.Errorf("Testing EUR. Expected 1 amount of 500; got %+v", got["EUR"])
+	}
+
+	ca := NewCurrencyAccumulator()
+	ca.AddMoney(financial.Money{Amount: 100, Currency: "USD"})
+	ca.AddWeighted("CHF", 250, 4)
+
+	other := NewCurrencyAccumulator()
+	other.Add("USD", 300)
+	ca.Merge(other)
+
+	if currencies := ca.Currencies(); !reflect.DeepEqual(currencies, []string{"CHF", "USD"}) {
+		t.Errorf("Testing currencies. Expected %v; got %v", []string{"CHF", "USD"}, currencies)
+	}
+
+	if ca.Accumulator("USD").Summary().Mean != 200 || ca.Accumulator("CHF").Summary().Sum != 1000 {
+		t.Errorf("Testing currency accumulators. Expected a USD mean of 200 and a CHF sum of 1000; got %+v", ca.Summaries())
+	}
+
+	if ca.Accumulator("JPY") != nil {
+		t.Errorf("Testing missing currency. Expected nil; got %v", ca.Accumulator("JPY"))
+	}
+}
@@ -0,0 +1,72 @@
+package financial
+
+import "sync"
+
+// SyncCentDict is a CentDict that can be added to from several goroutines at once,
+// e.g. by report workers that each aggregate a share of the orders.
+// The zero value is ready to use, and it must not be copied after first use.
+type SyncCentDict struct {
+	mu sync.RWMutex
+	cd CentDict
+}
+
+func NewSyncCentDict() *SyncCentDict {
+	return &SyncCentDict{cd: CentDict{}}
+}
+
+func (s *SyncCentDict) Add(k string, amount Cents) {
+	s.mu.Lock()
+	s.cd.Add(k, amount)
+	s.mu.Unlock()
+}
+
+// Merge adds all of the incoming values in one go, so it is much cheaper than calling
+// Add for each key when a worker has built up its own CentDict
+func (s *SyncCentDict) Merge(incoming CentDict) {
+	s.mu.Lock()
+	s.cd.Merge(incoming)
+	s.mu.Unlock()
+}
+
+// MergeFrom adds the values of another SyncCentDict, as they are at the time of the call.
+// Only one of the locks is held at a time, so two dicts merging from each other can't deadlock.
+func (s *SyncCentDict) MergeFrom(other *SyncCentDict) {
+	s.Merge(other.Snapshot())
+}
+
+func (s *SyncCentDict) Get(k string) Cents {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.cd[k]
+}
+
+func (s *SyncCentDict) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return len(s.cd)
+}
+
+// Snapshot returns a copy of the values, which the caller is free to change
+func (s *SyncCentDict) Snapshot() CentDict {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.cd.Copy()
+}
+
+// Swap returns the values and starts again from empty, in one step, so that nothing
+// added in between is lost.  Useful for flushing running totals periodically.
+func (s *SyncCentDict) Swap() CentDict {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := s.cd
+	if res == nil {
+		res = CentDict{}
+	}
+	s.cd = CentDict{}
+
+	return res
+}
@@ -0,0 +1,150 @@
+package financial
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestCentDictNilSafe(t *testing.T) {
+	cd := nilCentDict()
+	cd.Add("GBP", 100)
+	cd.Add("GBP", 50)
+	if cd["GBP"] != 150 {
+		t.Errorf("Testing Add on nil. Expected %v; got %v", 150, cd["GBP"])
+	}
+
+	var merged CentDict
+	merged.Merge(CentDict{"EUR": 10, "GBP": 5})
+	merged.Merge(nil)
+	if !merged.Compare(CentDict{"EUR": 10, "GBP": 5}) {
+		t.Errorf("Testing Merge on nil. Expected %v; got %v", CentDict{"EUR": 10, "GBP": 5}, merged)
+	}
+
+	// A nil CentDict inside a struct, as found in report rows
+	row := struct{ Totals CentDict }{}
+	row.Totals.Add("USD", 1)
+	if row.Totals["USD"] != 1 {
+		t.Errorf("Testing Add on nil field. Expected %v; got %v", 1, row.Totals["USD"])
+	}
+
+	copied := merged.Copy()
+	copied.Add("EUR", 1)
+	if merged["EUR"] != 10 {
+		t.Errorf("Testing Copy. Expected the original to be unchanged; got %v", merged)
+	}
+
+	if nilCentDict().Copy() == nil {
+		t.Errorf("Testing Copy of nil. Expected an empty CentDict; got nil")
+	}
+}
+
+func TestSyncCentDictConcurrent(t *testing.T) {
+	const workers = 20
+	const perWorker = 500
+
+	var s SyncCentDict
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			local := CentDict{}
+			for i := 0; i < perWorker; i++ {
+				s.Add("GBP", 1)
+				local.AddToKey(fmt.Sprintf("W%d", w%4), 2)
+
+				// Readers running alongside the writers
+				if i%100 == 0 {
+					_ = s.Snapshot()
+					_ = s.Get("GBP")
+				}
+			}
+			s.Merge(local)
+		}(w)
+	}
+
+	wg.Wait()
+
+	snapshot := s.Snapshot()
+	if snapshot["GBP"] != workers*perWorker {
+		t.Errorf("Testing concurrent Add. Expected %v; got %v", workers*perWorker, snapshot["GBP"])
+	}
+
+	for i := 0; i < 4; i++ {
+		k := fmt.Sprintf("W%d", i)
+		expected := Cents(workers / 4 * perWorker * 2)
+		if snapshot[k] != expected {
+			t.Errorf("Testing concurrent Merge %s. Expected %v; got %v", k, expected, snapshot[k])
+		}
+	}
+
+	// Changing the snapshot mustn't change the original
+	snapshot.AddToKey("GBP", 1)
+	if s.Get("GBP") != workers*perWorker {
+		t.Errorf("Testing Snapshot is a copy. Expected %v; got %v", workers*perWorker, s.Get("GBP"))
+	}
+}
+
+func TestSyncCentDictSwap(t *testing.T) {
+	s := NewSyncCentDict()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var swapped CentDict
+
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			s.Add("GBP", 1)
+		}()
+		go func() {
+			defer wg.Done()
+			res := s.Swap()
+			mu.Lock()
+			swapped.Merge(res)
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	// Nothing added is lost between the swaps
+	if total := swapped["GBP"] + s.Get("GBP"); total != 100 {
+		t.Errorf("Testing Swap. Expected %v; got %v", 100, total)
+	}
+
+	var empty SyncCentDict
+	if res := empty.Swap(); res == nil || len(res) != 0 {
+		t.Errorf("Testing Swap on zero value. Expected an empty CentDict; got %v", res)
+	}
+	if empty.Len() != 0 {
+		t.Errorf("Testing Len. Expected %v; got %v", 0, empty.Len())
+	}
+}
+
+func TestSyncCentDictMergeFrom(t *testing.T) {
+	a, b := NewSyncCentDict(), NewSyncCentDict()
+	a.Add("GBP", 1)
+	b.Add("EUR", 2)
+
+	// Merging both ways at once mustn't deadlock
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		a.MergeFrom(b)
+	}()
+	go func() {
+		defer wg.Done()
+		b.MergeFrom(a)
+	}()
+	wg.Wait()
+
+	if a.Get("EUR") != 2 || b.Get("GBP") < 1 {
+		t.Errorf("Testing MergeFrom. Got %v and %v", a.Snapshot(), b.Snapshot())
+	}
+}
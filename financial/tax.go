@@ -1,5 +1,7 @@
 package financial
 
+import "math/big"
+
 // Important
 // This is an implementation of 'unit' method or 'early rounding' calculation
 // as described in this post https://pakk.io/post/vat-rounding
@@ -15,8 +17,18 @@ const (
 
 type TaxCalc struct {
 	RoundingMethod TaxRoundingMethod
-	LineQty        int
-	TaxPercentage  float64
+	// RoundingMode is which way amounts are rounded, whichever method is used.  The default is half up.
+	RoundingMode  RoundingMode
+	LineQty       int
+	TaxPercentage float64
+	// Treatment is how tax applies to the line.  Only the default, standard treatment actually adds tax.
+	Treatment TaxTreatment
+
+	// Components is optional, and is for places that charge more than one tax
+	// on the same line, such as Canada and the US.  When there are components,
+	// TaxPercentage is worked out from them.
+	Components     TaxComponents
+	ComponentTaxes []TaxComponentAmount
 
 	UnitEx, LineEx, LineTax, LineInc Cents
 }
@@ -47,29 +59,79 @@ func (tx *TaxCalc) startFromInc() {
 // AddTax adds tax for a line unit price and qty.  By default it uses the "line" rounding method,
 // but can also use the "unit" method.  The "total" method is irrelevant here because
 // line tax totals don't come into place in that case, so we just use the default line method as well.
+// Use a TaxDocument to apply the "totals" method across a set of lines.
+// Amounts that would overflow are saturated; use AddTaxChecked to find out if that happened.
 func (tx *TaxCalc) AddTax() {
+	tx.addTax(nil)
+}
+
+// AddTaxChecked is AddTax, returning ErrOverflow if any of the amounts overflowed
+func (tx *TaxCalc) AddTaxChecked() error {
+	var oc overflowCheck
+	tx.addTax(&oc)
+	return oc.err
+}
+
+func (tx *TaxCalc) addTax(oc *overflowCheck) {
+	if !tx.Treatment.ChargesTax() {
+		tx.addNoTax(oc)
+		return
+	}
+
+	if len(tx.Components) > 0 {
+		tx.addComponentTaxes(oc)
+		return
+	}
+
 	if tx.RoundingMethod == TaxRoundingMethodUnit {
-		tx.AddTaxUnitMethod()
+		tx.addTaxUnitMethod(oc)
 		return
 	}
 
-	tx.AddTaxLineMethod()
+	tx.addTaxLineMethod(oc)
 }
 
 // RemoveTax removes tax for a line unit price and qty.  By default it uses the "line" rounding method,
 // but can also use the "unit" method.  The "total" method is irrelevant here because
 // line tax totals don't come into place in that case, so we just use the default line method as well.
+// Use a TaxDocument to apply the "totals" method across a set of lines.
+// Amounts that would overflow are saturated; use RemoveTaxChecked to find out if that happened.
 func (tx *TaxCalc) RemoveTax() {
+	tx.removeTax(nil)
+}
+
+// RemoveTaxChecked is RemoveTax, returning ErrOverflow if any of the amounts overflowed
+func (tx *TaxCalc) RemoveTaxChecked() error {
+	var oc overflowCheck
+	tx.removeTax(&oc)
+	return oc.err
+}
+
+func (tx *TaxCalc) removeTax(oc *overflowCheck) {
+	if !tx.Treatment.ChargesTax() {
+		tx.removeNoTax()
+		return
+	}
+
+	if len(tx.Components) > 0 {
+		tx.removeComponentTaxes(oc)
+		return
+	}
+
 	if tx.RoundingMethod == TaxRoundingMethodUnit {
-		tx.RemoveTaxUnitMethod()
+		tx.removeTaxUnitMethod(oc)
 		return
 	}
 
-	tx.RemoveTaxLineMethod()
+	tx.removeTaxLineMethod(oc)
 }
 
 // AddTax goes from a unit ex price, qty and tax percentage to total line ex, tax and inc
 func (tx *TaxCalc) AddTaxUnitMethod() {
+	tx.addTaxUnitMethod(nil)
+}
+
+func (tx *TaxCalc) addTaxUnitMethod(oc *overflowCheck) {
 	qty := tx.LineQty
 	taxPercentage := tx.TaxPercentage
 
@@ -84,17 +146,21 @@ func (tx *TaxCalc) AddTaxUnitMethod() {
 
 	// Unit tax amount and inc tax are first calculated so they are correct
 	// in and of themselves
-	unitTax := tx.UnitEx.ByPercentage(taxPercentage)
-	unitInc := tx.UnitEx + unitTax
+	unitTax := oc.byPercentage(tx.UnitEx, taxPercentage, tx.RoundingMode)
+	unitInc := oc.add(tx.UnitEx, unitTax)
 
-	tx.LineEx = tx.UnitEx * Cents(qty)
-	tx.LineTax = unitTax * Cents(qty)
-	tx.LineInc = unitInc * Cents(qty)
+	tx.LineEx = oc.mulQty(tx.UnitEx, qty)
+	tx.LineTax = oc.mulQty(unitTax, qty)
+	tx.LineInc = oc.mulQty(unitInc, qty)
 
 	return
 }
 
 func (tx *TaxCalc) AddTaxLineMethod() {
+	tx.addTaxLineMethod(nil)
+}
+
+func (tx *TaxCalc) addTaxLineMethod(oc *overflowCheck) {
 	qty := tx.LineQty
 	taxPercentage := tx.TaxPercentage
 
@@ -109,14 +175,18 @@ func (tx *TaxCalc) AddTaxLineMethod() {
 
 	// This is the line method, so multiply out the line ex total first
 	// and use that as the basis of the tax calculation
-	tx.LineEx = tx.UnitEx * Cents(qty)
-	tx.LineTax = tx.LineEx.ByPercentage(taxPercentage)
-	tx.LineInc = tx.LineEx + tx.LineTax
+	tx.LineEx = oc.mulQty(tx.UnitEx, qty)
+	tx.LineTax = oc.byPercentage(tx.LineEx, taxPercentage, tx.RoundingMode)
+	tx.LineInc = oc.add(tx.LineEx, tx.LineTax)
 
 	return
 }
 
 func (tx *TaxCalc) RemoveTaxUnitMethod() {
+	tx.removeTaxUnitMethod(nil)
+}
+
+func (tx *TaxCalc) removeTaxUnitMethod(oc *overflowCheck) {
 	qty := tx.LineQty
 	taxPercentage := tx.TaxPercentage
 
@@ -132,15 +202,19 @@ func (tx *TaxCalc) RemoveTaxUnitMethod() {
 	// Remeber, this is the unit method, so division by qty is first
 	// which gets us to a unit inc
 	unitInc := tx.LineInc / Cents(qty)
-	unitEx := unitInc.RemovePercentage(taxPercentage)
-	unitTax := unitInc - unitEx
+	unitEx := oc.removePercentage(unitInc, taxPercentage, tx.RoundingMode)
+	unitTax := oc.sub(unitInc, unitEx)
 
 	tx.UnitEx = unitEx
-	tx.LineEx = unitEx * Cents(qty)
-	tx.LineTax = unitTax * Cents(qty)
+	tx.LineEx = oc.mulQty(unitEx, qty)
+	tx.LineTax = oc.mulQty(unitTax, qty)
 }
 
 func (tx *TaxCalc) RemoveTaxLineMethod() {
+	tx.removeTaxLineMethod(nil)
+}
+
+func (tx *TaxCalc) removeTaxLineMethod(oc *overflowCheck) {
 	qty := tx.LineQty
 	taxPercentage := tx.TaxPercentage
 
@@ -153,8 +227,8 @@ func (tx *TaxCalc) RemoveTaxLineMethod() {
 	// Reset
 	tx.startFromInc()
 
-	tx.LineEx = tx.LineInc.RemovePercentage(taxPercentage)
-	tx.LineTax = tx.LineInc - tx.LineEx
+	tx.LineEx = oc.removePercentage(tx.LineInc, taxPercentage, tx.RoundingMode)
+	tx.LineTax = oc.sub(tx.LineInc, tx.LineEx)
 	tx.UnitEx = tx.LineEx / Cents(qty)
 }
 
@@ -166,19 +240,20 @@ func (txs TaxCalcs) CalcAggTaxRate() float64 {
 	// I don't use the normal VAT rounding technique here as it leads to roudning errors
 	// being transmitted to the aggregate tax percetnage, so, e.g. multiple products with a 20%
 	// VAT rate can end up having an aggregate of 20.02% or something like that.
-	var totalEx, totalTax float64
+	// The sums are done exactly, so there is no float drift either, and the only
+	// rounding is converting the final answer back to a float.
+	totalEx, totalTax := new(big.Rat), new(big.Rat)
 
 	for _, tx := range txs {
-		lineEx := float64(tx.UnitEx) * float64(tx.LineQty)
-		totalEx = totalEx + lineEx
-		tax := lineEx * (tx.TaxPercentage / 100)
-		totalTax = totalTax + tax
+		lineEx := centsRat(tx.UnitEx.ByQty(tx.LineQty))
+		totalEx.Add(totalEx, lineEx)
+		totalTax.Add(totalTax, new(big.Rat).Mul(lineEx, percentageRat(tx.TaxPercentage)))
 	}
 
 	// Divide by zero protection
-	if totalEx == 0 {
+	if totalEx.Sign() == 0 {
 		return 0
 	}
 
-	return (totalTax / totalEx) * 100
+	return ratFloat(new(big.Rat).Mul(new(big.Rat).Quo(totalTax, totalEx), hundred))
 }
@@ -0,0 +1,216 @@
+package financial
+
+import (
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// TaxComponent is one of several taxes charged on the same line, e.g. GST and PST in Canada,
+// or state, county and city sales tax in the US.
+// A compounding component is charged on the ex amount plus all of the components before it,
+// as QST in Quebec used to be, so the order of the components matters.
+type TaxComponent struct {
+	Jurisdiction  string
+	TaxPercentage float64
+	Compound      bool
+}
+
+type TaxComponents []TaxComponent
+
+// TaxComponentAmount is the tax charged for a single component of a TaxCalc
+type TaxComponentAmount struct {
+	TaxComponent
+	UnitTax, LineTax Cents
+}
+
+// EffectivePercentages is the percentage of the ex amount that each component works out at,
+// which only differs from its TaxPercentage when it compounds
+func (tcs TaxComponents) EffectivePercentages() []float64 {
+	res := make([]float64, len(tcs))
+	for i, r := range tcs.effectiveRats() {
+		res[i] = ratFloat(new(big.Rat).Mul(r, hundred))
+	}
+
+	return res
+}
+
+// effectiveRats are the exact effective multipliers of each component
+func (tcs TaxComponents) effectiveRats() []*big.Rat {
+	res := make([]*big.Rat, len(tcs))
+
+	sofar := big.NewRat(1, 1)
+	for i, tc := range tcs {
+		res[i] = percentageRat(tc.TaxPercentage)
+		if tc.Compound {
+			res[i].Mul(res[i], sofar)
+		}
+		sofar = new(big.Rat).Add(sofar, res[i])
+	}
+
+	return res
+}
+
+// effectiveRat is the exact effective multiplier of all of the components
+func (tcs TaxComponents) effectiveRat() *big.Rat {
+	total := new(big.Rat)
+	for _, r := range tcs.effectiveRats() {
+		total.Add(total, r)
+	}
+
+	return total
+}
+
+// EffectivePercentage is the total tax percentage of all the components, taking compounding into account,
+// e.g. 5% GST and 9.975% QST is 14.975%
+func (tcs TaxComponents) EffectivePercentage() float64 {
+	return ratFloat(new(big.Rat).Mul(tcs.effectiveRat(), hundred))
+}
+
+// key identifies the components, in order, so that lines can be grouped by them.  No components is
+// the empty string.  The jurisdictions are quoted so that nothing in them can be mistaken for a separator.
+func (tcs TaxComponents) key() string {
+	var b strings.Builder
+	for i, tc := range tcs {
+		if i > 0 {
+			b.WriteByte(';')
+		}
+		b.WriteString(strconv.Quote(tc.Jurisdiction))
+		b.WriteByte(',')
+		b.WriteString(strconv.FormatFloat(tc.TaxPercentage, 'g', -1, 64))
+		b.WriteByte(',')
+		b.WriteString(strconv.FormatBool(tc.Compound))
+	}
+
+	return b.String()
+}
+
+// calcComponentTaxes works forwards from an ex amount, each component rounded in its own right
+func (tcs TaxComponents) calcComponentTaxes(ex Cents, mode RoundingMode, oc *overflowCheck) []Cents {
+	res := make([]Cents, len(tcs))
+
+	var sofar Cents
+	for i, tc := range tcs {
+		base := ex
+		if tc.Compound {
+			base = oc.add(ex, sofar)
+		}
+
+		res[i] = oc.byPercentage(base, tc.TaxPercentage, mode)
+		sofar = oc.add(sofar, res[i])
+	}
+
+	return res
+}
+
+// splitComponentTaxes works backwards from a tax amount, allocating it across the components
+// in line with their effective percentages so that they always add up to the total
+func (tcs TaxComponents) splitComponentTaxes(tax Cents) []Cents {
+	return tax.AllocateByFloatRatios(tcs.EffectivePercentages())
+}
+
+func (tx *TaxCalc) setComponentTaxes(unitTaxes, lineTaxes []Cents) {
+	tx.ComponentTaxes = make([]TaxComponentAmount, len(tx.Components))
+	for i, tc := range tx.Components {
+		tx.ComponentTaxes[i] = TaxComponentAmount{
+			TaxComponent: tc,
+			UnitTax:      unitTaxes[i],
+			LineTax:      lineTaxes[i],
+		}
+	}
+}
+
+func (tx *TaxCalc) blankComponentTaxes() {
+	zeros := make([]Cents, len(tx.Components))
+	tx.setComponentTaxes(zeros, zeros)
+}
+
+func byQty(cs []Cents, qty int, oc *overflowCheck) []Cents {
+	res := make([]Cents, len(cs))
+	for i, c := range cs {
+		res[i] = oc.mulQty(c, qty)
+	}
+
+	return res
+}
+
+// divideByQty is the (rounded) unit amount of each line amount, or zero if there is no qty
+func divideByQty(cs []Cents, qty int) []Cents {
+	res := make([]Cents, len(cs))
+	if qty == 0 {
+		return res
+	}
+
+	for i, c := range cs {
+		res[i] = c.DivideByQty(qty)
+	}
+
+	return res
+}
+
+// addComponentTaxes is AddTax for a TaxCalc with components.  With the unit method,
+// each component is calculated and rounded on the unit price, otherwise on the line ex.
+func (tx *TaxCalc) addComponentTaxes(oc *overflowCheck) {
+	tx.TaxPercentage = tx.Components.EffectivePercentage()
+	qty := tx.LineQty
+
+	if qty == 0 {
+		tx.blank()
+		tx.blankComponentTaxes()
+		return
+	}
+
+	tx.startFromUnitEx()
+	tx.LineEx = oc.mulQty(tx.UnitEx, qty)
+
+	var unitTaxes, lineTaxes []Cents
+	if tx.RoundingMethod == TaxRoundingMethodUnit {
+		unitTaxes = tx.Components.calcComponentTaxes(tx.UnitEx, tx.RoundingMode, oc)
+		lineTaxes = byQty(unitTaxes, qty, oc)
+	} else {
+		lineTaxes = tx.Components.calcComponentTaxes(tx.LineEx, tx.RoundingMode, oc)
+		unitTaxes = divideByQty(lineTaxes, qty)
+	}
+
+	for _, t := range lineTaxes {
+		tx.LineTax = oc.add(tx.LineTax, t)
+	}
+	tx.LineInc = oc.add(tx.LineEx, tx.LineTax)
+	tx.setComponentTaxes(unitTaxes, lineTaxes)
+}
+
+// removeComponentTaxes is RemoveTax for a TaxCalc with components.  The total tax is taken out
+// using the effective percentage, and then split between the components.
+func (tx *TaxCalc) removeComponentTaxes(oc *overflowCheck) {
+	tx.TaxPercentage = tx.Components.EffectivePercentage()
+	qty := tx.LineQty
+
+	if qty == 0 {
+		tx.blank()
+		tx.blankComponentTaxes()
+		return
+	}
+
+	if tx.RoundingMethod == TaxRoundingMethodUnit {
+		tx.removeTaxUnitMethod(oc)
+		unitTaxes := tx.Components.splitComponentTaxes(tx.LineTax / Cents(qty))
+		tx.setComponentTaxes(unitTaxes, byQty(unitTaxes, qty, oc))
+		return
+	}
+
+	tx.removeTaxLineMethod(oc)
+	lineTaxes := tx.Components.splitComponentTaxes(tx.LineTax)
+	tx.setComponentTaxes(divideByQty(lineTaxes, qty), lineTaxes)
+}
+
+// ComponentTotals adds up the tax for each jurisdiction across all of the lines
+func (txs TaxCalcs) ComponentTotals() CentDict {
+	res := CentDict{}
+	for _, tx := range txs {
+		for _, ct := range tx.ComponentTaxes {
+			res.AddToKey(ct.Jurisdiction, ct.LineTax)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,234 @@
+package financial
+
+import (
+	"reflect"
+	"testing"
+)
+
+var (
+	britishColumbia = TaxComponents{
+		{Jurisdiction: "CA", TaxPercentage: 5},
+		{Jurisdiction: "CA-BC", TaxPercentage: 7},
+	}
+
+	quebecCompound = TaxComponents{
+		{Jurisdiction: "CA", TaxPercentage: 5},
+		{Jurisdiction: "CA-QC", TaxPercentage: 9.5, Compound: true},
+	}
+
+	newYorkCity = TaxComponents{
+		{Jurisdiction: "US-NY", TaxPercentage: 4},
+		{Jurisdiction: "US-NY-NYC", TaxPercentage: 4.5},
+		{Jurisdiction: "US-NY-MCTD", TaxPercentage: 0.375},
+	}
+)
+
+func TestTaxComponentsEffectivePercentage(t *testing.T) {
+	tests := []struct {
+		name       string
+		components TaxComponents
+		expected   float64
+	}{
+		{"none", nil, 0},
+		{"simple", britishColumbia, 12},
+		{"compound", quebecCompound, 14.975},
+		{"three components", newYorkCity, 8.875},
+	}
+
+	for _, test := range tests {
+		res := test.components.EffectivePercentage()
+		if res != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+	}
+}
+
+func TestTaxComponentsAddTax(t *testing.T) {
+	tests := []struct {
+		name                   string
+		in                     TaxCalc
+		expectedComponentTaxes []Cents
+		expectedLineTax        Cents
+	}{
+		{
+			name:                   "qty 0",
+			in:                     TaxCalc{UnitEx: 1000, Components: britishColumbia},
+			expectedComponentTaxes: []Cents{0, 0},
+		},
+		{
+			name:                   "GST and PST",
+			in:                     TaxCalc{UnitEx: 1000, LineQty: 1, Components: britishColumbia},
+			expectedComponentTaxes: []Cents{50, 70},
+			expectedLineTax:        120,
+		},
+		{
+			name:                   "compounding QST is charged on the GST too",
+			in:                     TaxCalc{UnitEx: 1000, LineQty: 1, Components: quebecCompound},
+			expectedComponentTaxes: []Cents{50, 100},
+			expectedLineTax:        150,
+		},
+		{
+			name:                   "state, city and district, unit method",
+			in:                     TaxCalc{UnitEx: 999, LineQty: 3, Components: newYorkCity},
+			expectedComponentTaxes: []Cents{120, 135, 12},
+			expectedLineTax:        267,
+		},
+		{
+			name:                   "state, city and district, line method",
+			in:                     TaxCalc{UnitEx: 999, LineQty: 3, Components: newYorkCity, RoundingMethod: TaxRoundingMethodLine},
+			expectedComponentTaxes: []Cents{120, 135, 11},
+			expectedLineTax:        266,
+		},
+	}
+
+	for _, test := range tests {
+		test.in.AddTax()
+		checkComponentTaxes(t, test.name, test.in, test.expectedComponentTaxes, test.expectedLineTax)
+
+		if test.in.LineInc != test.in.LineEx+test.in.LineTax {
+			t.Errorf("Testing %s. Line doesn't add up: %v", test.name, test.in)
+		}
+
+		if test.in.TaxPercentage != test.in.Components.EffectivePercentage() {
+			t.Errorf("Testing %s. TaxPercentage should be the effective percentage, got %v", test.name, test.in.TaxPercentage)
+		}
+	}
+}
+
+func TestTaxComponentsRemoveTax(t *testing.T) {
+	tests := []struct {
+		name                   string
+		in                     TaxCalc
+		expectedEx             Cents
+		expectedComponentTaxes []Cents
+		expectedLineTax        Cents
+	}{
+		{
+			name:                   "GST and PST, line method",
+			in:                     TaxCalc{LineInc: 1120, LineQty: 1, Components: britishColumbia, RoundingMethod: TaxRoundingMethodLine},
+			expectedEx:             1000,
+			expectedComponentTaxes: []Cents{50, 70},
+			expectedLineTax:        120,
+		},
+		{
+			name:                   "compound, unit method",
+			in:                     TaxCalc{LineInc: 2300, LineQty: 2, Components: quebecCompound},
+			expectedEx:             2000,
+			expectedComponentTaxes: []Cents{100, 200},
+			expectedLineTax:        300,
+		},
+		{
+			name:                   "awkward amount still adds up",
+			in:                     TaxCalc{LineInc: 1001, LineQty: 1, Components: newYorkCity, RoundingMethod: TaxRoundingMethodLine},
+			expectedEx:             919,
+			expectedComponentTaxes: []Cents{37, 42, 3},
+			expectedLineTax:        82,
+		},
+	}
+
+	for _, test := range tests {
+		test.in.RemoveTax()
+		checkComponentTaxes(t, test.name, test.in, test.expectedComponentTaxes, test.expectedLineTax)
+
+		if test.in.LineEx != test.expectedEx {
+			t.Errorf("Testing %s. Expected ex %v; got %v", test.name, test.expectedEx, test.in.LineEx)
+		}
+	}
+}
+
+func TestTaxComponentsInTaxDocument(t *testing.T) {
+	lines := TaxCalcs{
+		{UnitEx: 333, LineQty: 1, Components: britishColumbia},
+		{UnitEx: 333, LineQty: 1, Components: britishColumbia},
+		{UnitEx: 333, LineQty: 1, Components: britishColumbia},
+	}
+
+	td := NewTaxDocument(TaxRoundingMethodTotals, lines)
+	td.AddTax()
+
+	if td.TotalTax != 120 {
+		t.Errorf("Expected total tax of 120; got %v", td.TotalTax)
+	}
+
+	totals := td.Lines.ComponentTotals()
+	expected := CentDict{"CA": 50, "CA-BC": 70}
+	if !totals.Compare(expected) {
+		t.Errorf("Expected component totals %v; got %v", expected, totals)
+	}
+}
+
+func TestTaxComponentsAnalysis(t *testing.T) {
+	quebec := TaxComponents{{Jurisdiction: "CA", TaxPercentage: 5}, {Jurisdiction: "CA-QC", TaxPercentage: 9.975}}
+	gstPlusTen := TaxComponents{{Jurisdiction: "CA", TaxPercentage: 5}, {Jurisdiction: "CA-XX", TaxPercentage: 10}}
+	novaScotia := TaxComponents{{Jurisdiction: "CA-NS", TaxPercentage: 15}}
+
+	for _, method := range []TaxRoundingMethod{TaxRoundingMethodLine, TaxRoundingMethodTotals} {
+		td := NewTaxDocument(method, TaxCalcs{
+			{UnitEx: 1000, LineQty: 1, Components: novaScotia},
+			{UnitEx: 1000, LineQty: 1, Components: quebec},
+			{UnitEx: 1000, LineQty: 1, Components: gstPlusTen},
+			{UnitEx: 1000, LineQty: 1, TaxPercentage: 15},
+			{UnitEx: 1000, LineQty: 1, Components: novaScotia},
+		})
+		td.AddTax()
+
+		expected := []TaxRateAnalysis{
+			{TaxPercentage: quebec.EffectivePercentage(), Components: quebec, LineCount: 1, Ex: 1000, Tax: 150, Inc: 1150},
+			{TaxPercentage: 15, LineCount: 1, Ex: 1000, Tax: 150, Inc: 1150},
+			{TaxPercentage: 15, Components: gstPlusTen, LineCount: 1, Ex: 1000, Tax: 150, Inc: 1150},
+			{TaxPercentage: 15, Components: novaScotia, LineCount: 2, Ex: 2000, Tax: 300, Inc: 2300},
+		}
+
+		if !reflect.DeepEqual(td.Analysis, expected) {
+			t.Errorf("Testing %v analysis. Expected %v; got %v", method, expected, td.Analysis)
+		}
+	}
+}
+
+func TestTaxComponentsKey(t *testing.T) {
+	tests := []struct {
+		a, b TaxComponents
+	}{
+		{nil, TaxComponents{{Jurisdiction: "", TaxPercentage: 0}}},
+		{TaxComponents{{Jurisdiction: "CA", TaxPercentage: 5}}, TaxComponents{{Jurisdiction: "CA", TaxPercentage: 5, Compound: true}}},
+		{
+			TaxComponents{{Jurisdiction: "CA", TaxPercentage: 5}, {Jurisdiction: "CA-QC", TaxPercentage: 9.975}},
+			TaxComponents{{Jurisdiction: "CA-QC", TaxPercentage: 9.975}, {Jurisdiction: "CA", TaxPercentage: 5}},
+		},
+		{
+			TaxComponents{{Jurisdiction: "A", TaxPercentage: 5}, {Jurisdiction: "B", TaxPercentage: 5}},
+			TaxComponents{{Jurisdiction: `A",5,false;"B`, TaxPercentage: 5}},
+		},
+	}
+
+	for _, test := range tests {
+		if test.a.key() == test.b.key() {
+			t.Errorf("Testing %v and %v. Expected different keys; got %q for both", test.a, test.b, test.a.key())
+		}
+	}
+
+	same := TaxComponents{{Jurisdiction: "CA", TaxPercentage: 5}}
+	if same.key() != append(TaxComponents{}, same...).key() {
+		t.Errorf("Testing %v. Expected the same key for a copy", same)
+	}
+}
+
+func checkComponentTaxes(t *testing.T, name string, tx TaxCalc, expectedComponentTaxes []Cents, expectedLineTax Cents) {
+	t.Helper()
+
+	if len(tx.ComponentTaxes) != len(expectedComponentTaxes) {
+		t.Fatalf("Testing %s. Expected %d component taxes; got %v", name, len(expectedComponentTaxes), tx.ComponentTaxes)
+	}
+
+	var total Cents
+	for i, ct := range tx.ComponentTaxes {
+		if ct.LineTax != expectedComponentTaxes[i] {
+			t.Errorf("Testing %s. Component %s: expected %v; got %v", name, ct.Jurisdiction, expectedComponentTaxes[i], ct.LineTax)
+		}
+		total += ct.LineTax
+	}
+
+	if tx.LineTax != expectedLineTax || total != tx.LineTax {
+		t.Errorf("Testing %s. Expected line tax %v; got %v with components adding up to %v", name, expectedLineTax, tx.LineTax, total)
+	}
+}
@@ -0,0 +1,257 @@
+package financial
+
+import "sort"
+
+// TaxDocument is a whole invoice (or credit note, order etc.) made up of TaxCalc lines.
+// Unlike a single TaxCalc, it can use the 'totals' rounding method, where tax is calculated once
+// on the subtotal for each tax rate, and then spread back across the lines so that they still add up.
+// It also produces the analysis of ex, tax and inc by rate that UK and EU VAT invoices must show.
+type TaxDocument struct {
+	RoundingMethod TaxRoundingMethod
+	RoundingMode   RoundingMode
+	Lines          TaxCalcs
+
+	Analysis                    []TaxRateAnalysis
+	TotalEx, TotalTax, TotalInc Cents
+}
+
+// TaxRateAnalysis is one row of the VAT analysis: the totals for a single tax rate, treatment and set of
+// components, so that zero rated and exempt lines are shown separately even though neither is charged tax,
+// and a combined rate such as GST and QST isn't mixed up with a single tax that happens to add up the same
+type TaxRateAnalysis struct {
+	TaxPercentage float64
+	Treatment     TaxTreatment
+	Components    TaxComponents
+	LineCount     int
+
+	Ex, Tax, Inc Cents
+}
+
+func NewTaxDocument(roundingMethod TaxRoundingMethod, lines TaxCalcs) TaxDocument {
+	return TaxDocument{
+		RoundingMethod: roundingMethod,
+		Lines:          lines,
+	}
+}
+
+// taxRateGroup is a tax rate, treatment and set of components that lines are analysed by
+type taxRateGroup struct {
+	TaxPercentage float64
+	Treatment     TaxTreatment
+	Components    string
+}
+
+// taxRateGroups returns the index of each line, grouped by tax rate, treatment and components,
+// in order of rate, then treatment and then components
+func (td *TaxDocument) taxRateGroups() (rates []taxRateGroup, groups map[taxRateGroup][]int) {
+	groups = map[taxRateGroup][]int{}
+
+	for i, line := range td.Lines {
+		rate := taxRateGroup{TaxPercentage: line.TaxPercentage, Treatment: line.Treatment, Components: line.Components.key()}
+		if _, ok := groups[rate]; !ok {
+			rates = append(rates, rate)
+		}
+		groups[rate] = append(groups[rate], i)
+	}
+
+	sort.Slice(rates, func(i, j int) bool {
+		if rates[i].TaxPercentage != rates[j].TaxPercentage {
+			return rates[i].TaxPercentage < rates[j].TaxPercentage
+		}
+		if rates[i].Treatment != rates[j].Treatment {
+			return rates[i].Treatment < rates[j].Treatment
+		}
+		return rates[i].Components < rates[j].Components
+	})
+	return
+}
+
+// totalsGroupKey is what the lines that share a calculation under the totals method have in common
+type totalsGroupKey struct {
+	TaxPercentage float64
+	Components    string
+	Negative      bool
+}
+
+// totalsGroups groups together the lines that share a single calculation under the totals method,
+// which are those with the same tax rate and components, in the order they first appear.
+// Lines that aren't charged tax are left out, as there is nothing to share.  Negative lines, such as
+// those of a credit note or a refund on an invoice, are grouped apart from the positive ones, so that
+// each line's share of the tax is in proportion to its own amount and has the same sign.
+func (td *TaxDocument) totalsGroups() (groups [][]int) {
+	keys := map[totalsGroupKey]int{}
+
+	for i, line := range td.Lines {
+		if !line.Treatment.ChargesTax() {
+			continue
+		}
+
+		key := totalsGroupKey{
+			TaxPercentage: line.TaxPercentage,
+			Components:    line.Components.key(),
+			Negative:      line.LineEx < 0 || line.LineInc < 0,
+		}
+		if j, ok := keys[key]; ok {
+			groups[j] = append(groups[j], i)
+			continue
+		}
+
+		keys[key] = len(groups)
+		groups = append(groups, []int{i})
+	}
+
+	return
+}
+
+// AddTax works out the tax on each line from its UnitEx and LineQty and then
+// totals up the document
+func (td *TaxDocument) AddTax() {
+	for i := range td.Lines {
+		td.Lines[i].RoundingMethod = td.RoundingMethod
+		td.Lines[i].RoundingMode = td.RoundingMode
+		td.Lines[i].AddTax()
+	}
+
+	if td.RoundingMethod == TaxRoundingMethodTotals {
+		for _, lineIndexes := range td.totalsGroups() {
+			td.addTaxTotalsMethod(lineIndexes)
+		}
+	}
+
+	td.calcTotals()
+}
+
+// RemoveTax works backwards from each line's LineInc and LineQty and then
+// totals up the document
+func (td *TaxDocument) RemoveTax() {
+	for i := range td.Lines {
+		td.Lines[i].RoundingMethod = td.RoundingMethod
+		td.Lines[i].RoundingMode = td.RoundingMode
+		td.Lines[i].RemoveTax()
+	}
+
+	if td.RoundingMethod == TaxRoundingMethodTotals {
+		for _, lineIndexes := range td.totalsGroups() {
+			td.removeTaxTotalsMethod(lineIndexes)
+		}
+	}
+
+	td.calcTotals()
+}
+
+// addTaxTotalsMethod calculates the tax on the ex subtotal for the group, and then
+// allocates it back across the lines in proportion to their ex amounts
+func (td *TaxDocument) addTaxTotalsMethod(lineIndexes []int) {
+	first := td.Lines[lineIndexes[0]]
+
+	var subtotalEx Cents
+	ratios := make([]int, len(lineIndexes))
+	for j, i := range lineIndexes {
+		subtotalEx = subtotalEx.SaturatingAdd(td.Lines[i].LineEx)
+		ratios[j] = allocationRatio(td.Lines[i].LineEx)
+	}
+
+	componentTaxes := []Cents{subtotalEx.ByPercentageRounded(first.TaxPercentage, td.RoundingMode)}
+	if len(first.Components) > 0 {
+		componentTaxes = first.Components.calcComponentTaxes(subtotalEx, td.RoundingMode, nil)
+	}
+
+	td.allocateTotalsTaxes(lineIndexes, ratios, componentTaxes)
+	for _, i := range lineIndexes {
+		td.Lines[i].LineInc = td.Lines[i].LineEx.SaturatingAdd(td.Lines[i].LineTax)
+	}
+}
+
+// removeTaxTotalsMethod takes the tax out of the inc subtotal for the group, and then
+// allocates it back across the lines in proportion to their inc amounts
+func (td *TaxDocument) removeTaxTotalsMethod(lineIndexes []int) {
+	first := td.Lines[lineIndexes[0]]
+
+	var subtotalInc Cents
+	ratios := make([]int, len(lineIndexes))
+	for j, i := range lineIndexes {
+		subtotalInc = subtotalInc.SaturatingAdd(td.Lines[i].LineInc)
+		ratios[j] = allocationRatio(td.Lines[i].LineInc)
+	}
+
+	subtotalTax := subtotalInc.SaturatingSub(subtotalInc.RemovePercentageRounded(first.TaxPercentage, td.RoundingMode))
+	componentTaxes := []Cents{subtotalTax}
+	if len(first.Components) > 0 {
+		componentTaxes = first.Components.splitComponentTaxes(subtotalTax)
+	}
+
+	td.allocateTotalsTaxes(lineIndexes, ratios, componentTaxes)
+	for _, i := range lineIndexes {
+		line := &td.Lines[i]
+		if line.LineQty == 0 {
+			continue
+		}
+
+		line.LineEx = line.LineInc.SaturatingSub(line.LineTax)
+		line.UnitEx = line.LineEx / Cents(line.LineQty)
+	}
+}
+
+// allocationRatio is the size of a line's amount, for sharing out the tax.  Every line in a group has
+// the same sign, and AllocateByRatios treats negative ratios as zero, so the sign is left to the tax.
+func allocationRatio(c Cents) int {
+	if c < 0 {
+		return -int(c)
+	}
+
+	return int(c)
+}
+
+// allocateTotalsTaxes spreads each of the group's component taxes (or just the one tax, if there
+// are no components) across the lines, and sets each line's tax to the sum of its shares
+func (td *TaxDocument) allocateTotalsTaxes(lineIndexes []int, ratios []int, componentTaxes []Cents) {
+	lineTaxes := make([][]Cents, len(lineIndexes))
+	for j := range lineIndexes {
+		lineTaxes[j] = make([]Cents, len(componentTaxes))
+	}
+
+	for c, tax := range componentTaxes {
+		for j, share := range tax.AllocateByRatios(ratios) {
+			lineTaxes[j][c] = share
+		}
+	}
+
+	for j, i := range lineIndexes {
+		line := &td.Lines[i]
+		line.LineTax = 0
+		for _, share := range lineTaxes[j] {
+			line.LineTax = line.LineTax.SaturatingAdd(share)
+		}
+
+		if len(line.Components) > 0 {
+			line.setComponentTaxes(divideByQty(lineTaxes[j], line.LineQty), lineTaxes[j])
+		}
+	}
+}
+
+// calcTotals builds the analysis by rate and the document totals from the lines
+func (td *TaxDocument) calcTotals() {
+	td.Analysis = nil
+	td.TotalEx, td.TotalTax, td.TotalInc = 0, 0, 0
+
+	rates, groups := td.taxRateGroups()
+	for _, rate := range rates {
+		analysis := TaxRateAnalysis{
+			TaxPercentage: rate.TaxPercentage,
+			Treatment:     rate.Treatment,
+			Components:    td.Lines[groups[rate][0]].Components,
+			LineCount:     len(groups[rate]),
+		}
+
+		for _, i := range groups[rate] {
+			analysis.Ex = analysis.Ex.SaturatingAdd(td.Lines[i].LineEx)
+			analysis.Tax = analysis.Tax.SaturatingAdd(td.Lines[i].LineTax)
+			analysis.Inc = analysis.Inc.SaturatingAdd(td.Lines[i].LineInc)
+		}
+
+		td.Analysis = append(td.Analysis, analysis)
+		td.TotalEx = td.TotalEx.SaturatingAdd(analysis.Ex)
+		td.TotalTax = td.TotalTax.SaturatingAdd(analysis.Tax)
+		td.TotalInc = td.TotalInc.SaturatingAdd(analysis.Inc)
+	}
+}
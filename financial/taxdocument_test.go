@@ -0,0 +1,250 @@
+package financial
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTaxDocumentAddTax(t *testing.T) {
+	threeLines := func() TaxCalcs {
+		return TaxCalcs{
+			{UnitEx: 333, LineQty: 1, TaxPercentage: 20},
+			{UnitEx: 333, LineQty: 1, TaxPercentage: 20},
+			{UnitEx: 333, LineQty: 1, TaxPercentage: 20},
+		}
+	}
+
+	mixedRates := func() TaxCalcs {
+		return TaxCalcs{
+			{UnitEx: 1000, LineQty: 2, TaxPercentage: 20},
+			{UnitEx: 250, LineQty: 1, TaxPercentage: 5},
+			{UnitEx: 499, LineQty: 3, TaxPercentage: 0},
+			{UnitEx: 333, LineQty: 1, TaxPercentage: 20},
+			{UnitEx: 999, LineQty: 0, TaxPercentage: 20},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		roundingMethod   TaxRoundingMethod
+		lines            TaxCalcs
+		expectedLineTax  []Cents
+		expectedAnalysis []TaxRateAnalysis
+	}{
+		{
+			name:            "no lines",
+			roundingMethod:  TaxRoundingMethodTotals,
+			expectedLineTax: []Cents{},
+		},
+		{
+			name:            "unit method rounds up each line",
+			roundingMethod:  TaxRoundingMethodUnit,
+			lines:           threeLines(),
+			expectedLineTax: []Cents{67, 67, 67},
+			expectedAnalysis: []TaxRateAnalysis{
+				{TaxPercentage: 20, LineCount: 3, Ex: 999, Tax: 201, Inc: 1200},
+			},
+		},
+		{
+			name:            "line method rounds up each line",
+			roundingMethod:  TaxRoundingMethodLine,
+			lines:           threeLines(),
+			expectedLineTax: []Cents{67, 67, 67},
+			expectedAnalysis: []TaxRateAnalysis{
+				{TaxPercentage: 20, LineCount: 3, Ex: 999, Tax: 201, Inc: 1200},
+			},
+		},
+		{
+			name:            "totals method rounds once",
+			roundingMethod:  TaxRoundingMethodTotals,
+			lines:           threeLines(),
+			expectedLineTax: []Cents{67, 67, 66},
+			expectedAnalysis: []TaxRateAnalysis{
+				{TaxPercentage: 20, LineCount: 3, Ex: 999, Tax: 200, Inc: 1199},
+			},
+		},
+		{
+			name:            "totals method, mixed rates",
+			roundingMethod:  TaxRoundingMethodTotals,
+			lines:           mixedRates(),
+			expectedLineTax: []Cents{400, 13, 0, 67, 0},
+			expectedAnalysis: []TaxRateAnalysis{
+				{TaxPercentage: 0, LineCount: 1, Ex: 1497, Tax: 0, Inc: 1497},
+				{TaxPercentage: 5, LineCount: 1, Ex: 250, Tax: 13, Inc: 263},
+				{TaxPercentage: 20, LineCount: 3, Ex: 2333, Tax: 467, Inc: 2800},
+			},
+		},
+		{
+			name:           "totals method, credit note",
+			roundingMethod: TaxRoundingMethodTotals,
+			lines: TaxCalcs{
+				{UnitEx: -1000, LineQty: 1, TaxPercentage: 20},
+				{UnitEx: -10, LineQty: 1, TaxPercentage: 20},
+			},
+			expectedLineTax: []Cents{-200, -2},
+			expectedAnalysis: []TaxRateAnalysis{
+				{TaxPercentage: 20, LineCount: 2, Ex: -1010, Tax: -202, Inc: -1212},
+			},
+		},
+		{
+			name:           "totals method, credit note rounds once",
+			roundingMethod: TaxRoundingMethodTotals,
+			lines: TaxCalcs{
+				{UnitEx: -333, LineQty: 1, TaxPercentage: 20},
+				{UnitEx: -333, LineQty: 1, TaxPercentage: 20},
+				{UnitEx: -333, LineQty: 1, TaxPercentage: 20},
+			},
+			expectedLineTax: []Cents{-67, -67, -66},
+			expectedAnalysis: []TaxRateAnalysis{
+				{TaxPercentage: 20, LineCount: 3, Ex: -999, Tax: -200, Inc: -1199},
+			},
+		},
+		{
+			name:           "totals method, mixed signs",
+			roundingMethod: TaxRoundingMethodTotals,
+			lines: TaxCalcs{
+				{UnitEx: 1000, LineQty: 1, TaxPercentage: 20},
+				{UnitEx: -200, LineQty: 1, TaxPercentage: 20},
+				{UnitEx: 333, LineQty: 1, TaxPercentage: 20},
+			},
+			expectedLineTax: []Cents{200, -40, 67},
+			expectedAnalysis: []TaxRateAnalysis{
+				{TaxPercentage: 20, LineCount: 3, Ex: 1133, Tax: 227, Inc: 1360},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		td := NewTaxDocument(test.roundingMethod, test.lines)
+		td.AddTax()
+
+		checkTaxDocument(t, test.name, td, test.expectedLineTax, test.expectedAnalysis)
+	}
+}
+
+func TestTaxDocumentRemoveTax(t *testing.T) {
+	lines := func() TaxCalcs {
+		return TaxCalcs{
+			{LineInc: 100, LineQty: 1, TaxPercentage: 20},
+			{LineInc: 100, LineQty: 1, TaxPercentage: 20},
+			{LineInc: 100, LineQty: 1, TaxPercentage: 20},
+		}
+	}
+
+	tests := []struct {
+		name             string
+		roundingMethod   TaxRoundingMethod
+		lines            TaxCalcs
+		expectedLineTax  []Cents
+		expectedAnalysis []TaxRateAnalysis
+	}{
+		{
+			name:           "totals method, credit note",
+			roundingMethod: TaxRoundingMethodTotals,
+			lines: TaxCalcs{
+				{LineInc: -100, LineQty: 1, TaxPercentage: 20},
+				{LineInc: -100, LineQty: 1, TaxPercentage: 20},
+				{LineInc: -100, LineQty: 1, TaxPercentage: 20},
+			},
+			expectedLineTax: []Cents{-17, -17, -16},
+			expectedAnalysis: []TaxRateAnalysis{
+				{TaxPercentage: 20, LineCount: 3, Ex: -250, Tax: -50, Inc: -300},
+			},
+		},
+		{
+			name:           "totals method, mixed signs",
+			roundingMethod: TaxRoundingMethodTotals,
+			lines: TaxCalcs{
+				{LineInc: 1200, LineQty: 1, TaxPercentage: 20},
+				{LineInc: -60, LineQty: 1, TaxPercentage: 20},
+			},
+			expectedLineTax: []Cents{200, -10},
+			expectedAnalysis: []TaxRateAnalysis{
+				{TaxPercentage: 20, LineCount: 2, Ex: 950, Tax: 190, Inc: 1140},
+			},
+		},
+		{
+			name:            "line method",
+			roundingMethod:  TaxRoundingMethodLine,
+			expectedLineTax: []Cents{17, 17, 17},
+			expectedAnalysis: []TaxRateAnalysis{
+				{TaxPercentage: 20, LineCount: 3, Ex: 249, Tax: 51, Inc: 300},
+			},
+		},
+		{
+			name:            "totals method",
+			roundingMethod:  TaxRoundingMethodTotals,
+			expectedLineTax: []Cents{17, 17, 16},
+			expectedAnalysis: []TaxRateAnalysis{
+				{TaxPercentage: 20, LineCount: 3, Ex: 250, Tax: 50, Inc: 300},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		if test.lines == nil {
+			test.lines = lines()
+		}
+
+		td := NewTaxDocument(test.roundingMethod, test.lines)
+		td.RemoveTax()
+
+		checkTaxDocument(t, test.name, td, test.expectedLineTax, test.expectedAnalysis)
+	}
+}
+
+func TestTaxDocumentTotalsSaturate(t *testing.T) {
+	td := NewTaxDocument(TaxRoundingMethodLine, TaxCalcs{
+		{UnitEx: maxCents, LineQty: 1, TaxPercentage: 0},
+		{UnitEx: maxCents, LineQty: 1, TaxPercentage: 0},
+		{UnitEx: 100, LineQty: 1, TaxPercentage: 20},
+	})
+	td.AddTax()
+
+	if td.TotalEx != maxCents || td.TotalInc != maxCents || td.TotalTax != 20 {
+		t.Errorf("Testing totals past the largest amount. Expected %v/%v/%v; got %v/%v/%v",
+			maxCents, 20, maxCents, td.TotalEx, td.TotalTax, td.TotalInc)
+	}
+
+	if len(td.Analysis) != 2 || td.Analysis[0].Ex != maxCents || td.Analysis[0].Inc != maxCents {
+		t.Errorf("Testing analysis past the largest amount. Got %v", td.Analysis)
+	}
+}
+
+func checkTaxDocument(t *testing.T, name string, td TaxDocument, expectedLineTax []Cents, expectedAnalysis []TaxRateAnalysis) {
+	t.Helper()
+
+	if len(td.Lines) != len(expectedLineTax) {
+		t.Fatalf("Testing %s. Expected %d lines; got %d", name, len(expectedLineTax), len(td.Lines))
+	}
+
+	var totalEx, totalTax, totalInc Cents
+	for i, line := range td.Lines {
+		if line.LineTax != expectedLineTax[i] {
+			t.Errorf("Testing %s. Line %d: expected tax %v; got %v", name, i, expectedLineTax[i], line.LineTax)
+		}
+
+		if line.LineEx+line.LineTax != line.LineInc {
+			t.Errorf("Testing %s. Line %d doesn't add up: %v", name, i, line)
+		}
+
+		totalEx += line.LineEx
+		totalTax += line.LineTax
+		totalInc += line.LineInc
+	}
+
+	if len(td.Analysis) != len(expectedAnalysis) {
+		t.Fatalf("Testing %s. Expected analysis %v; got %v", name, expectedAnalysis, td.Analysis)
+	}
+
+	for i := range td.Analysis {
+		if !reflect.DeepEqual(td.Analysis[i], expectedAnalysis[i]) {
+			t.Errorf("Testing %s. Expected analysis row %v; got %v", name, expectedAnalysis[i], td.Analysis[i])
+		}
+	}
+
+	if td.TotalEx != totalEx || td.TotalTax != totalTax || td.TotalInc != totalInc {
+		t.Errorf("Testing %s. Document totals %v/%v/%v don't match the lines %v/%v/%v",
+			name, td.TotalEx, td.TotalTax, td.TotalInc, totalEx, totalTax, totalInc)
+	}
+}
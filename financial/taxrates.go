@@ -0,0 +1,247 @@
+package financial
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dogpakk/lib/datetime"
+)
+
+var (
+	ErrTaxRateNotFound        = errors.New("tax rate not found")
+	ErrOverlappingTaxRates    = errors.New("tax rates overlap")
+	ErrUnsupportedTaxRateFile = errors.New("unsupported tax rate file version")
+)
+
+// TaxClass is the tax class of a product.  The four below are the usual ones, but a
+// table can use any others it needs.
+type TaxClass string
+
+const (
+	TaxClassStandard TaxClass = "standard"
+	TaxClassReduced  TaxClass = "reduced"
+	TaxClassZero     TaxClass = "zero"
+	TaxClassExempt   TaxClass = "exempt"
+)
+
+// TaxRate is the rate for a tax class in a country, or a region of it, between two dates.
+// Category narrows a rate down to certain goods or services within the class, e.g. the UK's
+// temporary rates for hospitality, which are otherwise standard rated.
+// From is inclusive and To exclusive, and a zero To means the rate still applies.
+type TaxRate struct {
+	Country     string
+	Region      string
+	Class       TaxClass
+	Category    string
+	Percentage  float64
+	From, To    time.Time
+	Description string
+}
+
+// appliesOn compares calendar days, so that an order placed just after midnight local time,
+// which is still the day before in UTC, gets the rate for the day it was placed
+func (tr TaxRate) appliesOn(date time.Time) bool {
+	date = datetime.Date(date)
+	return !date.Before(tr.From) && (tr.To.IsZero() || date.Before(tr.To))
+}
+
+func (tr TaxRate) key() string {
+	return strings.Join([]string{tr.Country, tr.Region, string(tr.Class), tr.Category}, "|")
+}
+
+// TaxRateQuery is what to look up.  Region and Category are optional, and if there is no rate
+// specifically for them, the rate for the whole country, or the whole class, is used instead.
+type TaxRateQuery struct {
+	Country  string
+	Region   string
+	Class    TaxClass
+	Category string
+	Date     time.Time
+}
+
+// TaxRateTable holds the rates for any number of countries, including the history of changes,
+// so that an order can be charged at the rate on the day it was placed
+type TaxRateTable struct {
+	// Version is the version of the rates, as given in the file, so that it can be recorded alongside an order
+	Version string
+	Rates   []TaxRate
+}
+
+// The tax rate file is JSON, with dates as YYYY-MM-DD:
+//
+//  {
+//    "schema": 1,
+//    "version": "2021-10-01",
+//    "rates": [
+//      {"country": "GB", "class": "standard", "percentage": 20, "from": "2011-01-04"},
+//      {"country": "GB", "class": "standard", "category": "hospitality", "percentage": 5, "from": "2020-07-15", "to": "2021-10-01"}
+//    ]
+//  }
+
+const taxRateFileSchema = 1
+
+type taxRateFile struct {
+	Schema  int               `json:"schema"`
+	Version string            `json:"version"`
+	Rates   []taxRateFileRate `json:"rates"`
+}
+
+type taxRateFileRate struct {
+	Country     string   `json:"country"`
+	Region      string   `json:"region"`
+	Class       TaxClass `json:"class"`
+	Category    string   `json:"category"`
+	Percentage  float64  `json:"percentage"`
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Description string   `json:"description"`
+}
+
+func parseTaxRateDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	return time.Parse(rateDateFormat, s)
+}
+
+// LoadTaxRateTable reads a tax rate file, and checks that none of the rates overlap
+func LoadTaxRateTable(r io.Reader) (*TaxRateTable, error) {
+	var f taxRateFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return nil, err
+	}
+
+	if f.Schema != taxRateFileSchema {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedTaxRateFile, f.Schema)
+	}
+
+	t := &TaxRateTable{Version: f.Version}
+	for i, fr := range f.Rates {
+		from, err := parseTaxRateDate(fr.From)
+		if err != nil {
+			return nil, fmt.Errorf("rate %d: %w", i, err)
+		}
+
+		to, err := parseTaxRateDate(fr.To)
+		if err != nil {
+			return nil, fmt.Errorf("rate %d: %w", i, err)
+		}
+
+		t.Rates = append(t.Rates, TaxRate{
+			Country:     strings.ToUpper(fr.Country),
+			Region:      strings.ToUpper(fr.Region),
+			Class:       fr.Class,
+			Category:    fr.Category,
+			Percentage:  fr.Percentage,
+			From:        from,
+			To:          to,
+			Description: fr.Description,
+		})
+	}
+
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func LoadTaxRateTableFile(path string) (*TaxRateTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return LoadTaxRateTable(f)
+}
+
+// Validate checks that no two rates for the same country, region, class and category apply on the same day
+func (t *TaxRateTable) Validate() error {
+	byKey := map[string][]TaxRate{}
+	for _, tr := range t.Rates {
+		if !tr.To.IsZero() && !tr.To.After(tr.From) {
+			return fmt.Errorf("%w: %s ends before it starts", ErrOverlappingTaxRates, tr.key())
+		}
+		byKey[tr.key()] = append(byKey[tr.key()], tr)
+	}
+
+	for key, rates := range byKey {
+		sort.Slice(rates, func(i, j int) bool { return rates[i].From.Before(rates[j].From) })
+
+		for i := 1; i < len(rates); i++ {
+			prev := rates[i-1]
+			if prev.To.IsZero() || prev.To.After(rates[i].From) {
+				return fmt.Errorf("%w: %s from %s", ErrOverlappingTaxRates, key, rates[i].From.Format(rateDateFormat))
+			}
+		}
+	}
+
+	return nil
+}
+
+// find is the rate for an exact country, region, class and category on the date
+func (t *TaxRateTable) find(country, region string, class TaxClass, category string, date time.Time) (TaxRate, bool) {
+	for _, tr := range t.Rates {
+		if tr.Country == country && tr.Region == region && tr.Class == class && tr.Category == category && tr.appliesOn(date) {
+			return tr, true
+		}
+	}
+
+	return TaxRate{}, false
+}
+
+// Lookup finds the rate that applies, trying the most specific first: the region and category,
+// then the region for the whole class, then the country and category, and then the country for the whole class
+func (t *TaxRateTable) Lookup(q TaxRateQuery) (TaxRate, error) {
+	country, region := strings.ToUpper(q.Country), strings.ToUpper(q.Region)
+
+	type attempt struct {
+		region, category string
+	}
+
+	attempts := []attempt{{region, q.Category}, {region, ""}, {"", q.Category}, {"", ""}}
+	for _, a := range attempts {
+		if tr, ok := t.find(country, a.region, q.Class, a.category, q.Date); ok {
+			return tr, nil
+		}
+	}
+
+	return TaxRate{}, fmt.Errorf("%w: %s %s %s on %s", ErrTaxRateNotFound, country, region, q.Class, q.Date.Format(rateDateFormat))
+}
+
+// Percentage is the percentage from Lookup, ready for a TaxCalc
+func (t *TaxRateTable) Percentage(q TaxRateQuery) (float64, error) {
+	tr, err := t.Lookup(q)
+	return tr.Percentage, err
+}
+
+// TaxCalc looks up the rate and sets up a TaxCalc with it, ready for AddTax.  Zero and exempt
+// classes get the matching Treatment, so that they are reported as such and not just as 0%.
+func (t *TaxRateTable) TaxCalc(q TaxRateQuery, unitEx Cents, qty int) (TaxCalc, error) {
+	tr, err := t.Lookup(q)
+	if err != nil {
+		return TaxCalc{}, err
+	}
+
+	return TaxCalc{UnitEx: unitEx, LineQty: qty, TaxPercentage: tr.Percentage, Treatment: tr.Class.treatment()}, nil
+}
+
+// treatment is the TaxTreatment for lines in the class
+func (tc TaxClass) treatment() TaxTreatment {
+	switch tc {
+	case TaxClassZero:
+		return TaxTreatmentZeroRated
+	case TaxClassExempt:
+		return TaxTreatmentExempt
+	}
+
+	return TaxTreatmentStandard
+}
@@ -0,0 +1,137 @@
+package financial
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTaxRateTableLookup(t *testing.T) {
+	table, err := LoadTaxRateTableFile("testdata/taxrates.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if table.Version != "2021-10-01" {
+		t.Errorf("Testing version. Expected %v; got %v", "2021-10-01", table.Version)
+	}
+
+	tests := []struct {
+		name     string
+		q        TaxRateQuery
+		expected float64
+	}{
+		{"GB standard now", TaxRateQuery{Country: "GB", Class: TaxClassStandard, Date: rateDate("2021-06-01")}, 20},
+		{"lower case country", TaxRateQuery{Country: "gb", Class: TaxClassStandard, Date: rateDate("2021-06-01")}, 20},
+		{"GB 2009 reduction", TaxRateQuery{Country: "GB", Class: TaxClassStandard, Date: rateDate("2009-06-01")}, 15},
+		{"GB first day of 20%", TaxRateQuery{Country: "GB", Class: TaxClassStandard, Date: rateDate("2011-01-04")}, 20},
+		{"GB last day of 17.5%", TaxRateQuery{Country: "GB", Class: TaxClassStandard, Date: rateDate("2011-01-03")}, 17.5},
+		{"GB hospitality before the reduction", TaxRateQuery{Country: "GB", Class: TaxClassStandard, Category: "hospitality", Date: rateDate("2020-07-14")}, 20},
+		{"GB hospitality at 5%", TaxRateQuery{Country: "GB", Class: TaxClassStandard, Category: "hospitality", Date: rateDate("2020-07-15")}, 5},
+		{"GB hospitality at 12.5%", TaxRateQuery{Country: "GB", Class: TaxClassStandard, Category: "hospitality", Date: rateDate("2021-10-01")}, 12.5},
+		{"GB hospitality at 12.5% from 00:30 BST", TaxRateQuery{Country: "GB", Class: TaxClassStandard, Category: "hospitality", Date: time.Date(2021, 10, 1, 0, 30, 0, 0, time.FixedZone("BST", 60*60))}, 12.5},
+		{"GB hospitality at 5% until 23:59 BST", TaxRateQuery{Country: "GB", Class: TaxClassStandard, Category: "hospitality", Date: time.Date(2021, 9, 30, 23, 59, 0, 0, time.FixedZone("BST", 60*60))}, 5},
+		{"GB hospitality back to standard", TaxRateQuery{Country: "GB", Class: TaxClassStandard, Category: "hospitality", Date: rateDate("2022-04-01")}, 20},
+		{"GB other category uses standard", TaxRateQuery{Country: "GB", Class: TaxClassStandard, Category: "books", Date: rateDate("2020-08-01")}, 20},
+		{"GB reduced", TaxRateQuery{Country: "GB", Class: TaxClassReduced, Date: rateDate("2020-08-01")}, 5},
+		{"GB zero", TaxRateQuery{Country: "GB", Class: TaxClassZero, Date: rateDate("2020-08-01")}, 0},
+		{"IE COVID reduction", TaxRateQuery{Country: "IE", Class: TaxClassStandard, Date: rateDate("2020-12-25")}, 21},
+		{"DE reduced in 2020", TaxRateQuery{Country: "DE", Class: TaxClassReduced, Date: rateDate("2020-12-31")}, 5},
+		{"DE reduced in 2021", TaxRateQuery{Country: "DE", Class: TaxClassReduced, Date: rateDate("2021-01-01")}, 7},
+		{"PT region", TaxRateQuery{Country: "PT", Region: "PT-30", Class: TaxClassStandard, Date: rateDate("2020-01-01")}, 22},
+		{"PT region before it had its own rate", TaxRateQuery{Country: "PT", Region: "PT-30", Class: TaxClassStandard, Date: rateDate("2012-03-31")}, 23},
+		{"PT other region", TaxRateQuery{Country: "PT", Region: "PT-20", Class: TaxClassStandard, Date: rateDate("2020-01-01")}, 23},
+	}
+
+	for _, test := range tests {
+		res, err := table.Percentage(test.q)
+		if err != nil {
+			t.Errorf("Testing %s. Unexpected error: %s", test.name, err)
+			continue
+		}
+
+		if res != test.expected {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+	}
+
+	exempt, err := table.Lookup(TaxRateQuery{Country: "GB", Class: TaxClassExempt, Date: rateDate("2020-08-01")})
+	if err != nil || exempt.Class != TaxClassExempt || exempt.Percentage != 0 {
+		t.Errorf("Testing exempt. Got %v (%v)", exempt, err)
+	}
+
+	for _, q := range []TaxRateQuery{
+		{Country: "FR", Class: TaxClassStandard, Date: rateDate("2020-01-01")},
+		{Country: "IE", Class: TaxClassStandard, Date: rateDate("2000-01-01")},
+		{Country: "IE", Class: TaxClassReduced, Date: rateDate("2020-01-01")},
+	} {
+		if _, err := table.Lookup(q); !errors.Is(err, ErrTaxRateNotFound) {
+			t.Errorf("Testing %v. Expected ErrTaxRateNotFound; got %v", q, err)
+		}
+	}
+
+	tx, err := table.TaxCalc(TaxRateQuery{Country: "GB", Class: TaxClassStandard, Category: "hospitality", Date: rateDate("2020-08-01")}, 1000, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.AddTax()
+	if tx.LineTax != 100 || tx.LineInc != 2100 {
+		t.Errorf("Testing TaxCalc. Expected tax %v and inc %v; got %v and %v", 100, 2100, tx.LineTax, tx.LineInc)
+	}
+
+	for class, expected := range map[TaxClass]TaxTreatment{
+		TaxClassStandard: TaxTreatmentStandard,
+		TaxClassReduced:  TaxTreatmentStandard,
+		TaxClassZero:     TaxTreatmentZeroRated,
+		TaxClassExempt:   TaxTreatmentExempt,
+	} {
+		tx, err := table.TaxCalc(TaxRateQuery{Country: "GB", Class: class, Date: rateDate("2020-08-01")}, 1000, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if tx.Treatment != expected {
+			t.Errorf("Testing TaxCalc treatment for %s. Expected %v; got %v", class, expected, tx.Treatment)
+		}
+	}
+}
+
+func TestLoadTaxRateTableErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected error
+	}{
+		{"wrong schema", `{"schema": 2, "rates": []}`, ErrUnsupportedTaxRateFile},
+		{"overlapping", `{"schema": 1, "rates": [
+			{"country": "GB", "class": "standard", "percentage": 17.5, "from": "2008-01-01", "to": "2010-01-01"},
+			{"country": "GB", "class": "standard", "percentage": 20, "from": "2009-01-01"}
+		]}`, ErrOverlappingTaxRates},
+		{"two open ended", `{"schema": 1, "rates": [
+			{"country": "GB", "class": "standard", "percentage": 17.5, "from": "2008-01-01"},
+			{"country": "GB", "class": "standard", "percentage": 20, "from": "2011-01-04"}
+		]}`, ErrOverlappingTaxRates},
+		{"ends before it starts", `{"schema": 1, "rates": [
+			{"country": "GB", "class": "standard", "percentage": 20, "from": "2011-01-04", "to": "2011-01-01"}
+		]}`, ErrOverlappingTaxRates},
+	}
+
+	for _, test := range tests {
+		if _, err := LoadTaxRateTable(strings.NewReader(test.json)); !errors.Is(err, test.expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, err)
+		}
+	}
+
+	if _, err := LoadTaxRateTable(strings.NewReader(`{"schema": 1, "rates": [{"country": "GB", "from": "01/01/2020"}]}`)); err == nil {
+		t.Errorf("Testing bad date. Expected an error but didn't get one")
+	}
+
+	// Rates for different categories or regions don't overlap each other
+	if _, err := LoadTaxRateTable(strings.NewReader(`{"schema": 1, "rates": [
+		{"country": "GB", "class": "standard", "percentage": 20, "from": "2011-01-04"},
+		{"country": "GB", "class": "standard", "category": "hospitality", "percentage": 5, "from": "2020-07-15"}
+	]}`)); err != nil {
+		t.Errorf("Testing categories. Unexpected error: %s", err)
+	}
+}
@@ -0,0 +1,226 @@
+package financial
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	ErrInvalidTender   = errors.New("tender amount must be positive")
+	ErrOverTendered    = errors.New("only cash can be tendered over the amount due")
+	ErrCheckoutSettled = errors.New("checkout is already settled")
+)
+
+// CashRounding is how a cash payment is rounded in countries that no longer use their smallest coins.
+// Only the amount paid in cash is rounded; card and other payments are always for the exact amount.
+type CashRounding struct {
+	// Increment is the smallest amount that can be paid in cash, e.g. 5 for 0.05.  0 or 1 means no rounding.
+	Increment Cents
+	// RoundingMode is which way amounts between two increments go.  The default of half up
+	// rounds to the nearest, which is the rule everywhere in the table below.
+	RoundingMode RoundingMode
+}
+
+// CashRoundingTable is the cash rounding in each country that has it, by ISO 3166 code
+var CashRoundingTable = map[string]CashRounding{
+	"AU": {Increment: 5},
+	"BE": {Increment: 5},
+	"CA": {Increment: 5},
+	"CH": {Increment: 5},
+	"DK": {Increment: 50},
+	"FI": {Increment: 5},
+	"NL": {Increment: 5},
+	"NO": {Increment: 100},
+	"NZ": {Increment: 10},
+	"SE": {Increment: 100},
+}
+
+// CashRoundingFor is the cash rounding for the country, or no rounding if it isn't in the table
+func CashRoundingFor(country string) CashRounding {
+	return CashRoundingTable[strings.ToUpper(country)]
+}
+
+// Round rounds the amount to what can be paid in cash
+func (cr CashRounding) Round(c Cents) Cents {
+	if cr.Increment <= 1 {
+		return c
+	}
+
+	steps := roundRat(new(big.Rat).SetFrac(big.NewInt(int64(c)), big.NewInt(int64(cr.Increment))), cr.RoundingMode)
+	return steps.SaturatingMulQty(int(cr.Increment))
+}
+
+type TenderType uint
+
+const (
+	TenderCash TenderType = iota
+	TenderCard
+	TenderVoucher
+	TenderOther
+)
+
+func (tt TenderType) String() string {
+	switch tt {
+	case TenderCash:
+		return "cash"
+	case TenderCard:
+		return "card"
+	case TenderVoucher:
+		return "voucher"
+	case TenderOther:
+		return "other"
+	}
+
+	return "unknown"
+}
+
+// Tender is a single payment towards a checkout
+type Tender struct {
+	Type      TenderType
+	Amount    Cents
+	Reference string
+}
+
+// Checkout tracks the tenders paid against the total of a sale.  When cash settles the balance,
+// the balance is rounded to what can be paid in cash, and the difference is kept as the rounding
+// adjustment, so that the payments still add up to the sale.
+type Checkout struct {
+	Total        Cents
+	CashRounding CashRounding
+	Tenders      []Tender
+
+	// Rounding is the cash rounding adjustment, positive if the customer paid more than the total
+	Rounding Cents
+	// Change is what was given back in cash
+	Change Cents
+}
+
+// NewCheckout starts a checkout for the inc total of the lines, which should already have had their tax calculated
+func NewCheckout(lines TaxCalcs, cashRounding CashRounding) *Checkout {
+	co := &Checkout{CashRounding: cashRounding}
+	for _, line := range lines {
+		co.Total = co.Total.SaturatingAdd(line.LineInc)
+	}
+
+	return co
+}
+
+// Due is the total plus any cash rounding
+func (co *Checkout) Due() Cents {
+	return co.Total + co.Rounding
+}
+
+// Tendered is everything paid, before any change
+func (co *Checkout) Tendered() Cents {
+	var res Cents
+	for _, t := range co.Tenders {
+		res = res.SaturatingAdd(t.Amount)
+	}
+
+	return res
+}
+
+// Remaining is the balance still to pay
+func (co *Checkout) Remaining() Cents {
+	res := co.Due() - (co.Tendered() - co.Change)
+	if res < 0 {
+		return 0
+	}
+
+	return res
+}
+
+// CashDue is what the customer would pay to settle the balance in cash
+func (co *Checkout) CashDue() Cents {
+	return co.CashRounding.Round(co.Remaining())
+}
+
+func (co *Checkout) IsSettled() bool {
+	return co.Remaining() == 0
+}
+
+// AddTender records a payment.  Cash can be more than the balance, in which case the change is
+// worked out, but anything else has to be for the balance or less.
+func (co *Checkout) AddTender(t Tender) error {
+	if t.Amount <= 0 {
+		return fmt.Errorf("%w: %v", ErrInvalidTender, t.Amount)
+	}
+
+	if co.IsSettled() {
+		return ErrCheckoutSettled
+	}
+
+	remaining := co.Remaining()
+	if t.Type != TenderCash {
+		if t.Amount > remaining {
+			return fmt.Errorf("%w: %s of %s with %s remaining", ErrOverTendered, t.Type, t.Amount.FormatAsPrice(), remaining.FormatAsPrice())
+		}
+
+		co.Tenders = append(co.Tenders, t)
+		return nil
+	}
+
+	// Cash that settles the balance settles the rounded balance, and a partial cash payment
+	// leaves the exact balance for whatever pays the rest
+	cashDue := co.CashRounding.Round(remaining)
+	co.Tenders = append(co.Tenders, t)
+	if t.Amount >= cashDue {
+		co.Rounding += cashDue - remaining
+		co.Change = t.Amount - cashDue
+	}
+
+	return nil
+}
+
+// RoundingLine is the rounding adjustment as a line of its own, to go alongside the sale's lines.
+// Rounding isn't a supply, so it is outside the scope of tax.
+func (co *Checkout) RoundingLine() (TaxCalc, bool) {
+	if co.Rounding == 0 {
+		return TaxCalc{}, false
+	}
+
+	return TaxCalc{
+		LineQty:   1,
+		Treatment: TaxTreatmentOutOfScope,
+		UnitEx:    co.Rounding,
+		LineEx:    co.Rounding,
+		LineInc:   co.Rounding,
+	}, true
+}
+
+type CheckoutEntryType uint
+
+const (
+	CheckoutEntrySale CheckoutEntryType = iota
+	CheckoutEntryRounding
+	CheckoutEntryTender
+	CheckoutEntryChange
+)
+
+// CheckoutEntry is one line of the record of a checkout.  Amounts owed by the customer are
+// positive and payments negative, so the entries of a settled checkout add up to zero.
+type CheckoutEntry struct {
+	Type   CheckoutEntryType
+	Tender TenderType
+	Amount Cents
+}
+
+// Entries are the sale, the rounding adjustment if there was one, each tender, and the change if any was given
+func (co *Checkout) Entries() []CheckoutEntry {
+	res := []CheckoutEntry{{Type: CheckoutEntrySale, Amount: co.Total}}
+	if co.Rounding != 0 {
+		res = append(res, CheckoutEntry{Type: CheckoutEntryRounding, Amount: co.Rounding})
+	}
+
+	for _, t := range co.Tenders {
+		res = append(res, CheckoutEntry{Type: CheckoutEntryTender, Tender: t.Type, Amount: -t.Amount})
+	}
+
+	if co.Change != 0 {
+		res = append(res, CheckoutEntry{Type: CheckoutEntryChange, Tender: TenderCash, Amount: co.Change})
+	}
+
+	return res
+}
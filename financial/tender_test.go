@@ -0,0 +1,167 @@
+package financial
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCashRounding(t *testing.T) {
+	tests := []struct {
+		country  string
+		amount   Cents
+		expected Cents
+	}{
+		{"CH", 1002, 1000},
+		{"CH", 1003, 1005},
+		{"CH", 1007, 1005},
+		{"CH", 1008, 1010},
+		{"ch", 1025, 1025},
+		{"CH", -1003, -1005},
+		{"CA", 1099, 1100},
+		{"AU", 1001, 1000},
+		{"NZ", 1005, 1010},
+		{"NZ", 1004, 1000},
+		{"DK", 1024, 1000},
+		{"DK", 1025, 1050},
+		{"SE", 1050, 1100},
+		{"GB", 1003, 1003},
+		{"", 1003, 1003},
+	}
+
+	for _, test := range tests {
+		got := CashRoundingFor(test.country).Round(test.amount)
+		if got != test.expected {
+			t.Errorf("Testing %s %v. Expected %v; got %v", test.country, test.amount, test.expected, got)
+		}
+	}
+
+	if got := (CashRounding{Increment: 5, RoundingMode: RoundFloor}).Round(1009); got != 1005 {
+		t.Errorf("Testing floor rounding. Expected %v; got %v", 1005, got)
+	}
+}
+
+func checkoutEntriesTotal(co *Checkout) Cents {
+	var res Cents
+	for _, e := range co.Entries() {
+		res += e.Amount
+	}
+
+	return res
+}
+
+func TestCheckout(t *testing.T) {
+	lines := TaxCalcs{{LineInc: 1001}, {LineInc: 2001}}
+
+	tests := []struct {
+		name             string
+		country          string
+		tenders          []Tender
+		expectedRounding Cents
+		expectedChange   Cents
+		expectedEntries  int
+	}{
+		{
+			name:             "cash with change",
+			country:          "CH",
+			tenders:          []Tender{{Type: TenderCash, Amount: 5000}},
+			expectedRounding: -2,
+			expectedChange:   2000,
+			expectedEntries:  4,
+		},
+		{
+			name:             "card then cash for the rounded balance",
+			country:          "CH",
+			tenders:          []Tender{{Type: TenderCard, Amount: 2000}, {Type: TenderCash, Amount: 1000}},
+			expectedRounding: -2,
+			expectedEntries:  4,
+		},
+		{
+			name:            "part cash then card for the exact balance",
+			country:         "CH",
+			tenders:         []Tender{{Type: TenderCash, Amount: 1000}, {Type: TenderCard, Amount: 2002}},
+			expectedEntries: 3,
+		},
+		{
+			name:             "cash rounded up",
+			country:          "CA",
+			tenders:          []Tender{{Type: TenderCard, Amount: 2000}, {Type: TenderVoucher, Amount: 999}, {Type: TenderCash, Amount: 500}},
+			expectedRounding: 2,
+			expectedChange:   495,
+			expectedEntries:  6,
+		},
+		{
+			name:            "no cash rounding",
+			country:         "GB",
+			tenders:         []Tender{{Type: TenderCash, Amount: 5000}},
+			expectedChange:  1998,
+			expectedEntries: 3,
+		},
+	}
+
+	for _, test := range tests {
+		co := NewCheckout(lines, CashRoundingFor(test.country))
+		for _, tender := range test.tenders {
+			if err := co.AddTender(tender); err != nil {
+				t.Errorf("Testing %s. Unexpected error %v", test.name, err)
+			}
+		}
+
+		if !co.IsSettled() {
+			t.Errorf("Testing %s. Expected to be settled; %v remaining", test.name, co.Remaining())
+		}
+
+		if co.Rounding != test.expectedRounding || co.Change != test.expectedChange {
+			t.Errorf("Testing %s. Expected %v rounding and %v change; got %v and %v", test.name,
+				test.expectedRounding, test.expectedChange, co.Rounding, co.Change)
+		}
+
+		if len(co.Entries()) != test.expectedEntries || checkoutEntriesTotal(co) != 0 {
+			t.Errorf("Testing %s entries. Expected %v adding up to 0; got %v", test.name, test.expectedEntries, co.Entries())
+		}
+
+		line, ok := co.RoundingLine()
+		if ok != (test.expectedRounding != 0) || line.LineInc != test.expectedRounding {
+			t.Errorf("Testing %s rounding line. Expected %v; got %v", test.name, test.expectedRounding, line.LineInc)
+		}
+
+		if ok && line.Treatment != TaxTreatmentOutOfScope {
+			t.Errorf("Testing %s rounding line treatment. Expected %v; got %v", test.name, TaxTreatmentOutOfScope, line.Treatment)
+		}
+	}
+}
+
+func TestCheckoutBalance(t *testing.T) {
+	co := NewCheckout(TaxCalcs{{LineInc: 3003}}, CashRoundingFor("CH"))
+
+	if co.Remaining() != 3003 || co.CashDue() != 3005 {
+		t.Errorf("Testing balance. Expected 3003 remaining and 3005 in cash; got %v and %v", co.Remaining(), co.CashDue())
+	}
+
+	if err := co.AddTender(Tender{Type: TenderCard, Amount: 3004}); !errors.Is(err, ErrOverTendered) {
+		t.Errorf("Testing card over the balance. Expected %v; got %v", ErrOverTendered, err)
+	}
+
+	if err := co.AddTender(Tender{Type: TenderCash}); !errors.Is(err, ErrInvalidTender) {
+		t.Errorf("Testing zero tender. Expected %v; got %v", ErrInvalidTender, err)
+	}
+
+	if err := co.AddTender(Tender{Type: TenderCard, Amount: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	if co.Remaining() != 2003 || co.CashDue() != 2005 {
+		t.Errorf("Testing part paid balance. Expected 2003 remaining and 2005 in cash; got %v and %v", co.Remaining(), co.CashDue())
+	}
+
+	if err := co.AddTender(Tender{Type: TenderCash, Amount: 2005}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := co.AddTender(Tender{Type: TenderCash, Amount: 5}); !errors.Is(err, ErrCheckoutSettled) {
+		t.Errorf("Testing tender when settled. Expected %v; got %v", ErrCheckoutSettled, err)
+	}
+
+	if co.Due() != 3005 || co.Tendered() != 3005 {
+		t.Errorf("Testing due and tendered. Expected 3005 and 3005; got %v and %v", co.Due(), co.Tendered())
+	}
+}
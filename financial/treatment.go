@@ -0,0 +1,173 @@
+package financial
+
+// TaxTreatment is how tax applies to a line, over and above its rate.  Zero rated, exempt and
+// out of scope lines are all charged no tax, but they are reported differently, and a reverse
+// charge line is charged no tax by the supplier because the customer accounts for it instead.
+type TaxTreatment uint
+
+const (
+	// TaxTreatmentStandard charges tax at the TaxPercentage, whatever it is, so it covers reduced rates too
+	TaxTreatmentStandard TaxTreatment = iota
+	// TaxTreatmentZeroRated is a taxable supply at 0%, so it still counts towards taxable turnover
+	TaxTreatmentZeroRated
+	// TaxTreatmentExempt is a supply that is exempt from tax, such as insurance or some financial services
+	TaxTreatmentExempt
+	// TaxTreatmentReverseCharge means the customer accounts for the tax at the TaxPercentage, e.g. B2B services
+	// across borders.  No tax is charged, but the TaxPercentage is kept for the customer's side of the calculation.
+	TaxTreatmentReverseCharge
+	// TaxTreatmentOutOfScope is outside the scope of tax altogether, e.g. wages, or sales made outside the country
+	TaxTreatmentOutOfScope
+)
+
+// ChargesTax is whether tax is actually added to the line
+func (tt TaxTreatment) ChargesTax() bool {
+	return tt == TaxTreatmentStandard
+}
+
+func (tt TaxTreatment) String() string {
+	switch tt {
+	case TaxTreatmentStandard:
+		return "standard"
+	case TaxTreatmentZeroRated:
+		return "zero rated"
+	case TaxTreatmentExempt:
+		return "exempt"
+	case TaxTreatmentReverseCharge:
+		return "reverse charge"
+	case TaxTreatmentOutOfScope:
+		return "out of scope"
+	}
+
+	return "unknown"
+}
+
+// InvoiceLabel is the wording an invoice must show against lines with this treatment.
+// Standard lines need no label, as their rate is shown.
+func (tt TaxTreatment) InvoiceLabel() string {
+	switch tt {
+	case TaxTreatmentZeroRated:
+		return "Zero rated"
+	case TaxTreatmentExempt:
+		return "Exempt"
+	case TaxTreatmentReverseCharge:
+		return "Reverse charge: customer to account for VAT"
+	case TaxTreatmentOutOfScope:
+		return "Outside the scope of VAT"
+	}
+
+	return ""
+}
+
+// addNoTax is AddTax for lines that aren't charged tax
+func (tx *TaxCalc) addNoTax(oc *overflowCheck) {
+	if tx.LineQty == 0 {
+		tx.blank()
+	} else {
+		tx.LineEx = oc.mulQty(tx.UnitEx, tx.LineQty)
+		tx.LineTax = 0
+		tx.LineInc = tx.LineEx
+	}
+
+	if len(tx.Components) > 0 {
+		tx.blankComponentTaxes()
+	}
+}
+
+// removeNoTax is RemoveTax for lines that aren't charged tax
+func (tx *TaxCalc) removeNoTax() {
+	if tx.LineQty == 0 {
+		tx.blank()
+	} else {
+		tx.LineEx = tx.LineInc
+		tx.LineTax = 0
+		tx.UnitEx = tx.LineEx / Cents(tx.LineQty)
+	}
+
+	if len(tx.Components) > 0 {
+		tx.blankComponentTaxes()
+	}
+}
+
+// ReverseChargeTax is the tax the customer has to account for on a reverse charge line,
+// at the line's TaxPercentage.  It is zero for any other treatment.
+func (tx TaxCalc) ReverseChargeTax() Cents {
+	if tx.Treatment != TaxTreatmentReverseCharge {
+		return 0
+	}
+
+	pc := tx.TaxPercentage
+	if len(tx.Components) > 0 {
+		pc = tx.Components.EffectivePercentage()
+	}
+
+	return tx.LineEx.ByPercentageRounded(pc, tx.RoundingMode)
+}
+
+// VATReturnBoxes are the nine boxes of a UK VAT return, in pence, named as in the
+// Making Tax Digital API
+type VATReturnBoxes struct {
+	VATDueSales                  Cents // Box 1
+	VATDueAcquisitions           Cents // Box 2
+	TotalVATDue                  Cents // Box 3
+	VATReclaimedCurrPeriod       Cents // Box 4
+	NetVATDue                    Cents // Box 5
+	TotalValueSalesExVAT         Cents // Box 6
+	TotalValuePurchasesExVAT     Cents // Box 7
+	TotalValueGoodsSuppliedExVAT Cents // Box 8
+	TotalAcquisitionsExVAT       Cents // Box 9
+}
+
+// AddSale puts a sales line into the boxes according to its treatment.
+// Zero rated, exempt and reverse charge sales all count towards the total sales, but out of scope sales don't.
+func (b *VATReturnBoxes) AddSale(tx TaxCalc) {
+	switch tx.Treatment {
+	case TaxTreatmentStandard:
+		b.VATDueSales = b.VATDueSales.SaturatingAdd(tx.LineTax)
+		b.TotalValueSalesExVAT = b.TotalValueSalesExVAT.SaturatingAdd(tx.LineEx)
+	case TaxTreatmentZeroRated, TaxTreatmentExempt, TaxTreatmentReverseCharge:
+		b.TotalValueSalesExVAT = b.TotalValueSalesExVAT.SaturatingAdd(tx.LineEx)
+	}
+
+	b.calcTotals()
+}
+
+// AddPurchase puts a purchase line into the boxes according to its treatment.  For a reverse
+// charge purchase, we account for the tax as if we had charged it to ourselves: it is due in box 1
+// and reclaimed in box 4, and the value goes in both box 6 and box 7.
+func (b *VATReturnBoxes) AddPurchase(tx TaxCalc) {
+	switch tx.Treatment {
+	case TaxTreatmentStandard:
+		b.VATReclaimedCurrPeriod = b.VATReclaimedCurrPeriod.SaturatingAdd(tx.LineTax)
+		b.TotalValuePurchasesExVAT = b.TotalValuePurchasesExVAT.SaturatingAdd(tx.LineEx)
+	case TaxTreatmentZeroRated, TaxTreatmentExempt:
+		b.TotalValuePurchasesExVAT = b.TotalValuePurchasesExVAT.SaturatingAdd(tx.LineEx)
+	case TaxTreatmentReverseCharge:
+		tax := tx.ReverseChargeTax()
+		b.VATDueSales = b.VATDueSales.SaturatingAdd(tax)
+		b.VATReclaimedCurrPeriod = b.VATReclaimedCurrPeriod.SaturatingAdd(tax)
+		b.TotalValueSalesExVAT = b.TotalValueSalesExVAT.SaturatingAdd(tx.LineEx)
+		b.TotalValuePurchasesExVAT = b.TotalValuePurchasesExVAT.SaturatingAdd(tx.LineEx)
+	}
+
+	b.calcTotals()
+}
+
+// calcTotals works out boxes 3 and 5 from the others
+func (b *VATReturnBoxes) calcTotals() {
+	b.TotalVATDue = b.VATDueSales.SaturatingAdd(b.VATDueAcquisitions)
+	b.NetVATDue = b.TotalVATDue.SaturatingSub(b.VATReclaimedCurrPeriod)
+}
+
+// NewVATReturnBoxes puts all of the sales and purchase lines for a period into the boxes.
+// The lines must already have had their tax calculated.
+func NewVATReturnBoxes(sales, purchases TaxCalcs) VATReturnBoxes {
+	var b VATReturnBoxes
+	for _, tx := range sales {
+		b.AddSale(tx)
+	}
+	for _, tx := range purchases {
+		b.AddPurchase(tx)
+	}
+
+	return b
+}
@@ -0,0 +1,166 @@
+package financial
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTaxTreatmentAddTax(t *testing.T) {
+	tests := []struct {
+		name                                     string
+		treatment                                TaxTreatment
+		expectedLineEx, expectedTax, expectedInc Cents
+		expectedReverseCharge                    Cents
+	}{
+		{"standard", TaxTreatmentStandard, 2000, 400, 2400, 0},
+		{"zero rated", TaxTreatmentZeroRated, 2000, 0, 2000, 0},
+		{"exempt", TaxTreatmentExempt, 2000, 0, 2000, 0},
+		{"reverse charge", TaxTreatmentReverseCharge, 2000, 0, 2000, 400},
+		{"out of scope", TaxTreatmentOutOfScope, 2000, 0, 2000, 0},
+	}
+
+	for _, test := range tests {
+		for _, method := range []TaxRoundingMethod{TaxRoundingMethodLine, TaxRoundingMethodUnit} {
+			tx := TaxCalc{UnitEx: 1000, LineQty: 2, TaxPercentage: 20, Treatment: test.treatment, RoundingMethod: method}
+			tx.AddTax()
+
+			if tx.LineEx != test.expectedLineEx || tx.LineTax != test.expectedTax || tx.LineInc != test.expectedInc {
+				t.Errorf("Testing %s. Expected %v/%v/%v; got %v/%v/%v", test.name,
+					test.expectedLineEx, test.expectedTax, test.expectedInc, tx.LineEx, tx.LineTax, tx.LineInc)
+			}
+
+			if tx.ReverseChargeTax() != test.expectedReverseCharge {
+				t.Errorf("Testing %s reverse charge. Expected %v; got %v", test.name, test.expectedReverseCharge, tx.ReverseChargeTax())
+			}
+		}
+	}
+}
+
+func TestTaxTreatmentRemoveTax(t *testing.T) {
+	tx := TaxCalc{LineInc: 2401, LineQty: 2, TaxPercentage: 20, Treatment: TaxTreatmentExempt}
+	tx.RemoveTax()
+
+	if tx.LineEx != 2401 || tx.LineTax != 0 || tx.UnitEx != 1200 {
+		t.Errorf("Testing exempt RemoveTax. Expected 2401/0/1200; got %v/%v/%v", tx.LineEx, tx.LineTax, tx.UnitEx)
+	}
+
+	tx = TaxCalc{LineInc: 2401, LineQty: 0, TaxPercentage: 20, Treatment: TaxTreatmentZeroRated}
+	tx.RemoveTax()
+
+	if tx.LineEx != 0 || tx.LineInc != 0 {
+		t.Errorf("Testing zero qty RemoveTax. Expected 0/0; got %v/%v", tx.LineEx, tx.LineInc)
+	}
+}
+
+func TestTaxTreatmentComponents(t *testing.T) {
+	tx := TaxCalc{
+		UnitEx:     10000,
+		LineQty:    1,
+		Treatment:  TaxTreatmentReverseCharge,
+		Components: TaxComponents{{Jurisdiction: "GST", TaxPercentage: 5}, {Jurisdiction: "PST", TaxPercentage: 7}},
+	}
+	tx.AddTax()
+
+	if tx.LineTax != 0 || tx.LineInc != 10000 {
+		t.Errorf("Testing reverse charge components. Expected 0/10000; got %v/%v", tx.LineTax, tx.LineInc)
+	}
+
+	if tx.ReverseChargeTax() != 1200 {
+		t.Errorf("Testing reverse charge components tax. Expected %v; got %v", 1200, tx.ReverseChargeTax())
+	}
+}
+
+func TestTaxTreatmentLabels(t *testing.T) {
+	tests := []struct {
+		treatment     TaxTreatment
+		expectedName  string
+		expectedLabel string
+	}{
+		{TaxTreatmentStandard, "standard", ""},
+		{TaxTreatmentZeroRated, "zero rated", "Zero rated"},
+		{TaxTreatmentExempt, "exempt", "Exempt"},
+		{TaxTreatmentReverseCharge, "reverse charge", "Reverse charge: customer to account for VAT"},
+		{TaxTreatmentOutOfScope, "out of scope", "Outside the scope of VAT"},
+	}
+
+	for _, test := range tests {
+		if test.treatment.String() != test.expectedName {
+			t.Errorf("Testing %d name. Expected %q; got %q", test.treatment, test.expectedName, test.treatment.String())
+		}
+
+		if test.treatment.InvoiceLabel() != test.expectedLabel {
+			t.Errorf("Testing %s label. Expected %q; got %q", test.treatment, test.expectedLabel, test.treatment.InvoiceLabel())
+		}
+	}
+}
+
+func TestTaxDocumentTreatments(t *testing.T) {
+	for _, method := range []TaxRoundingMethod{TaxRoundingMethodLine, TaxRoundingMethodTotals} {
+		td := NewTaxDocument(method, TaxCalcs{
+			{UnitEx: 333, LineQty: 1, TaxPercentage: 20},
+			{UnitEx: 333, LineQty: 1, TaxPercentage: 20},
+			{UnitEx: 1000, LineQty: 1, TaxPercentage: 0, Treatment: TaxTreatmentZeroRated},
+			{UnitEx: 500, LineQty: 1, TaxPercentage: 0, Treatment: TaxTreatmentExempt},
+			{UnitEx: 2000, LineQty: 1, TaxPercentage: 20, Treatment: TaxTreatmentReverseCharge},
+		})
+		td.AddTax()
+
+		expected := []TaxRateAnalysis{
+			{TaxPercentage: 0, Treatment: TaxTreatmentZeroRated, LineCount: 1, Ex: 1000, Tax: 0, Inc: 1000},
+			{TaxPercentage: 0, Treatment: TaxTreatmentExempt, LineCount: 1, Ex: 500, Tax: 0, Inc: 500},
+			{TaxPercentage: 20, Treatment: TaxTreatmentStandard, LineCount: 2, Ex: 666, Tax: 133, Inc: 799},
+			{TaxPercentage: 20, Treatment: TaxTreatmentReverseCharge, LineCount: 1, Ex: 2000, Tax: 0, Inc: 2000},
+		}
+		if method == TaxRoundingMethodLine {
+			expected[2].Tax, expected[2].Inc = 134, 800
+		}
+
+		if len(td.Analysis) != len(expected) {
+			t.Fatalf("Testing %v analysis. Expected %v; got %v", method, expected, td.Analysis)
+		}
+
+		for i := range expected {
+			if !reflect.DeepEqual(td.Analysis[i], expected[i]) {
+				t.Errorf("Testing %v analysis row %d. Expected %v; got %v", method, i, expected[i], td.Analysis[i])
+			}
+		}
+	}
+}
+
+func TestVATReturnBoxes(t *testing.T) {
+	calc := func(unitEx Cents, pc float64, treatment TaxTreatment) TaxCalc {
+		tx := TaxCalc{UnitEx: unitEx, LineQty: 1, TaxPercentage: pc, Treatment: treatment}
+		tx.AddTax()
+		return tx
+	}
+
+	sales := TaxCalcs{
+		calc(10000, 20, TaxTreatmentStandard),
+		calc(5000, 5, TaxTreatmentStandard),
+		calc(2000, 0, TaxTreatmentZeroRated),
+		calc(1000, 0, TaxTreatmentExempt),
+		calc(3000, 20, TaxTreatmentReverseCharge),
+		calc(9999, 0, TaxTreatmentOutOfScope),
+	}
+
+	purchases := TaxCalcs{
+		calc(4000, 20, TaxTreatmentStandard),
+		calc(500, 0, TaxTreatmentZeroRated),
+		calc(6000, 20, TaxTreatmentReverseCharge),
+		calc(7777, 0, TaxTreatmentOutOfScope),
+	}
+
+	expected := VATReturnBoxes{
+		VATDueSales:              2000 + 250 + 1200,
+		TotalVATDue:              3450,
+		VATReclaimedCurrPeriod:   800 + 1200,
+		NetVATDue:                1450,
+		TotalValueSalesExVAT:     10000 + 5000 + 2000 + 1000 + 3000 + 6000,
+		TotalValuePurchasesExVAT: 4000 + 500 + 6000,
+	}
+
+	got := NewVATReturnBoxes(sales, purchases)
+	if got != expected {
+		t.Errorf("Testing VAT return boxes. Expected %+v; got %+v", expected, got)
+	}
+}
@@ -0,0 +1,167 @@
+package financial
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var ErrInvalidPeriodKey = errors.New("invalid VAT return period key")
+
+// VATReturnLine is a sale or purchase for a VAT return.  EUGoods marks goods sold to, or bought from,
+// a VAT registered business in the EU, which go in boxes 8 and 9 as well.  Since 2021 that only
+// applies to goods moving between Northern Ireland and the EU.
+type VATReturnLine struct {
+	TaxCalc
+	EUGoods bool
+}
+
+// AddEUGoodsSale puts a sale of goods to the EU into the boxes.  It goes in box 6 as usual, and in box 8 as well.
+func (b *VATReturnBoxes) AddEUGoodsSale(tx TaxCalc) {
+	b.AddSale(tx)
+
+	if tx.Treatment != TaxTreatmentOutOfScope {
+		b.TotalValueGoodsSuppliedExVAT = b.TotalValueGoodsSuppliedExVAT.SaturatingAdd(tx.LineEx)
+	}
+}
+
+// AddEUGoodsPurchase puts an acquisition of goods from the EU into the boxes.  It goes in box 7 as usual,
+// and in box 9 as well.  An acquisition that is reverse charged is accounted for in box 2 rather than
+// box 1, and as it isn't a sale, it doesn't go in box 6.
+func (b *VATReturnBoxes) AddEUGoodsPurchase(tx TaxCalc) {
+	switch tx.Treatment {
+	case TaxTreatmentOutOfScope:
+		return
+	case TaxTreatmentReverseCharge:
+		tax := tx.ReverseChargeTax()
+		b.VATDueAcquisitions = b.VATDueAcquisitions.SaturatingAdd(tax)
+		b.VATReclaimedCurrPeriod = b.VATReclaimedCurrPeriod.SaturatingAdd(tax)
+		b.TotalValuePurchasesExVAT = b.TotalValuePurchasesExVAT.SaturatingAdd(tx.LineEx)
+		b.calcTotals()
+	default:
+		b.AddPurchase(tx)
+	}
+
+	b.TotalAcquisitionsExVAT = b.TotalAcquisitionsExVAT.SaturatingAdd(tx.LineEx)
+}
+
+// VATReturn is the sales and purchases for one VAT period.  PeriodKey is the 4 character
+// key HMRC gives for the period, e.g. "21A1".
+type VATReturn struct {
+	PeriodKey string
+	Sales     []VATReturnLine
+	Purchases []VATReturnLine
+}
+
+func NewVATReturn(periodKey string) *VATReturn {
+	return &VATReturn{PeriodKey: periodKey}
+}
+
+// AddSales adds lines that have already had their tax calculated
+func (vr *VATReturn) AddSales(euGoods bool, lines ...TaxCalc) {
+	for _, tx := range lines {
+		vr.Sales = append(vr.Sales, VATReturnLine{TaxCalc: tx, EUGoods: euGoods})
+	}
+}
+
+// AddPurchases adds lines that have already had their tax calculated
+func (vr *VATReturn) AddPurchases(euGoods bool, lines ...TaxCalc) {
+	for _, tx := range lines {
+		vr.Purchases = append(vr.Purchases, VATReturnLine{TaxCalc: tx, EUGoods: euGoods})
+	}
+}
+
+// Boxes adds up all of the lines into the nine boxes, in pence
+func (vr *VATReturn) Boxes() VATReturnBoxes {
+	var b VATReturnBoxes
+	for _, line := range vr.Sales {
+		if line.EUGoods {
+			b.AddEUGoodsSale(line.TaxCalc)
+		} else {
+			b.AddSale(line.TaxCalc)
+		}
+	}
+
+	for _, line := range vr.Purchases {
+		if line.EUGoods {
+			b.AddEUGoodsPurchase(line.TaxCalc)
+		} else {
+			b.AddPurchase(line.TaxCalc)
+		}
+	}
+
+	return b
+}
+
+// MTDVATReturn is the body that the Making Tax Digital API expects when submitting a VAT return.
+// Boxes 1 to 5 are in pounds and pence, and boxes 6 to 9 are in whole pounds.
+type MTDVATReturn struct {
+	PeriodKey                    string      `json:"periodKey"`
+	VATDueSales                  json.Number `json:"vatDueSales"`
+	VATDueAcquisitions           json.Number `json:"vatDueAcquisitions"`
+	TotalVATDue                  json.Number `json:"totalVatDue"`
+	VATReclaimedCurrPeriod       json.Number `json:"vatReclaimedCurrPeriod"`
+	NetVATDue                    json.Number `json:"netVatDue"`
+	TotalValueSalesExVAT         int64       `json:"totalValueSalesExVAT"`
+	TotalValuePurchasesExVAT     int64       `json:"totalValuePurchasesExVAT"`
+	TotalValueGoodsSuppliedExVAT int64       `json:"totalValueGoodsSuppliedExVAT"`
+	TotalAcquisitionsExVAT       int64       `json:"totalAcquisitionsExVAT"`
+	Finalised                    bool        `json:"finalised"`
+}
+
+// wholePounds drops the pence, as HMRC requires for boxes 6 to 9.  They are truncated rather
+// than rounded, so -£12.99 is -£12.
+func wholePounds(c Cents) int64 {
+	return int64(c) / 100
+}
+
+// MTD converts the boxes to the MTD body.  Box 5 is always positive in MTD, whether it is
+// a payment or a repayment; which it is follows from boxes 3 and 4.
+func (b VATReturnBoxes) MTD(periodKey string, finalised bool) (MTDVATReturn, error) {
+	if len(periodKey) != 4 {
+		return MTDVATReturn{}, fmt.Errorf("%w: %q", ErrInvalidPeriodKey, periodKey)
+	}
+
+	netVATDue := b.NetVATDue
+	if netVATDue < 0 {
+		netVATDue = -netVATDue
+	}
+
+	return MTDVATReturn{
+		PeriodKey:                    periodKey,
+		VATDueSales:                  json.Number(b.VATDueSales.FormatAsPrice()),
+		VATDueAcquisitions:           json.Number(b.VATDueAcquisitions.FormatAsPrice()),
+		TotalVATDue:                  json.Number(b.TotalVATDue.FormatAsPrice()),
+		VATReclaimedCurrPeriod:       json.Number(b.VATReclaimedCurrPeriod.FormatAsPrice()),
+		NetVATDue:                    json.Number(netVATDue.FormatAsPrice()),
+		TotalValueSalesExVAT:         wholePounds(b.TotalValueSalesExVAT),
+		TotalValuePurchasesExVAT:     wholePounds(b.TotalValuePurchasesExVAT),
+		TotalValueGoodsSuppliedExVAT: wholePounds(b.TotalValueGoodsSuppliedExVAT),
+		TotalAcquisitionsExVAT:       wholePounds(b.TotalAcquisitionsExVAT),
+		Finalised:                    finalised,
+	}, nil
+}
+
+// IsRepayment is whether HMRC owes the business, rather than the other way round
+func (b VATReturnBoxes) IsRepayment() bool {
+	return b.NetVATDue < 0
+}
+
+// MTD adds up the return and converts it to the MTD body.  Finalised is the business's
+// declaration that the return is complete, and HMRC rejects a submission without it.
+func (vr *VATReturn) MTD(finalised bool) (MTDVATReturn, error) {
+	return vr.Boxes().MTD(vr.PeriodKey, finalised)
+}
+
+// WriteMTDJSON writes the MTD body ready to be submitted
+func (vr *VATReturn) WriteMTDJSON(w io.Writer, finalised bool) error {
+	body, err := vr.MTD(finalised)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(body)
+}
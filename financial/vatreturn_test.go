@@ -0,0 +1,127 @@
+package financial
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"testing"
+)
+
+func vatReturnLine(unitEx Cents, pc float64, treatment TaxTreatment) TaxCalc {
+	tx := TaxCalc{UnitEx: unitEx, LineQty: 1, TaxPercentage: pc, Treatment: treatment}
+	tx.AddTax()
+	return tx
+}
+
+// testVATReturn is a quarter for a business in Northern Ireland, which still trades goods with the EU
+func testVATReturn() *VATReturn {
+	vr := NewVATReturn("21A1")
+	vr.AddSales(false,
+		vatReturnLine(123456, 20, TaxTreatmentStandard),
+		vatReturnLine(10050, 0, TaxTreatmentExempt),
+		vatReturnLine(88888, 0, TaxTreatmentOutOfScope),
+	)
+	vr.AddSales(true, vatReturnLine(50099, 0, TaxTreatmentZeroRated))
+	vr.AddPurchases(false,
+		vatReturnLine(40075, 20, TaxTreatmentStandard),
+		vatReturnLine(10000, 20, TaxTreatmentReverseCharge),
+	)
+	vr.AddPurchases(true, vatReturnLine(30099, 20, TaxTreatmentReverseCharge))
+
+	return vr
+}
+
+func TestVATReturnBoxesWithEUGoods(t *testing.T) {
+	expected := VATReturnBoxes{
+		VATDueSales:                  26691,
+		VATDueAcquisitions:           6020,
+		TotalVATDue:                  32711,
+		VATReclaimedCurrPeriod:       16035,
+		NetVATDue:                    16676,
+		TotalValueSalesExVAT:         193605,
+		TotalValuePurchasesExVAT:     80174,
+		TotalValueGoodsSuppliedExVAT: 50099,
+		TotalAcquisitionsExVAT:       30099,
+	}
+
+	got := testVATReturn().Boxes()
+	if got != expected {
+		t.Errorf("Testing VAT return boxes. Expected %+v; got %+v", expected, got)
+	}
+}
+
+func TestVATReturnMTDJSON(t *testing.T) {
+	expected, err := ioutil.ReadFile("testdata/mtd-vat-return.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var b bytes.Buffer
+	if err := testVATReturn().WriteMTDJSON(&b, true); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.String() != string(expected) {
+		t.Errorf("Testing MTD JSON. Expected %s; got %s", expected, b.String())
+	}
+}
+
+func TestVATReturnMTD(t *testing.T) {
+	tests := []struct {
+		name            string
+		boxes           VATReturnBoxes
+		periodKey       string
+		expectedNet     string
+		expectedBox6    int64
+		expectRepayment bool
+		expectedErr     error
+	}{
+		{
+			name:         "payment",
+			boxes:        VATReturnBoxes{TotalVATDue: 1000, VATReclaimedCurrPeriod: 250, NetVATDue: 750, TotalValueSalesExVAT: 500099},
+			periodKey:    "#001",
+			expectedNet:  "7.50",
+			expectedBox6: 5000,
+		},
+		{
+			name:            "repayment is positive in box 5",
+			boxes:           VATReturnBoxes{TotalVATDue: 250, VATReclaimedCurrPeriod: 1000, NetVATDue: -750, TotalValueSalesExVAT: -1299},
+			periodKey:       "21A2",
+			expectedNet:     "7.50",
+			expectedBox6:    -12,
+			expectRepayment: true,
+		},
+		{
+			name:        "period key too long",
+			periodKey:   "21A12",
+			expectedErr: ErrInvalidPeriodKey,
+		},
+		{
+			name:        "no period key",
+			expectedErr: ErrInvalidPeriodKey,
+		},
+	}
+
+	for _, test := range tests {
+		got, err := test.boxes.MTD(test.periodKey, false)
+		if !errors.Is(err, test.expectedErr) {
+			t.Errorf("Testing %s error. Expected %v; got %v", test.name, test.expectedErr, err)
+		}
+
+		if err != nil {
+			continue
+		}
+
+		if got.NetVATDue.String() != test.expectedNet {
+			t.Errorf("Testing %s box 5. Expected %v; got %v", test.name, test.expectedNet, got.NetVATDue)
+		}
+
+		if got.TotalValueSalesExVAT != test.expectedBox6 {
+			t.Errorf("Testing %s box 6. Expected %v; got %v", test.name, test.expectedBox6, got.TotalValueSalesExVAT)
+		}
+
+		if test.boxes.IsRepayment() != test.expectRepayment {
+			t.Errorf("Testing %s repayment. Expected %v; got %v", test.name, test.expectRepayment, test.boxes.IsRepayment())
+		}
+	}
+}
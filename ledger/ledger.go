@@ -0,0 +1,295 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dogpakk/lib/financial"
+)
+
+var (
+	ErrUnbalanced      = errors.New("journal entry does not balance")
+	ErrTooFewPostings  = errors.New("journal entry needs at least two postings")
+	ErrInvalidPosting  = errors.New("posting needs an account and a currency")
+	ErrAccountNotFound = errors.New("account not found")
+	ErrAccountExists   = errors.New("account already exists")
+)
+
+// AccountType is the usual five way split of a chart of accounts
+type AccountType uint
+
+const (
+	AccountTypeAsset AccountType = iota
+	AccountTypeLiability
+	AccountTypeEquity
+	AccountTypeIncome
+	AccountTypeExpense
+)
+
+// DebitNormal says whether an account of this type normally has a debit balance,
+// which is assets and expenses; the others normally have a credit balance
+func (at AccountType) DebitNormal() bool {
+	return at == AccountTypeAsset || at == AccountTypeExpense
+}
+
+type Account struct {
+	Code string      `json:"code" bson:"_id"`
+	Name string      `json:"name" bson:"name"`
+	Type AccountType `json:"type" bson:"type"`
+}
+
+// Posting is one side of a journal entry.  Debits are positive and credits are negative,
+// so the postings of a balanced entry add up to zero in each currency.
+type Posting struct {
+	Account  string          `json:"account" bson:"account"`
+	Currency string          `json:"currency" bson:"currency"`
+	Amount   financial.Cents `json:"amount" bson:"amount"`
+}
+
+func Debit(account, currency string, amount financial.Cents) Posting {
+	return Posting{Account: account, Currency: currency, Amount: amount}
+}
+
+func Credit(account, currency string, amount financial.Cents) Posting {
+	return Posting{Account: account, Currency: currency, Amount: -amount}
+}
+
+func (p Posting) IsDebit() bool {
+	return p.Amount > 0
+}
+
+// JournalEntry is a single transaction, which can post to any number of accounts
+// in any number of currencies, as long as each currency balances on its own
+type JournalEntry struct {
+	ID          string    `json:"id" bson:"_id"`
+	Date        time.Time `json:"date" bson:"date"`
+	Description string    `json:"description" bson:"description"`
+	Postings    []Posting `json:"postings" bson:"postings"`
+}
+
+func NewJournalEntry(date time.Time, description string, postings ...Posting) JournalEntry {
+	return JournalEntry{
+		Date:        date,
+		Description: description,
+		Postings:    postings,
+	}
+}
+
+// CurrencyTotals is the net of the postings in each currency, which is all zeros if the entry balances
+func (je JournalEntry) CurrencyTotals() financial.CentDict {
+	res := financial.CentDict{}
+	for _, p := range je.Postings {
+		res.AddToKey(p.Currency, p.Amount)
+	}
+
+	return res
+}
+
+// Validate checks that the entry can be posted, which mainly means that it balances in every currency
+func (je JournalEntry) Validate() error {
+	if len(je.Postings) < 2 {
+		return ErrTooFewPostings
+	}
+
+	for i, p := range je.Postings {
+		if strings.TrimSpace(p.Account) == "" || strings.TrimSpace(p.Currency) == "" {
+			return fmt.Errorf("%w: posting %d", ErrInvalidPosting, i)
+		}
+	}
+
+	totals := je.CurrencyTotals()
+	var unbalanced []string
+	for currency, total := range totals {
+		if total != 0 {
+			unbalanced = append(unbalanced, fmt.Sprintf("%s by %s", currency, total.FormatAsPrice()))
+		}
+	}
+
+	if len(unbalanced) > 0 {
+		sort.Strings(unbalanced)
+		return fmt.Errorf("%w: %s", ErrUnbalanced, strings.Join(unbalanced, ", "))
+	}
+
+	return nil
+}
+
+// Ledger does the bookkeeping on top of whichever Store holds the accounts and entries
+type Ledger struct {
+	Store Store
+}
+
+func NewLedger(store Store) *Ledger {
+	return &Ledger{Store: store}
+}
+
+// OpenAccount adds an account to the chart of accounts
+func (l *Ledger) OpenAccount(ctx context.Context, account Account) error {
+	if _, err := l.Store.Account(ctx, account.Code); err == nil {
+		return fmt.Errorf("%w: %s", ErrAccountExists, account.Code)
+	} else if !errors.Is(err, ErrAccountNotFound) {
+		return err
+	}
+
+	return l.Store.SaveAccount(ctx, account)
+}
+
+// Post checks that the entry balances and that all of its accounts exist, and then saves it,
+// returning it with the ID given to it by the store
+func (l *Ledger) Post(ctx context.Context, je JournalEntry) (JournalEntry, error) {
+	if err := je.Validate(); err != nil {
+		return je, err
+	}
+
+	checked := map[string]bool{}
+	for _, p := range je.Postings {
+		if checked[p.Account] {
+			continue
+		}
+
+		if _, err := l.Store.Account(ctx, p.Account); err != nil {
+			return je, err
+		}
+		checked[p.Account] = true
+	}
+
+	return l.Store.SaveEntry(ctx, je)
+}
+
+// Balances are the balances of every account, keyed by account and then currency,
+// from all entries dated before asOf.  A zero asOf means all entries.
+func (l *Ledger) Balances(ctx context.Context, asOf time.Time) (financial.KeyedCentDict, error) {
+	entries, err := l.Store.Entries(ctx, EntryFilter{To: asOf})
+	if err != nil {
+		return nil, err
+	}
+
+	res := financial.KeyedCentDict{}
+	for _, je := range entries {
+		for _, p := range je.Postings {
+			res.AddToKey(p.Account, p.Currency, p.Amount)
+		}
+	}
+
+	return res, nil
+}
+
+// Balance is the balance of a single account in each currency, from all entries dated before asOf
+func (l *Ledger) Balance(ctx context.Context, account string, asOf time.Time) (financial.CentDict, error) {
+	entries, err := l.Store.Entries(ctx, EntryFilter{Account: account, To: asOf})
+	if err != nil {
+		return nil, err
+	}
+
+	res := financial.CentDict{}
+	for _, je := range entries {
+		for _, p := range je.Postings {
+			if p.Account == account {
+				res.AddToKey(p.Currency, p.Amount)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// RunningBalance is one line of an account statement
+type RunningBalance struct {
+	EntryID     string
+	Date        time.Time
+	Description string
+	Amount      financial.Cents
+	Balance     financial.Cents
+}
+
+// RunningBalances is the statement for an account in a single currency: each posting in date order
+// with the balance after it
+func (l *Ledger) RunningBalances(ctx context.Context, account, currency string) ([]RunningBalance, error) {
+	entries, err := l.Store.Entries(ctx, EntryFilter{Account: account})
+	if err != nil {
+		return nil, err
+	}
+
+	var res []RunningBalance
+	var balance financial.Cents
+	for _, je := range entries {
+		for _, p := range je.Postings {
+			if p.Account != account || p.Currency != currency {
+				continue
+			}
+
+			balance += p.Amount
+			res = append(res, RunningBalance{
+				EntryID:     je.ID,
+				Date:        je.Date,
+				Description: je.Description,
+				Amount:      p.Amount,
+				Balance:     balance,
+			})
+		}
+	}
+
+	return res, nil
+}
+
+// TrialBalance lists each account's balance in the debit or credit column, keyed by account
+// and then currency.  Credits are shown as positive amounts.  If the books are in order,
+// the debit and credit totals are the same in every currency.
+type TrialBalance struct {
+	AsOf    time.Time
+	Debits  financial.KeyedCentDict
+	Credits financial.KeyedCentDict
+
+	TotalDebits  financial.CentDict
+	TotalCredits financial.CentDict
+}
+
+func (l *Ledger) TrialBalance(ctx context.Context, asOf time.Time) (TrialBalance, error) {
+	res := TrialBalance{
+		AsOf:         asOf,
+		Debits:       financial.KeyedCentDict{},
+		Credits:      financial.KeyedCentDict{},
+		TotalDebits:  financial.CentDict{},
+		TotalCredits: financial.CentDict{},
+	}
+
+	balances, err := l.Balances(ctx, asOf)
+	if err != nil {
+		return res, err
+	}
+
+	for account, byCurrency := range balances {
+		for currency, balance := range byCurrency {
+			switch {
+			case balance > 0:
+				res.Debits.AddToKey(account, currency, balance)
+				res.TotalDebits.AddToKey(currency, balance)
+			case balance < 0:
+				res.Credits.AddToKey(account, currency, -balance)
+				res.TotalCredits.AddToKey(currency, -balance)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// IsBalanced checks that the debits equal the credits in every currency
+func (tb TrialBalance) IsBalanced() bool {
+	for currency, total := range tb.TotalDebits {
+		if tb.TotalCredits[currency] != total {
+			return false
+		}
+	}
+
+	for currency, total := range tb.TotalCredits {
+		if tb.TotalDebits[currency] != total {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,194 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/dogpakk/lib/financial"
+)
+
+func day(d int) time.Time {
+	return time.Date(2021, time.March, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestJournalEntryValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		postings []Posting
+		expected error
+	}{
+		{"balanced", []Posting{Debit("bank", "GBP", 1000), Credit("sales", "GBP", 1000)}, nil},
+		{"split", []Posting{Debit("bank", "GBP", 1200), Credit("sales", "GBP", 1000), Credit("vat", "GBP", 200)}, nil},
+		{"two currencies each balanced", []Posting{
+			Debit("bank", "GBP", 1000), Credit("sales", "GBP", 1000),
+			Debit("bank-eur", "EUR", 500), Credit("sales", "EUR", 500),
+		}, nil},
+		{"unbalanced", []Posting{Debit("bank", "GBP", 1000), Credit("sales", "GBP", 999)}, ErrUnbalanced},
+		{"balanced overall but not per currency", []Posting{Debit("bank", "GBP", 1000), Credit("sales", "EUR", 1000)}, ErrUnbalanced},
+		{"one posting", []Posting{Debit("bank", "GBP", 0)}, ErrTooFewPostings},
+		{"no account", []Posting{Debit("", "GBP", 1000), Credit("sales", "GBP", 1000)}, ErrInvalidPosting},
+		{"no currency", []Posting{Debit("bank", "", 1000), Credit("sales", "", 1000)}, ErrInvalidPosting},
+	}
+
+	for _, test := range tests {
+		err := NewJournalEntry(day(1), test.name, test.postings...).Validate()
+		if !errors.Is(err, test.expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, err)
+		}
+	}
+}
+
+func testLedger(t *testing.T) *Ledger {
+	ctx := context.Background()
+	l := NewLedger(NewMemoryStore())
+
+	for _, account := range []Account{
+		{"bank", "Bank", AccountTypeAsset},
+		{"debtors", "Debtors", AccountTypeAsset},
+		{"sales", "Sales", AccountTypeIncome},
+		{"vat", "VAT", AccountTypeLiability},
+		{"rent", "Rent", AccountTypeExpense},
+	} {
+		if err := l.OpenAccount(ctx, account); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, je := range []JournalEntry{
+		NewJournalEntry(day(3), "invoice 2", Debit("debtors", "EUR", 6000), Credit("sales", "EUR", 5000), Credit("vat", "EUR", 1000)),
+		NewJournalEntry(day(1), "invoice 1", Debit("debtors", "GBP", 12000), Credit("sales", "GBP", 10000), Credit("vat", "GBP", 2000)),
+		NewJournalEntry(day(2), "payment 1", Debit("bank", "GBP", 12000), Credit("debtors", "GBP", 12000)),
+		NewJournalEntry(day(3), "rent", Debit("rent", "GBP", 50000), Credit("bank", "GBP", 50000)),
+	} {
+		if _, err := l.Post(ctx, je); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return l
+}
+
+func TestLedgerPost(t *testing.T) {
+	ctx := context.Background()
+	l := testLedger(t)
+
+	if err := l.OpenAccount(ctx, Account{Code: "bank"}); !errors.Is(err, ErrAccountExists) {
+		t.Errorf("Testing opening an account twice. Expected %v; got %v", ErrAccountExists, err)
+	}
+
+	_, err := l.Post(ctx, NewJournalEntry(day(4), "unknown", Debit("bank", "GBP", 100), Credit("nowhere", "GBP", 100)))
+	if !errors.Is(err, ErrAccountNotFound) {
+		t.Errorf("Testing posting to an unknown account. Expected %v; got %v", ErrAccountNotFound, err)
+	}
+
+	_, err = l.Post(ctx, NewJournalEntry(day(4), "unbalanced", Debit("bank", "GBP", 100), Credit("sales", "GBP", 10)))
+	if !errors.Is(err, ErrUnbalanced) {
+		t.Errorf("Testing posting an unbalanced entry. Expected %v; got %v", ErrUnbalanced, err)
+	}
+
+	je, err := l.Post(ctx, NewJournalEntry(day(4), "ok", Debit("bank", "GBP", 100), Credit("sales", "GBP", 100)))
+	if err != nil || je.ID == "" {
+		t.Errorf("Testing posting. Expected an ID and no error; got %q and %v", je.ID, err)
+	}
+}
+
+func TestLedgerBalances(t *testing.T) {
+	ctx := context.Background()
+	l := testLedger(t)
+
+	tests := []struct {
+		name     string
+		account  string
+		asOf     time.Time
+		expected financial.CentDict
+	}{
+		{"bank", "bank", time.Time{}, financial.CentDict{"GBP": -38000}},
+		{"bank before the rent", "bank", day(3), financial.CentDict{"GBP": 12000}},
+		{"debtors", "debtors", time.Time{}, financial.CentDict{"GBP": 0, "EUR": 6000}},
+		{"sales in both currencies", "sales", time.Time{}, financial.CentDict{"GBP": -10000, "EUR": -5000}},
+		{"nothing yet", "vat", day(1), financial.CentDict{}},
+	}
+
+	for _, test := range tests {
+		res, err := l.Balance(ctx, test.account, test.asOf)
+		if err != nil {
+			t.Errorf("Testing %s. Unexpected error: %s", test.name, err)
+		}
+
+		if len(res) != len(test.expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, res)
+		}
+		for currency, balance := range test.expected {
+			if res[currency] != balance {
+				t.Errorf("Testing %s %s. Expected %v; got %v", test.name, currency, balance, res[currency])
+			}
+		}
+	}
+
+	all, err := l.Balances(ctx, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if all["vat"]["GBP"] != -2000 || all["vat"]["EUR"] != -1000 || all["rent"]["GBP"] != 50000 {
+		t.Errorf("Testing all balances. Got %v", all)
+	}
+}
+
+func TestLedgerRunningBalances(t *testing.T) {
+	l := testLedger(t)
+
+	res, err := l.RunningBalances(context.Background(), "bank", "GBP")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []RunningBalance{
+		{Description: "payment 1", Amount: 12000, Balance: 12000},
+		{Description: "rent", Amount: -50000, Balance: -38000},
+	}
+
+	if len(res) != len(expected) {
+		t.Fatalf("Testing running balances. Expected %d lines; got %v", len(expected), res)
+	}
+
+	for i := range expected {
+		if res[i].Description != expected[i].Description || res[i].Amount != expected[i].Amount || res[i].Balance != expected[i].Balance {
+			t.Errorf("Testing running balance %d. Expected %v; got %v", i, expected[i], res[i])
+		}
+	}
+}
+
+func TestLedgerTrialBalance(t *testing.T) {
+	l := testLedger(t)
+
+	tb, err := l.TrialBalance(context.Background(), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !tb.IsBalanced() {
+		t.Errorf("Testing trial balance. Expected it to balance; got debits %v and credits %v", tb.TotalDebits, tb.TotalCredits)
+	}
+
+	expectedDebits := financial.CentDict{"GBP": 50000, "EUR": 6000}
+	for currency, total := range expectedDebits {
+		if tb.TotalDebits[currency] != total {
+			t.Errorf("Testing total debits %s. Expected %v; got %v", currency, total, tb.TotalDebits[currency])
+		}
+	}
+
+	if tb.Credits["bank"]["GBP"] != 38000 {
+		t.Errorf("Testing bank overdrawn. Expected %v; got %v", 38000, tb.Credits["bank"]["GBP"])
+	}
+
+	if _, ok := tb.Debits["debtors"]["GBP"]; ok {
+		t.Errorf("Testing zero balance. Expected debtors GBP to be left out; got %v", tb.Debits["debtors"])
+	}
+
+	tb.TotalCredits.AddToKey("GBP", 1)
+	if tb.IsBalanced() {
+		t.Errorf("Testing unbalanced trial balance. Expected it not to balance")
+	}
+}
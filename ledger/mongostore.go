@@ -0,0 +1,99 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/dogpakk/lib/mongoutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MongoStore keeps the accounts and entries in two MongoDB collections.
+// Entries on the same date come back in _id order, so new entries should be left
+// without an ID so that they get an ObjectID, which goes up as they are saved.
+type MongoStore struct {
+	AccountsCollection *mongo.Collection
+	EntriesCollection  *mongo.Collection
+}
+
+const (
+	mongoFieldID              = "_id"
+	mongoFieldDate            = "date"
+	mongoFieldPostingsAccount = "postings.account"
+)
+
+func NewMongoStore(db *mongo.Database, accountsCollection, entriesCollection string) *MongoStore {
+	return &MongoStore{
+		AccountsCollection: db.Collection(accountsCollection),
+		EntriesCollection:  db.Collection(entriesCollection),
+	}
+}
+
+func (s *MongoStore) SaveAccount(ctx context.Context, account Account) error {
+	q := mongoutil.NewQuery(mongoFieldID, account.Code)
+	_, err := s.AccountsCollection.ReplaceOne(ctx, q, account, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (s *MongoStore) Account(ctx context.Context, code string) (account Account, err error) {
+	q := mongoutil.NewQuery(mongoFieldID, code)
+	err = s.AccountsCollection.FindOne(ctx, q).Decode(&account)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = fmt.Errorf("%w: %s", ErrAccountNotFound, code)
+	}
+
+	return
+}
+
+func (s *MongoStore) Accounts(ctx context.Context) (res []Account, err error) {
+	fq := mongoutil.NewBlankQuery().NewFindQuery(mongoFieldID, false, 0, 0)
+
+	cur, err := s.AccountsCollection.Find(ctx, fq.Query, fq.FindOptions())
+	if err != nil {
+		return
+	}
+
+	err = cur.All(ctx, &res)
+	return
+}
+
+func (s *MongoStore) SaveEntry(ctx context.Context, je JournalEntry) (JournalEntry, error) {
+	if je.ID == "" {
+		je.ID = primitive.NewObjectID().Hex()
+	}
+
+	_, err := s.EntriesCollection.InsertOne(ctx, je)
+	return je, err
+}
+
+// entriesQuery builds the query for a filter, which can use an index on postings.account and date
+func (filter EntryFilter) entriesQuery() mongoutil.Query {
+	q := mongoutil.NewBlankQuery()
+	q.AddFilterIf(filter.Account != "", mongoFieldPostingsAccount, filter.Account)
+
+	dateRange := mongoutil.NewBlankQuery()
+	dateRange.AddFilterIf(!filter.From.IsZero(), mongoutil.OpGte, filter.From)
+	dateRange.AddFilterIf(!filter.To.IsZero(), mongoutil.OpLt, filter.To)
+
+	return q.AddFilterIf(len(dateRange) > 0, mongoFieldDate, dateRange)
+}
+
+func (s *MongoStore) Entries(ctx context.Context, filter EntryFilter) (res []JournalEntry, err error) {
+	fq := filter.entriesQuery().NewDefaultFindQuery()
+	fq.Sort = bson.D{
+		{Key: mongoFieldDate, Value: 1},
+		{Key: mongoFieldID, Value: 1},
+	}
+
+	cur, err := s.EntriesCollection.Find(ctx, fq.Query, fq.FindOptions())
+	if err != nil {
+		return
+	}
+
+	err = cur.All(ctx, &res)
+	return
+}
@@ -0,0 +1,132 @@
+package ledger
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EntryFilter narrows down the entries returned by a Store.
+// From is inclusive and To is exclusive, and a zero time means no limit.
+type EntryFilter struct {
+	Account  string
+	From, To time.Time
+}
+
+func (f EntryFilter) matches(je JournalEntry) bool {
+	if !f.From.IsZero() && je.Date.Before(f.From) {
+		return false
+	}
+
+	if !f.To.IsZero() && !je.Date.Before(f.To) {
+		return false
+	}
+
+	if f.Account == "" {
+		return true
+	}
+
+	for _, p := range je.Postings {
+		if p.Account == f.Account {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Store is where a Ledger keeps its accounts and entries.
+// Entries must come back in date order, with entries on the same date in the order they were saved.
+type Store interface {
+	SaveAccount(ctx context.Context, account Account) error
+	Account(ctx context.Context, code string) (Account, error)
+	Accounts(ctx context.Context) ([]Account, error)
+
+	// SaveEntry stores a new entry, giving it an ID if it doesn't have one
+	SaveEntry(ctx context.Context, je JournalEntry) (JournalEntry, error)
+	Entries(ctx context.Context, filter EntryFilter) ([]JournalEntry, error)
+}
+
+// MemoryStore keeps everything in memory, for tests and short lived ledgers.  It is safe for concurrent use.
+type MemoryStore struct {
+	mu       sync.RWMutex
+	accounts map[string]Account
+	entries  []JournalEntry
+	nextID   int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		accounts: map[string]Account{},
+	}
+}
+
+func (s *MemoryStore) SaveAccount(ctx context.Context, account Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accounts[account.Code] = account
+	return nil
+}
+
+func (s *MemoryStore) Account(ctx context.Context, code string) (Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	account, ok := s.accounts[code]
+	if !ok {
+		return account, fmt.Errorf("%w: %s", ErrAccountNotFound, code)
+	}
+
+	return account, nil
+}
+
+func (s *MemoryStore) Accounts(ctx context.Context) ([]Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	res := make([]Account, 0, len(s.accounts))
+	for _, account := range s.accounts {
+		res = append(res, account)
+	}
+
+	sort.Slice(res, func(i, j int) bool { return res[i].Code < res[j].Code })
+	return res, nil
+}
+
+func (s *MemoryStore) SaveEntry(ctx context.Context, je JournalEntry) (JournalEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	if je.ID == "" {
+		je.ID = strconv.Itoa(s.nextID)
+	}
+
+	// Take a copy of the postings so the caller can't change them afterwards
+	je.Postings = append([]Posting(nil), je.Postings...)
+
+	// Insert after any entries on the same date, to keep them in date order
+	i := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].Date.After(je.Date) })
+	s.entries = append(s.entries, JournalEntry{})
+	copy(s.entries[i+1:], s.entries[i:])
+	s.entries[i] = je
+
+	return je, nil
+}
+
+func (s *MemoryStore) Entries(ctx context.Context, filter EntryFilter) (res []JournalEntry, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, je := range s.entries {
+		if filter.matches(je) {
+			res = append(res, je)
+		}
+	}
+
+	return
+}
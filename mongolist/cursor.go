@@ -0,0 +1,287 @@
+package mongolist
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dogpakk/lib/mongoutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// SortField is a sort after the ListState's Order, for lists that need more than one field to order them
+type SortField struct {
+	Field      string `json:"field"`
+	Descending bool   `json:"descending"`
+}
+
+// CursorKey signs the cursors handed out to clients, so that a cursor can only come from
+// PageCursors.  Cursors are base64 and not encrypted, so the sort values in them can be read.
+type CursorKey []byte
+
+// PageCursors are the cursors for the pages either side of the one shown, to send back as the
+// ListState's Next or Prev
+type PageCursors struct {
+	Next string `json:"next,omitempty"`
+	Prev string `json:"prev,omitempty"`
+}
+
+// cursor is what a token holds: the sort it was made for, and the sort values and _id of the document
+type cursor struct {
+	Sort   []string    `bson:"s"`
+	Values bson.A      `bson:"v"`
+	ID     interface{} `bson:"id"`
+}
+
+// SortKeys are the Order followed by ThenBy
+func (ls ListState) SortKeys() mongoutil.SortKeys {
+	var res mongoutil.SortKeys
+	if ls.Order != "" {
+		res = append(res, mongoutil.SortKey{Field: ls.Order, Descending: ls.OrderDescending})
+	}
+
+	for _, sf := range ls.ThenBy {
+		res = append(res, mongoutil.SortKey{Field: sf.Field, Descending: sf.Descending})
+	}
+
+	return res
+}
+
+// Backwards is whether the ListState is for the page before a cursor, in which case the
+// results come in reverse order and need reversing before they are shown
+func (ls ListState) Backwards() bool {
+	return ls.Prev != ""
+}
+
+// sortNames identify a sort in a cursor, so that a cursor can't be used with a different one
+func sortNames(keys mongoutil.SortKeys) []string {
+	res := make([]string, len(keys))
+	for i, k := range keys {
+		res[i] = k.Field
+		if k.Descending {
+			res[i] = "-" + k.Field
+		}
+	}
+
+	return res
+}
+
+func (key CursorKey) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (key CursorKey) token(c cursor) (string, error) {
+	if len(key) == 0 {
+		return "", fmt.Errorf("%w: no cursor key", ErrInvalidCursor)
+	}
+
+	payload, err := bson.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(key.sign(payload)), nil
+}
+
+func (key CursorKey) parse(token string, keys mongoutil.SortKeys) (cursor, error) {
+	var c cursor
+	if len(key) == 0 {
+		return c, fmt.Errorf("%w: no cursor key", ErrInvalidCursor)
+	}
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 2 {
+		return c, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return c, fmt.Errorf("%w: malformed", ErrInvalidCursor)
+	}
+	sig, err := enc.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(sig, key.sign(payload)) {
+		return c, fmt.Errorf("%w: bad signature", ErrInvalidCursor)
+	}
+
+	if err := bson.Unmarshal(payload, &c); err != nil {
+		return c, fmt.Errorf("%w: %s", ErrInvalidCursor, err)
+	}
+
+	if strings.Join(c.Sort, ",") != strings.Join(sortNames(keys), ",") || len(c.Values) != len(keys) {
+		return c, fmt.Errorf("%w: made for a different sort", ErrInvalidCursor)
+	}
+
+	return c, nil
+}
+
+// KeysetFindQuery is FindQuery paged by cursor instead of offset, which stays fast however far into the list
+// it goes.  The first page has no Next or Prev, and each page after that is fetched with a cursor from
+// PageCursors.  Offset is ignored, and _id is always the last sort, to order documents with the same values.
+// It fetches one more than the Limit, to tell whether there is another page; see HasMore.
+func (ls ListState) KeysetFindQuery(key CursorKey) (mongoutil.FindQuery, error) {
+	return ls.keysetFindQuery(untypedFilter, key)
+}
+
+func (ls ListState) keysetFindQuery(buildFilter filterBuilder, key CursorKey) (mongoutil.FindQuery, error) {
+	fq, err := ls.findQuery(buildFilter)
+	if err != nil {
+		return fq, err
+	}
+
+	values, id, err := ls.cursorPosition(key)
+	if err != nil {
+		return fq, err
+	}
+
+	return mongoutil.NewKeysetFindQuery(fq.Query, ls.SortKeys(), values, id, ls.Backwards(), ls.keysetLimit())
+}
+
+// keysetLimit is one more than the Limit, so that the extra result shows there is another page
+func (ls ListState) keysetLimit() int {
+	if ls.Limit <= 0 {
+		return 0
+	}
+
+	return ls.Limit + 1
+}
+
+// HasMore is whether n results from a keyset query are more than the Limit, meaning there is another
+// page beyond them.  If so, the extra one, which is the last as fetched, is dropped before the page is shown.
+func (ls ListState) HasMore(n int) bool {
+	return ls.Limit > 0 && n > ls.Limit
+}
+
+// cursorPosition is the sort values and _id in the Next or Prev cursor, or a nil id for the first page
+func (ls ListState) cursorPosition(key CursorKey) ([]interface{}, interface{}, error) {
+	token := ls.Next
+	if ls.Backwards() {
+		if ls.Next != "" {
+			return nil, nil, fmt.Errorf("%w: only one of next and prev can be given", ErrInvalidCursor)
+		}
+		token = ls.Prev
+	}
+
+	if token == "" {
+		return nil, nil, nil
+	}
+
+	c, err := key.parse(token, ls.SortKeys())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []interface{}(c.Values), c.ID, nil
+}
+
+// KeysetPipelines is BodyToPipelines paged by cursor, as KeysetFindQuery, including fetching one more than
+// the Limit.  The second pipeline has neither the cursor nor the limit, for counting the whole list.
+func (ls ListState) KeysetPipelines(key CursorKey) (bson.A, bson.A, error) {
+	return ls.keysetPipelines(untypedFilter, key)
+}
+
+func (ls ListState) keysetPipelines(buildFilter filterBuilder, key CursorKey) (bson.A, bson.A, error) {
+	// Only the match is needed, as the keyset adds its own sort and limit
+	base := ls
+	base.Order, base.ThenBy, base.Offset, base.Limit = "", nil, 0, 0
+
+	pipeline, noLimitPipeline, err := bodyToPipelines(base, buildFilter)
+	if err != nil {
+		return bson.A{}, bson.A{}, err
+	}
+
+	values, id, err := ls.cursorPosition(key)
+	if err != nil {
+		return bson.A{}, bson.A{}, err
+	}
+
+	keys := ls.SortKeys()
+	if id != nil {
+		kq, err := keys.KeysetQuery(values, id, ls.Backwards())
+		if err != nil {
+			return bson.A{}, bson.A{}, err
+		}
+		pipeline = append(pipeline, bson.M{mongoMatch: kq})
+	}
+
+	pipeline = append(pipeline, bson.M{mongoSort: keys.KeysetSort(ls.Backwards())})
+	if limit := ls.keysetLimit(); limit > 0 {
+		pipeline = append(pipeline, intAgg(limit, mongoLimit))
+	}
+
+	return pipeline, noLimitPipeline, nil
+}
+
+// PageCursors are the cursors for the pages either side of a page of results.  first and last are the
+// first and last documents of the page as shown, i.e. after dropping the extra result and reversing a
+// Backwards page, and are nil if the page is empty.  more is HasMore for the results as fetched.
+// They can be any type that marshals to bson with _id and the sort fields, which can be missing or null.
+//
+// Going forwards, there is a Next only if there are more, and a Prev if the page was reached by cursor.
+// Going backwards, it is the other way round: a Prev only if there are more, and always a Next.
+func (ls ListState) PageCursors(key CursorKey, first, last interface{}, more bool) (PageCursors, error) {
+	var pc PageCursors
+	if first == nil || last == nil {
+		return pc, nil
+	}
+
+	hasPrev, hasNext := ls.Next != "", more
+	if ls.Backwards() {
+		hasPrev, hasNext = more, true
+	}
+
+	var err error
+	if hasPrev {
+		if pc.Prev, err = ls.cursorFor(key, first); err != nil {
+			return pc, err
+		}
+	}
+
+	if hasNext {
+		pc.Next, err = ls.cursorFor(key, last)
+	}
+
+	return pc, err
+}
+
+func (ls ListState) cursorFor(key CursorKey, doc interface{}) (string, error) {
+	b, err := bson.Marshal(doc)
+	if err != nil {
+		return "", err
+	}
+	raw := bson.Raw(b)
+
+	keys := ls.SortKeys()
+	c := cursor{Sort: sortNames(keys), Values: make(bson.A, len(keys))}
+	for i, k := range keys {
+		// Missing sorts as null, and both are left as nil in the cursor
+		v, err := raw.LookupErr(strings.Split(k.Field, ".")...)
+		switch {
+		case errors.Is(err, bsoncore.ErrElementNotFound):
+			continue
+		case err != nil:
+			return "", fmt.Errorf("%w: can't read %q from the document: %s", ErrInvalidCursor, k.Field, err)
+		case v.Type == bsontype.Null:
+			continue
+		}
+		c.Values[i] = v
+	}
+
+	id, err := raw.LookupErr("_id")
+	if err != nil {
+		return "", fmt.Errorf("%w: the document has no _id", ErrInvalidCursor)
+	}
+	c.ID = id
+
+	return key.token(c)
+}
@@ -0,0 +1,374 @@
+package mongolist
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/dogpakk/lib/mongoutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type cursorTestOrder struct {
+	ID      primitive.ObjectID `bson:"_id"`
+	Created time.Time          `bson:"created"`
+	Name    string             `bson:"name"`
+}
+
+var cursorTestKey = CursorKey("secret")
+
+func cursorTestPage() (ListState, cursorTestOrder, cursorTestOrder) {
+	ls := ListState{
+		Order:            "created",
+		OrderDescending:  true,
+		ThenBy:           []SortField{{Field: "name"}},
+		Limit:            2,
+		IncludeInactives: true,
+	}
+
+	first := cursorTestOrder{ID: primitive.NewObjectID(), Created: time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC), Name: "a"}
+	last := cursorTestOrder{ID: primitive.NewObjectID(), Created: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), Name: "b"}
+	return ls, first, last
+}
+
+func TestKeysetFindQuery(t *testing.T) {
+	ls, first, last := cursorTestPage()
+
+	fq, err := ls.KeysetFindQuery(cursorTestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedSort := bson.D{{Key: "created", Value: -1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}}
+	if !reflect.DeepEqual(fq.Sort, expectedSort) || len(fq.Query) != 0 || fq.Limit != 3 {
+		t.Errorf("Testing first page. Expected sort %v, no query and a limit of 3; got %v, %v and %d", expectedSort, fq.Sort, fq.Query, fq.Limit)
+	}
+
+	pc, err := ls.PageCursors(cursorTestKey, first, last, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pc.Prev != "" || pc.Next == "" {
+		t.Fatalf("Testing first page cursors. Expected only a next; got %+v", pc)
+	}
+
+	created := primitive.NewDateTimeFromTime(last.Created)
+	tests := []struct {
+		name         string
+		next, prev   string
+		expectedSort bson.D
+		expected     mongoutil.Query
+	}{
+		{
+			name:         "next",
+			next:         pc.Next,
+			expectedSort: expectedSort,
+			expected: mongoutil.NewQuery(mongoutil.OpOr, mongoutil.Queries{
+				{mongoutil.OpOr: mongoutil.Queries{{"created": mongoutil.NewQuery(mongoutil.OpLt, created)}, {"created": nil}}},
+				{"created": created, "name": mongoutil.NewQuery(mongoutil.OpGt, "b")},
+				{"created": created, "name": "b", "_id": mongoutil.NewQuery(mongoutil.OpGt, last.ID)},
+			}),
+		},
+		{
+			name:         "prev",
+			prev:         pc.Next,
+			expectedSort: bson.D{{Key: "created", Value: 1}, {Key: "name", Value: -1}, {Key: "_id", Value: -1}},
+			expected: mongoutil.NewQuery(mongoutil.OpOr, mongoutil.Queries{
+				{"created": mongoutil.NewQuery(mongoutil.OpGt, created)},
+				{"created": created, mongoutil.OpOr: mongoutil.Queries{{"name": mongoutil.NewQuery(mongoutil.OpLt, "b")}, {"name": nil}}},
+				{"created": created, "name": "b", "_id": mongoutil.NewQuery(mongoutil.OpLt, last.ID)},
+			}),
+		},
+	}
+
+	for _, test := range tests {
+		ls.Next, ls.Prev = test.next, test.prev
+		fq, err := ls.KeysetFindQuery(cursorTestKey)
+		if err != nil {
+			t.Errorf("Testing %s. Unexpected error %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(fq.Sort, test.expectedSort) {
+			t.Errorf("Testing %s sort. Expected %v; got %v", test.name, test.expectedSort, fq.Sort)
+		}
+
+		if !reflect.DeepEqual(fq.Query, test.expected) {
+			t.Errorf("Testing %s query. Expected %v; got %v", test.name, test.expected, fq.Query)
+		}
+
+		if fq.Offset != 0 || fq.Limit != 3 {
+			t.Errorf("Testing %s paging. Expected no offset and a limit of 3; got %d and %d", test.name, fq.Offset, fq.Limit)
+		}
+
+		// A full page reached by cursor has a Prev as well as a Next
+		if pc, err := ls.PageCursors(cursorTestKey, first, last, true); err != nil || pc.Prev == "" || pc.Next == "" {
+			t.Errorf("Testing %s cursors. Expected both; got %+v, %v", test.name, pc, err)
+		}
+	}
+}
+
+func TestKeysetFindQueryFilters(t *testing.T) {
+	ls, _, last := cursorTestPage()
+	ls.IncludeInactives = false
+	pc, _ := ls.PageCursors(cursorTestKey, last, last, true)
+
+	ls.Next = pc.Next
+	fq, err := ls.KeysetFindQuery(cursorTestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The cursor range is added to the filters, not in place of them
+	top, ok := fq.Query[mongoutil.OpAnd].(mongoutil.Queries)
+	if !ok || len(top) != 2 || top[0][mongoutil.OpAnd] == nil || top[1][mongoutil.OpOr] == nil {
+		t.Errorf("Testing filters and cursor. Expected both under $and; got %v", fq.Query)
+	}
+}
+
+func TestCursorInvalid(t *testing.T) {
+	ls, first, last := cursorTestPage()
+	pc, err := ls.PageCursors(cursorTestKey, first, last, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := []byte(pc.Next)
+	if tampered[3] == 'A' {
+		tampered[3] = 'B'
+	} else {
+		tampered[3] = 'A'
+	}
+
+	reordered := ls
+	reordered.OrderDescending = false
+
+	tests := []struct {
+		name      string
+		listState ListState
+		key       CursorKey
+		next      string
+		prev      string
+	}{
+		{"tampered", ls, cursorTestKey, string(tampered), ""},
+		{"wrong key", ls, CursorKey("other"), pc.Next, ""},
+		{"no key", ls, nil, pc.Next, ""},
+		{"different sort", reordered, cursorTestKey, pc.Next, ""},
+		{"both directions", ls, cursorTestKey, pc.Next, pc.Next},
+		{"malformed", ls, cursorTestKey, "not a cursor", ""},
+	}
+
+	for _, test := range tests {
+		test.listState.Next, test.listState.Prev = test.next, test.prev
+		if _, err := test.listState.KeysetFindQuery(test.key); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, ErrInvalidCursor, err)
+		}
+	}
+
+	if _, err := ls.PageCursors(cursorTestKey, bson.M{"created": 1}, bson.M{"created": 2}, true); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Testing documents without an _id. Expected %v; got %v", ErrInvalidCursor, err)
+	}
+
+	if _, err := ls.PageCursors(cursorTestKey, bson.M{"_id": 1, "created": "x"}, bson.M{"_id": 2, "created": "x"}, true); err != nil {
+		t.Errorf("Testing documents without all the sort fields. Unexpected error %v", err)
+	}
+}
+
+func TestKeysetIDOrder(t *testing.T) {
+	fq, err := ListState{Order: "_id", OrderDescending: true, ThenBy: []SortField{{Field: "name"}}}.KeysetFindQuery(cursorTestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing can come after _id, as it is unique
+	expected := bson.D{{Key: "_id", Value: -1}}
+	if !reflect.DeepEqual(fq.Sort, expected) {
+		t.Errorf("Testing sort by _id. Expected %v; got %v", expected, fq.Sort)
+	}
+
+	fq, err = ListState{}.KeysetFindQuery(cursorTestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = bson.D{{Key: "_id", Value: 1}}
+	if !reflect.DeepEqual(fq.Sort, expected) {
+		t.Errorf("Testing no sort. Expected %v; got %v", expected, fq.Sort)
+	}
+}
+
+func TestKeysetPipelines(t *testing.T) {
+	ls, first, last := cursorTestPage()
+	ls.Filters = []Filter{{Field: "name", Operator: filterOperatorNeq, Value: "x"}}
+	pc, _ := ls.PageCursors(cursorTestKey, first, last, true)
+
+	ls.Next = pc.Next
+	pipeline, noLimitPipeline, err := ls.KeysetPipelines(cursorTestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(pipeline) != 4 || len(noLimitPipeline) != 1 {
+		t.Fatalf("Testing pipeline stages. Expected 4 and 1; got %v and %v", pipeline, noLimitPipeline)
+	}
+
+	if _, ok := pipeline[1].(bson.M)[mongoMatch].(mongoutil.Query)[mongoutil.OpOr]; !ok {
+		t.Errorf("Testing cursor match. Expected $or; got %v", pipeline[1])
+	}
+
+	expected := bson.M{mongoSort: bson.D{{Key: "created", Value: -1}, {Key: "name", Value: 1}, {Key: "_id", Value: 1}}}
+	if !reflect.DeepEqual(pipeline[2], expected) {
+		t.Errorf("Testing sort. Expected %v; got %v", expected, pipeline[2])
+	}
+
+	if !reflect.DeepEqual(pipeline[3], bson.M{mongoLimit: 3}) {
+		t.Errorf("Testing limit. Expected 3; got %v", pipeline[3])
+	}
+}
+
+func TestListSchemaCursor(t *testing.T) {
+	schema := ListSchema{
+		Fields: map[string]FieldSchema{
+			"created": {Type: FieldTypeDate, Sortable: true},
+			"name":    {Type: FieldTypeString, Sortable: true},
+			"notes":   {Type: FieldTypeString},
+		},
+		CursorKey: cursorTestKey,
+	}
+
+	ls, first, last := cursorTestPage()
+	pc, err := ls.PageCursors(cursorTestKey, first, last, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ls.Next = pc.Next
+	fq, err := schema.FindQuery(ls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fq.Query[mongoutil.OpOr]; !ok {
+		t.Errorf("Testing schema with a cursor key. Expected a cursor range; got %v", fq.Query)
+	}
+
+	noCursors := schema
+	noCursors.CursorKey = nil
+
+	unsortable := ls
+	unsortable.ThenBy = []SortField{{Field: "notes"}}
+
+	tests := []struct {
+		name          string
+		schema        ListSchema
+		listState     ListState
+		expectedField string
+		expectedCode  string
+	}{
+		{"bad cursor", schema, ListState{Order: "created", Prev: "abc.def"}, "prev", ValidationCodeInvalidValue},
+		{"no cursor key", noCursors, ls, "next", ValidationCodeInvalidValue},
+		{"unsortable then by", schema, ListState{ThenBy: unsortable.ThenBy}, "thenBy[0].field", ValidationCodeNotSortable},
+	}
+
+	for _, test := range tests {
+		var errs ValidationErrors
+		_, err := test.schema.FindQuery(test.listState)
+		if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Field != test.expectedField || errs[0].Code != test.expectedCode {
+			t.Errorf("Testing %s. Expected %s at %s; got %v", test.name, test.expectedCode, test.expectedField, err)
+		}
+	}
+}
+
+func TestPageCursorsMore(t *testing.T) {
+	ls, first, last := cursorTestPage()
+	start, _ := ls.PageCursors(cursorTestKey, first, last, true)
+
+	tests := []struct {
+		name         string
+		next, prev   string
+		more         bool
+		expectedNext bool
+		expectedPrev bool
+	}{
+		{"only page", "", "", false, false, false},
+		{"first page", "", "", true, true, false},
+		{"next, full", start.Next, "", true, true, true},
+		{"next, last page", start.Next, "", false, false, true},
+		{"prev, full", "", start.Next, true, true, true},
+		{"prev, first page", "", start.Next, false, true, false},
+	}
+
+	for _, test := range tests {
+		ls.Next, ls.Prev = test.next, test.prev
+		pc, err := ls.PageCursors(cursorTestKey, first, last, test.more)
+		if err != nil || (pc.Next != "") != test.expectedNext || (pc.Prev != "") != test.expectedPrev {
+			t.Errorf("Testing %s. Expected next %v and prev %v; got %+v, %v", test.name, test.expectedNext, test.expectedPrev, pc, err)
+		}
+	}
+
+	if pc, _ := ls.PageCursors(cursorTestKey, nil, nil, true); pc != (PageCursors{}) {
+		t.Errorf("Testing an empty page. Expected no cursors; got %+v", pc)
+	}
+
+	if ls.HasMore(2) || !ls.HasMore(3) || (ListState{}).HasMore(100) {
+		t.Errorf("Testing HasMore with a limit of 2. Expected only 3 to have more")
+	}
+}
+
+func TestKeysetNullSortValues(t *testing.T) {
+	ls, _, _ := cursorTestPage()
+	id := primitive.NewObjectID()
+
+	// Neither sort field is set, so both are null
+	missing := bson.M{"_id": id}
+	pc, err := ls.PageCursors(cursorTestKey, missing, missing, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		next     string
+		prev     string
+		expected mongoutil.Query
+	}{
+		{
+			// Nothing comes after null in created descending, so that branch is left out
+			name: "next",
+			next: pc.Next,
+			expected: mongoutil.NewQuery(mongoutil.OpOr, mongoutil.Queries{
+				{"created": nil, "name": mongoutil.NewQuery(mongoutil.OpNe, nil)},
+				{"created": nil, "name": nil, "_id": mongoutil.NewQuery(mongoutil.OpGt, id)},
+			}),
+		},
+		{
+			name: "prev",
+			prev: pc.Next,
+			expected: mongoutil.NewQuery(mongoutil.OpOr, mongoutil.Queries{
+				{"created": mongoutil.NewQuery(mongoutil.OpNe, nil)},
+				{"created": nil, "name": nil, "_id": mongoutil.NewQuery(mongoutil.OpLt, id)},
+			}),
+		},
+	}
+
+	for _, test := range tests {
+		ls.Next, ls.Prev = test.next, test.prev
+		fq, err := ls.KeysetFindQuery(cursorTestKey)
+		if err != nil {
+			t.Errorf("Testing %s. Unexpected error %v", test.name, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(fq.Query, test.expected) {
+			t.Errorf("Testing %s query. Expected %v; got %v", test.name, test.expected, fq.Query)
+		}
+	}
+
+	// An explicit null is the same as missing
+	ls.Next, ls.Prev = "", ""
+	explicit := bson.M{"_id": id, "created": nil, "name": nil}
+	if pc2, err := ls.PageCursors(cursorTestKey, explicit, explicit, true); err != nil || pc2.Next != pc.Next {
+		t.Errorf("Testing explicit nulls. Expected the same cursor as missing fields; got %v", err)
+	}
+}
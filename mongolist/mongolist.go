@@ -1,6 +1,7 @@
 package mongolist
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
@@ -39,8 +40,11 @@ const (
 	filterOperatorAfter           = "after"
 	filterOperatorOnOrAfter       = "onorafter"
 	filterOperatorEntityNullCheck = "entityNullCheck"
+	filterOperatorRegex           = "regex"
 )
 
+var ErrInvalidFilterValue = errors.New("invalid filter value")
+
 type Filter struct {
 	Field    string      `json:"field"`
 	Operator string      `json:"operator"`
@@ -55,9 +59,32 @@ type ListState struct {
 	Limit            int      `json:"limit"`
 	Offset           int      `json:"offset"`
 	IncludeInactives bool     `json:"includeInactives"`
+
+	// ThenBy are further sorts after Order, for paging by cursor
+	ThenBy []SortField `json:"thenBy"`
+	// Next and Prev are cursors from PageCursors, to page by cursor rather than Offset.  At most one can be set.
+	Next string `json:"next"`
+	Prev string `json:"prev"`
 }
 
 func (ls ListState) FindQuery() (mongoutil.FindQuery, error) {
+	return ls.findQuery(untypedFilter)
+}
+
+// filterBuilder makes the mongo filter for a single Filter
+type filterBuilder func(filter Filter) (interface{}, error)
+
+func untypedFilter(filter Filter) (interface{}, error) {
+	// A regex is only allowed where a ListSchema says so, so without one it is rejected
+	// rather than falling through to an equality match
+	if filter.Operator == filterOperatorRegex {
+		return nil, fmt.Errorf("%w: %s filters need a ListSchema that allows them", ErrInvalidFilterValue, filter.Operator)
+	}
+
+	return createFilter(filter.Field, filter.Operator, filter.Value)
+}
+
+func (ls ListState) findQuery(buildFilter filterBuilder) (mongoutil.FindQuery, error) {
 	// Initialse with a blank query, which we might even end up using
 	// if there are no filters
 	findQuery := mu.NewFindQuery(mu.NewBlankQuery(), ls.Order, ls.OrderDescending, ls.Offset, ls.Limit)
@@ -65,7 +92,7 @@ func (ls ListState) FindQuery() (mongoutil.FindQuery, error) {
 	// Build up the list of filters from the listState
 	var filters mu.Queries
 	for _, filter := range ls.Filters {
-		f, err := createFilter(filter.Field, filter.Operator, filter.Value)
+		f, err := buildFilter(filter)
 		if err != nil {
 			return findQuery, err
 		}
@@ -102,11 +129,15 @@ func (ls ListState) FindQuery() (mongoutil.FindQuery, error) {
 }
 
 func BodyToPipelines(listState ListState) (bson.A, bson.A, error) {
+	return bodyToPipelines(listState, untypedFilter)
+}
+
+func bodyToPipelines(listState ListState, buildFilter filterBuilder) (bson.A, bson.A, error) {
 	// Filtering first
 	var mongoFilters []bson.M
 
 	for _, filter := range listState.Filters {
-		mongoFilter, err := createFilter(filter.Field, filter.Operator, filter.Value)
+		mongoFilter, err := buildFilter(filter)
 		if err != nil {
 			return bson.A{}, bson.A{}, err
 		} else {
@@ -175,24 +206,25 @@ func order(sortDescending bool) int {
 	return direction
 }
 
+// parseDate parses a date filter value, which must be a string in dateFormat
+func parseDate(value interface{}) (time.Time, error) {
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: dates must be strings like %s", ErrInvalidFilterValue, dateFormat)
+	}
+
+	t, err := time.Parse(dateFormat, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s", ErrInvalidFilterValue, err)
+	}
+
+	return t, nil
+}
+
 func createFilter(field, operator string, value interface{}) (interface{}, error) {
 	switch operator {
-	case filterOperatorStartsWith:
-		return bson.D{
-			{"$regex", fmt.Sprintf("^%s", value)},
-			{"$options", "i"},
-		}, nil
-
-	case filterOperatorContains:
-		return bson.D{
-			{"$regex", fmt.Sprintf("%s", value)},
-			{"$options", "i"},
-		}, nil
-	case filterOperatorEndsWith:
-		return bson.D{
-			{"$regex", fmt.Sprintf("%s$", value)},
-			{"$options", "i"},
-		}, nil
+	case filterOperatorStartsWith, filterOperatorContains, filterOperatorEndsWith:
+		return textFilter(operator, value, false)
 	case filterOperatorNeq:
 		return bson.M{
 			"$ne": value,
@@ -220,7 +252,7 @@ func createFilter(field, operator string, value interface{}) (interface{}, error
 			"$lte": value,
 		}, nil
 	case filterOperatorBefore:
-		t, err := time.Parse(dateFormat, value.(string))
+		t, err := parseDate(value)
 		if err != nil {
 			return bson.M{}, err
 		}
@@ -229,7 +261,7 @@ func createFilter(field, operator string, value interface{}) (interface{}, error
 			"$lt": t,
 		}, nil
 	case filterOperatorOnOrBefore:
-		t, err := time.Parse(dateFormat, value.(string))
+		t, err := parseDate(value)
 		if err != nil {
 			return bson.M{}, err
 		}
@@ -239,7 +271,7 @@ func createFilter(field, operator string, value interface{}) (interface{}, error
 		}, nil
 
 	case filterOperatorAfter:
-		t, err := time.Parse(dateFormat, value.(string))
+		t, err := parseDate(value)
 		if err != nil {
 			return bson.M{}, err
 		}
@@ -249,7 +281,7 @@ func createFilter(field, operator string, value interface{}) (interface{}, error
 		}, nil
 
 	case filterOperatorOnOrAfter:
-		t, err := time.Parse(dateFormat, value.(string))
+		t, err := parseDate(value)
 		if err != nil {
 			return bson.M{}, err
 		}
@@ -259,7 +291,10 @@ func createFilter(field, operator string, value interface{}) (interface{}, error
 		}, nil
 
 	case filterOperatorEntityNullCheck:
-		b := value.(bool)
+		b, ok := value.(bool)
+		if !ok {
+			return bson.M{}, fmt.Errorf("%w: %s needs true or false", ErrInvalidFilterValue, operator)
+		}
 		if !b {
 			return bson.M{"$in": bson.A{primitive.ObjectID{}, nil}}, nil
 		}
@@ -0,0 +1,419 @@
+package mongolist
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dogpakk/lib/mongoutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidListState is matched by errors.Is for any ValidationErrors
+var ErrInvalidListState = errors.New("invalid list state")
+
+type FieldType uint
+
+const (
+	FieldTypeString FieldType = iota
+	FieldTypeNumber
+	FieldTypeBool
+	FieldTypeDate
+	FieldTypeObjectID
+)
+
+func (ft FieldType) String() string {
+	switch ft {
+	case FieldTypeString:
+		return "string"
+	case FieldTypeNumber:
+		return "number"
+	case FieldTypeBool:
+		return "bool"
+	case FieldTypeDate:
+		return "date"
+	case FieldTypeObjectID:
+		return "objectid"
+	}
+
+	return "unknown"
+}
+
+// filterOperatorEqDefault is what a client sends for eq when it leaves the operator out
+const filterOperatorEqDefault = ""
+
+// defaultOperators are the operators that make sense for each type
+var defaultOperators = map[FieldType][]string{
+	FieldTypeString: {filterOperatorEq, filterOperatorEqOrNull, filterOperatorNeq, filterOperatorStartsWith, filterOperatorContains, filterOperatorEndsWith},
+	FieldTypeNumber: {filterOperatorEq, filterOperatorEqOrNull, filterOperatorNeq, filterOperatorGt, filterOperatorGte, filterOperatorLt, filterOperatorLte},
+	FieldTypeBool:   {filterOperatorEq, filterOperatorNeq},
+	FieldTypeDate:   {filterOperatorBefore, filterOperatorOnOrBefore, filterOperatorAfter, filterOperatorOnOrAfter},
+	FieldTypeObjectID: {
+		filterOperatorEq, filterOperatorEqOrNull, filterOperatorNeq, filterOperatorEntityNullCheck,
+	},
+}
+
+// FieldSchema is what a list allows for one field
+type FieldSchema struct {
+	Type FieldType
+	// Operators are the filter operators allowed on the field.  Nil allows all of the operators for the type.
+	Operators []string
+	// Sortable is whether the list can be ordered by the field
+	Sortable bool
+}
+
+func (fs FieldSchema) allows(operator string) bool {
+	if operator == filterOperatorEqDefault {
+		operator = filterOperatorEq
+	}
+
+	operators := fs.Operators
+	if operators == nil {
+		operators = defaultOperators[fs.Type]
+	}
+
+	for _, op := range operators {
+		if op == operator {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ListSchema is what a list endpoint accepts in its ListState.  Anything not declared is rejected,
+// so that clients can't filter or sort on fields that aren't meant to be exposed or indexed.
+type ListSchema struct {
+	Fields map[string]FieldSchema
+	// MaxLimit is the largest page a client can ask for.  0 means no maximum.
+	MaxLimit int
+	// MaxFilters is the most filters a client can send.  0 means no maximum.
+	MaxFilters int
+
+	// MaxRegexLength is the longest pattern for fields that allow the regex operator.  0 means the default of 100.
+	MaxRegexLength int
+	// MaxRegexTime is how long the server may run a query that uses the regex operator.  0 means the default of 5 seconds.
+	MaxRegexTime time.Duration
+
+	// DiacriticInsensitive compares strings ignoring accents as well as case, so that "cafe" finds "Café".
+	// Equality and sorting use a collation in Locale, which defaults to "en", and the text operators
+	// match each letter against its accented forms, as collation doesn't apply to regexes.
+	// Only indexes with the same collation are used, so the collection's indexes should be created with Collation().
+	DiacriticInsensitive bool
+	Locale               string
+
+	// CursorKey pages the list by cursor rather than offset, signing the cursors with the key.
+	// See ListState.KeysetFindQuery.
+	CursorKey CursorKey
+}
+
+const defaultCollationLocale = "en"
+
+// Collation is the collation for the schema, or nil if it doesn't need one.  FindQuery sets it,
+// but it has to be passed to Aggregate separately when using Pipelines.
+func (s ListSchema) Collation() *options.Collation {
+	if !s.DiacriticInsensitive {
+		return nil
+	}
+
+	locale := s.Locale
+	if locale == "" {
+		locale = defaultCollationLocale
+	}
+
+	// Strength 1 compares base letters only, ignoring both case and accents
+	return &options.Collation{Locale: locale, Strength: 1}
+}
+
+const defaultMaxRegexTime = 5 * time.Second
+
+// MaxTime is the server time limit for the ListState's query, or 0 if it doesn't need one.  The regex operator
+// is checked before it is run, but as a backstop, queries using it are stopped after MaxRegexTime.
+// FindQuery sets it, but it has to be passed to Aggregate separately when using Pipelines.
+func (s ListSchema) MaxTime(ls ListState) time.Duration {
+	for _, f := range ls.Filters {
+		if f.Operator != filterOperatorRegex {
+			continue
+		}
+
+		if s.MaxRegexTime > 0 {
+			return s.MaxRegexTime
+		}
+		return defaultMaxRegexTime
+	}
+
+	return 0
+}
+
+// Validation error codes
+const (
+	ValidationCodeUnknownField    = "unknown_field"
+	ValidationCodeInvalidOperator = "invalid_operator"
+	ValidationCodeInvalidValue    = "invalid_value"
+	ValidationCodeNotSortable     = "not_sortable"
+	ValidationCodeOutOfRange      = "out_of_range"
+	ValidationCodeTooManyFilters  = "too_many_filters"
+)
+
+// ValidationError is a single problem with a ListState.  Field is where the problem is in the ListState
+// as the client sent it, e.g. "filters[1].operator".
+type ValidationError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors are all of the problems with a ListState, ready to be sent back as a 400
+type ValidationErrors []ValidationError
+
+func (ve ValidationErrors) Error() string {
+	msgs := make([]string, len(ve))
+	for i, e := range ve {
+		msgs[i] = fmt.Sprintf("%s: %s", e.Field, e.Message)
+	}
+
+	return fmt.Sprintf("%s: %s", ErrInvalidListState, strings.Join(msgs, "; "))
+}
+
+func (ve ValidationErrors) Is(target error) bool {
+	return target == ErrInvalidListState
+}
+
+// StatusCode is the HTTP status to respond with
+func (ve ValidationErrors) StatusCode() int {
+	return http.StatusBadRequest
+}
+
+func (ve *ValidationErrors) add(field, code, format string, args ...interface{}) {
+	*ve = append(*ve, ValidationError{Field: field, Code: code, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the ListState against the schema, and returns ValidationErrors with every problem found
+func (s ListSchema) Validate(ls ListState) error {
+	var errs ValidationErrors
+
+	if s.MaxFilters > 0 && len(ls.Filters) > s.MaxFilters {
+		errs.add("filters", ValidationCodeTooManyFilters, "at most %d filters are allowed", s.MaxFilters)
+	}
+
+	for i, f := range ls.Filters {
+		path := fmt.Sprintf("filters[%d]", i)
+
+		fs, ok := s.Fields[f.Field]
+		if !ok {
+			errs.add(path+".field", ValidationCodeUnknownField, "%q can't be filtered on", f.Field)
+			continue
+		}
+
+		if !fs.allows(f.Operator) {
+			errs.add(path+".operator", ValidationCodeInvalidOperator, "%q isn't allowed on %q", f.Operator, f.Field)
+			continue
+		}
+
+		if err := checkValue(fs.Type, f.Operator, f.Value); err != nil {
+			errs.add(path+".value", ValidationCodeInvalidValue, "%s", err)
+			continue
+		}
+
+		if f.Operator == filterOperatorRegex {
+			if err := checkRegex(f.Value.(string), s.MaxRegexLength); err != nil {
+				errs.add(path+".value", ValidationCodeInvalidValue, "%s", err)
+			}
+		}
+	}
+
+	if ls.Order != "" {
+		if fs, ok := s.Fields[ls.Order]; !ok || !fs.Sortable {
+			errs.add("order", ValidationCodeNotSortable, "%q can't be sorted on", ls.Order)
+		}
+	}
+
+	for i, sf := range ls.ThenBy {
+		if fs, ok := s.Fields[sf.Field]; !ok || !fs.Sortable {
+			errs.add(fmt.Sprintf("thenBy[%d].field", i), ValidationCodeNotSortable, "%q can't be sorted on", sf.Field)
+		}
+	}
+
+	if ls.Next != "" || ls.Prev != "" {
+		field := "next"
+		if ls.Backwards() {
+			field = "prev"
+		}
+
+		if s.CursorKey == nil {
+			errs.add(field, ValidationCodeInvalidValue, "the list isn't paged by cursor")
+		} else if _, _, err := ls.cursorPosition(s.CursorKey); err != nil {
+			errs.add(field, ValidationCodeInvalidValue, "%s", err)
+		}
+	}
+
+	if ls.Limit < 0 || (s.MaxLimit > 0 && ls.Limit > s.MaxLimit) {
+		errs.add("limit", ValidationCodeOutOfRange, "limit must be between 0 and %d", s.MaxLimit)
+	}
+
+	if ls.Offset < 0 {
+		errs.add("offset", ValidationCodeOutOfRange, "offset can't be negative")
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+// checkValue checks that the value is the right type for the field and operator
+func checkValue(ft FieldType, operator string, value interface{}) error {
+	if value == nil {
+		switch operator {
+		case filterOperatorEq, filterOperatorEqDefault, filterOperatorNeq, filterOperatorEqOrNull:
+			return nil
+		}
+
+		return fmt.Errorf("%s needs a value", operator)
+	}
+
+	if operator == filterOperatorRegex {
+		if _, ok := value.(string); !ok || ft != FieldTypeString {
+			return fmt.Errorf("%s needs a string field and pattern", operator)
+		}
+		return nil
+	}
+
+	if operator == filterOperatorEntityNullCheck {
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s needs true or false", operator)
+		}
+		return nil
+	}
+
+	switch ft {
+	case FieldTypeString:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("expected a string, got %T", value)
+		}
+	case FieldTypeNumber:
+		if !isNumber(value) {
+			return fmt.Errorf("expected a number, got %T", value)
+		}
+	case FieldTypeBool:
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("expected true or false, got %T", value)
+		}
+	case FieldTypeDate:
+		if _, err := parseDate(value); err != nil {
+			return err
+		}
+	case FieldTypeObjectID:
+		if _, err := objectID(value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case float64, float32, int, int32, int64, json.Number:
+		return true
+	}
+
+	return false
+}
+
+func objectID(value interface{}) (primitive.ObjectID, error) {
+	switch v := value.(type) {
+	case primitive.ObjectID:
+		return v, nil
+	case string:
+		id, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return primitive.NilObjectID, fmt.Errorf("%q isn't an object ID", v)
+		}
+		return id, nil
+	}
+
+	return primitive.NilObjectID, fmt.Errorf("expected an object ID, got %T", value)
+}
+
+// typedFilter builds a filter knowing the type of the field.  Strings are never turned into
+// object IDs, object IDs always are, and JSON numbers are converted to numbers.
+func (s ListSchema) typedFilter(f Filter) (interface{}, error) {
+	fs := s.Fields[f.Field]
+	value := f.Value
+
+	switch fs.Type {
+	case FieldTypeString:
+		switch f.Operator {
+		case filterOperatorEq, filterOperatorEqDefault:
+			return value, nil
+		case filterOperatorEqOrNull:
+			return bson.M{mongoutil.OpIn: bson.A{value, nil}}, nil
+		case filterOperatorStartsWith, filterOperatorContains, filterOperatorEndsWith:
+			return textFilter(f.Operator, value, s.DiacriticInsensitive)
+		case filterOperatorRegex:
+			pattern, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s needs a string", ErrInvalidFilterValue, f.Operator)
+			}
+			return regexFilter(pattern), nil
+		}
+	case FieldTypeNumber:
+		if n, ok := value.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				value = i
+			} else if fl, err := n.Float64(); err == nil {
+				value = fl
+			}
+		}
+	case FieldTypeObjectID:
+		if f.Operator != filterOperatorEntityNullCheck && value != nil {
+			id, err := objectID(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s", ErrInvalidFilterValue, err)
+			}
+			value = id
+		}
+	}
+
+	return createFilter(f.Field, f.Operator, value)
+}
+
+// FindQuery validates the ListState and then builds its query, paged by cursor if the schema has a CursorKey
+func (s ListSchema) FindQuery(ls ListState) (mongoutil.FindQuery, error) {
+	if err := s.Validate(ls); err != nil {
+		return mongoutil.FindQuery{}, err
+	}
+
+	findQuery := ls.findQuery
+	if s.CursorKey != nil {
+		findQuery = func(buildFilter filterBuilder) (mongoutil.FindQuery, error) {
+			return ls.keysetFindQuery(buildFilter, s.CursorKey)
+		}
+	}
+
+	fq, err := findQuery(s.typedFilter)
+	fq.Collation = s.Collation()
+	fq.MaxTime = s.MaxTime(ls)
+	return fq, err
+}
+
+// Pipelines validates the ListState and then builds its pipelines, as BodyToPipelines, or KeysetPipelines
+// if the schema has a CursorKey
+func (s ListSchema) Pipelines(ls ListState) (bson.A, bson.A, error) {
+	if err := s.Validate(ls); err != nil {
+		return bson.A{}, bson.A{}, err
+	}
+
+	if s.CursorKey != nil {
+		return ls.keysetPipelines(s.typedFilter, s.CursorKey)
+	}
+
+	return bodyToPipelines(ls, s.typedFilter)
+}
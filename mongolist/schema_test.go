@@ -0,0 +1,207 @@
+package mongolist
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/dogpakk/lib/mongoutil"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var testSchema = ListSchema{
+	Fields: map[string]FieldSchema{
+		"name":     {Type: FieldTypeString, Sortable: true},
+		"sku":      {Type: FieldTypeString, Operators: []string{filterOperatorEq, filterOperatorStartsWith}},
+		"price":    {Type: FieldTypeNumber, Sortable: true},
+		"featured": {Type: FieldTypeBool},
+		"created":  {Type: FieldTypeDate, Sortable: true},
+		"category": {Type: FieldTypeObjectID},
+	},
+	MaxLimit:   100,
+	MaxFilters: 5,
+}
+
+// filterFor digs the filter for the field out of a FindQuery built with the default "and"
+func filterFor(fq mongoutil.FindQuery, field string) interface{} {
+	top := fq.Query[mongoutil.OpAnd].(mongoutil.Queries)
+	for _, q := range top[len(top)-1][mongoutil.OpAnd].(mongoutil.Queries) {
+		if f, ok := q[field]; ok {
+			return f
+		}
+	}
+
+	return nil
+}
+
+func TestListSchemaFindQuery(t *testing.T) {
+	categoryID := primitive.NewObjectID()
+	hexName := primitive.NewObjectID().Hex()
+
+	fq, err := testSchema.FindQuery(ListState{
+		Filters: []Filter{
+			{Field: "name", Value: hexName},
+			{Field: "category", Operator: filterOperatorEq, Value: categoryID.Hex()},
+			{Field: "price", Operator: filterOperatorGt, Value: 10.5},
+			{Field: "featured", Operator: filterOperatorEq, Value: false},
+			{Field: "created", Operator: filterOperatorAfter, Value: "2021-01-01T00:00:00.000Z"},
+		},
+		Order: "price",
+		Limit: 100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		field    string
+		expected interface{}
+	}{
+		{"name", hexName},
+		{"category", categoryID},
+		{"price", bson.M{"$gt": 10.5}},
+		{"featured", bson.M{"$in": bson.A{false, nil}}},
+	}
+
+	for _, test := range tests {
+		got := filterFor(fq, test.field)
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Testing %s filter. Expected %v; got %v", test.field, test.expected, got)
+		}
+	}
+
+	if _, _, err := testSchema.Pipelines(ListState{Filters: []Filter{{Field: "sku", Operator: filterOperatorStartsWith, Value: "AB"}}}); err != nil {
+		t.Errorf("Testing pipelines. Unexpected error %v", err)
+	}
+}
+
+func TestListSchemaValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		listState ListState
+		expected  ValidationErrors
+	}{
+		{
+			name:      "blank",
+			listState: ListState{},
+		},
+		{
+			name:      "unknown field",
+			listState: ListState{Filters: []Filter{{Field: "password", Value: "x"}}},
+			expected:  ValidationErrors{{Field: "filters[0].field", Code: ValidationCodeUnknownField}},
+		},
+		{
+			name:      "unknown operator",
+			listState: ListState{Filters: []Filter{{Field: "name", Operator: "$where", Value: "x"}}},
+			expected:  ValidationErrors{{Field: "filters[0].operator", Code: ValidationCodeInvalidOperator}},
+		},
+		{
+			name:      "operator not allowed on the field",
+			listState: ListState{Filters: []Filter{{Field: "sku", Operator: filterOperatorContains, Value: "x"}}},
+			expected:  ValidationErrors{{Field: "filters[0].operator", Code: ValidationCodeInvalidOperator}},
+		},
+		{
+			name:      "operator not for the type",
+			listState: ListState{Filters: []Filter{{Field: "created", Operator: filterOperatorGt, Value: "x"}}},
+			expected:  ValidationErrors{{Field: "filters[0].operator", Code: ValidationCodeInvalidOperator}},
+		},
+		{
+			name: "wrong value types",
+			listState: ListState{Filters: []Filter{
+				{Field: "featured", Value: "yes"},
+				{Field: "created", Operator: filterOperatorBefore, Value: true},
+				{Field: "created", Operator: filterOperatorBefore, Value: "yesterday"},
+				{Field: "category", Value: "not an id"},
+				{Field: "price", Operator: filterOperatorLt, Value: "10"},
+			}},
+			expected: ValidationErrors{
+				{Field: "filters[0].value", Code: ValidationCodeInvalidValue},
+				{Field: "filters[1].value", Code: ValidationCodeInvalidValue},
+				{Field: "filters[2].value", Code: ValidationCodeInvalidValue},
+				{Field: "filters[3].value", Code: ValidationCodeInvalidValue},
+				{Field: "filters[4].value", Code: ValidationCodeInvalidValue},
+			},
+		},
+		{
+			name:      "null check needs a bool",
+			listState: ListState{Filters: []Filter{{Field: "category", Operator: filterOperatorEntityNullCheck, Value: "true"}}},
+			expected:  ValidationErrors{{Field: "filters[0].value", Code: ValidationCodeInvalidValue}},
+		},
+		{
+			name:      "missing value",
+			listState: ListState{Filters: []Filter{{Field: "price", Operator: filterOperatorGt}}},
+			expected:  ValidationErrors{{Field: "filters[0].value", Code: ValidationCodeInvalidValue}},
+		},
+		{
+			name:      "sorting and paging",
+			listState: ListState{Order: "featured", Limit: 101, Offset: -1},
+			expected: ValidationErrors{
+				{Field: "order", Code: ValidationCodeNotSortable},
+				{Field: "limit", Code: ValidationCodeOutOfRange},
+				{Field: "offset", Code: ValidationCodeOutOfRange},
+			},
+		},
+		{
+			name: "too many filters",
+			listState: ListState{Filters: []Filter{
+				{Field: "name", Value: "a"}, {Field: "name", Value: "b"}, {Field: "name", Value: "c"},
+				{Field: "name", Value: "d"}, {Field: "name", Value: "e"}, {Field: "name", Value: "f"},
+			}},
+			expected: ValidationErrors{{Field: "filters", Code: ValidationCodeTooManyFilters}},
+		},
+	}
+
+	for _, test := range tests {
+		err := testSchema.Validate(test.listState)
+		if test.expected == nil {
+			if err != nil {
+				t.Errorf("Testing %s. Unexpected error %v", test.name, err)
+			}
+			continue
+		}
+
+		var got ValidationErrors
+		if !errors.As(err, &got) {
+			t.Errorf("Testing %s. Expected ValidationErrors; got %v", test.name, err)
+			continue
+		}
+
+		if !errors.Is(err, ErrInvalidListState) || got.StatusCode() != 400 {
+			t.Errorf("Testing %s. Expected %v with a 400; got %v with a %d", test.name, ErrInvalidListState, err, got.StatusCode())
+		}
+
+		if len(got) != len(test.expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, test.expected, got)
+			continue
+		}
+
+		for i := range got {
+			if got[i].Field != test.expected[i].Field || got[i].Code != test.expected[i].Code || got[i].Message == "" {
+				t.Errorf("Testing %s error %d. Expected %v; got %v", test.name, i, test.expected[i], got[i])
+			}
+		}
+
+		if _, err := testSchema.FindQuery(test.listState); !errors.Is(err, ErrInvalidListState) {
+			t.Errorf("Testing %s FindQuery. Expected %v; got %v", test.name, ErrInvalidListState, err)
+		}
+	}
+}
+
+func TestCreateFilterBadValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+	}{
+		{"date that isn't a string", Filter{Field: "created", Operator: filterOperatorBefore, Value: 12}},
+		{"date that doesn't parse", Filter{Field: "created", Operator: filterOperatorOnOrAfter, Value: "soon"}},
+		{"null check that isn't a bool", Filter{Field: "category", Operator: filterOperatorEntityNullCheck, Value: "true"}},
+	}
+
+	for _, test := range tests {
+		_, err := ListState{Filters: []Filter{test.filter}}.FindQuery()
+		if !errors.Is(err, ErrInvalidFilterValue) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, ErrInvalidFilterValue, err)
+		}
+	}
+}
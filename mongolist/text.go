@@ -0,0 +1,308 @@
+package mongolist
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrRegexTooComplex = errors.New("regex is too complex")
+
+const (
+	// defaultMaxRegexLength is the longest pattern allowed for the regex operator, unless a ListSchema says otherwise
+	defaultMaxRegexLength = 100
+	// maxRegexRepeat is the largest count allowed in a {n,m} repeat
+	maxRegexRepeat = 100
+	// maxRegexUnbounded is the most *, + and {n,} repeats allowed in one pattern, as each one
+	// multiplies the ways a string can be split between them, e.g. .*.*.*x
+	maxRegexUnbounded = 4
+)
+
+// diacritics are the accented forms of each letter.  Collation doesn't apply to $regex, so in the
+// diacritic insensitive mode, letters in a text search are matched against all of their forms instead.
+var diacritics = map[rune]string{
+	'a': "aàáâãäåāăą",
+	'c': "cçćčĉċ",
+	'd': "dďđ",
+	'e': "eèéêëēĕėęě",
+	'g': "gĝğġģ",
+	'i': "iìíîïĩīĭįı",
+	'l': "lĺļľŀł",
+	'n': "nñńņňŉ",
+	'o': "oòóôõöøōŏő",
+	'r': "rŕŗř",
+	's': "sśŝşš",
+	't': "tţťŧ",
+	'u': "uùúûüũūŭůűų",
+	'y': "yýÿŷ",
+	'z': "zźżž",
+}
+
+// diacriticBase is the plain letter for each accented one, so that a search typed with accents
+// also matches text without them
+var diacriticBase = map[rune]rune{}
+
+func init() {
+	for base, forms := range diacritics {
+		for _, r := range forms {
+			diacriticBase[r] = base
+		}
+	}
+}
+
+// diacriticPattern escapes the text for a regex, matching each letter with or without accents
+func diacriticPattern(text string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if base, ok := diacriticBase[r]; ok {
+			forms := diacritics[base]
+			b.WriteString("[" + forms + strings.ToUpper(forms) + "]")
+			continue
+		}
+
+		b.WriteString(regexp.QuoteMeta(string(r)))
+	}
+
+	return b.String()
+}
+
+// textFilter is a case insensitive starts, contains or ends filter.  The text is always matched
+// literally, with any regex metacharacters in it escaped.
+func textFilter(operator string, value interface{}, diacriticInsensitive bool) (interface{}, error) {
+	text, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s needs a string", ErrInvalidFilterValue, operator)
+	}
+
+	pattern := regexp.QuoteMeta(text)
+	if diacriticInsensitive {
+		pattern = diacriticPattern(text)
+	}
+
+	switch operator {
+	case filterOperatorStartsWith:
+		pattern = "^" + pattern
+	case filterOperatorEndsWith:
+		pattern = pattern + "$"
+	}
+
+	return regexFilter(pattern), nil
+}
+
+func regexFilter(pattern string) bson.D {
+	return bson.D{
+		{Key: "$regex", Value: pattern},
+		{Key: "$options", Value: "i"},
+	}
+}
+
+// checkRegex makes sure a pattern for the regex operator is valid and cheap enough to run.  As well
+// as the length, it rejects a repeat or an optional part inside another repeat, such as (a+)+ or (a?){30},
+// and an alternation inside a repeat, such as (a|aa)+, which are what make a regex backtrack catastrophically, along with large
+// {n,m} counts and more than a few unbounded repeats.  Patterns are parsed with Go's regex syntax,
+// which is a subset of Mongo's, so features such as backreferences are rejected too.
+// Mongo's regexes still backtrack, so queries using them should have a time limit as well; see ListSchema.MaxTime.
+func checkRegex(pattern string, maxLength int) error {
+	if maxLength <= 0 {
+		maxLength = defaultMaxRegexLength
+	}
+
+	if len(pattern) > maxLength {
+		return fmt.Errorf("%w: longer than %d characters", ErrRegexTooComplex, maxLength)
+	}
+
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidFilterValue, err)
+	}
+
+	// Go's parser merges alternations where it can, e.g. \w|\d into one class, but Mongo's doesn't,
+	// so they are looked for in the pattern as written
+	if repeatedAlternation(pattern) {
+		return fmt.Errorf("%w: has an alternation inside a repeat", ErrRegexTooComplex)
+	}
+
+	unbounded := 0
+	return checkRegexNode(re, false, &unbounded)
+}
+
+func checkRegexNode(re *syntax.Regexp, inRepeat bool, unbounded *int) error {
+	switch re.Op {
+	case syntax.OpRepeat:
+		if re.Max > maxRegexRepeat || re.Min > maxRegexRepeat {
+			return fmt.Errorf("%w: repeats more than %d times", ErrRegexTooComplex, maxRegexRepeat)
+		}
+		fallthrough
+	case syntax.OpStar, syntax.OpPlus:
+		if inRepeat {
+			return fmt.Errorf("%w: has a repeat inside a repeat", ErrRegexTooComplex)
+		}
+		inRepeat = true
+
+		// A repeat of something that can match nothing can split the text in endless ways, e.g. (\b)*
+		if canMatchEmpty(re.Sub[0]) {
+			return fmt.Errorf("%w: repeats something that can be empty", ErrRegexTooComplex)
+		}
+
+		if re.Op != syntax.OpRepeat || re.Max == -1 {
+			*unbounded++
+			if *unbounded > maxRegexUnbounded {
+				return fmt.Errorf("%w: has more than %d unbounded repeats", ErrRegexTooComplex, maxRegexUnbounded)
+			}
+		}
+	}
+
+	for _, sub := range re.Sub {
+		if err := checkRegexNode(sub, inRepeat, unbounded); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// canMatchEmpty is whether the regex can match without taking any text, which an optional part inside
+// a repeat, such as (a?)*, is as bad as a repeat inside a repeat
+func canMatchEmpty(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpEmptyMatch, syntax.OpStar, syntax.OpQuest,
+		syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	case syntax.OpRepeat:
+		return re.Min == 0 || canMatchEmpty(re.Sub[0])
+	case syntax.OpPlus, syntax.OpCapture:
+		return canMatchEmpty(re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			if !canMatchEmpty(sub) {
+				return false
+			}
+		}
+		return true
+	case syntax.OpAlternate:
+		for _, sub := range re.Sub {
+			if canMatchEmpty(sub) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// repeatedAlternation is whether a group containing a | is followed by *, + or {, at any depth.
+// A choice between single distinct letters or digits, such as (a|b), can only match one way, so it is
+// allowed.  Escapes and \Q...\E quotes are skipped, and a ) without a ( is taken as a literal, so that
+// nothing the scan gets wrong can do worse than reject a pattern.
+func repeatedAlternation(pattern string) bool {
+	type group struct {
+		start       int
+		alternation bool
+	}
+	var groups []group
+
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i = escapeEnd(pattern, i)
+		case '[':
+			i = classEnd(pattern, i)
+		case '(':
+			groups = append(groups, group{start: i})
+		case '|':
+			if len(groups) > 0 {
+				groups[len(groups)-1].alternation = true
+			}
+		case ')':
+			if len(groups) == 0 {
+				continue
+			}
+
+			g := groups[len(groups)-1]
+			groups = groups[:len(groups)-1]
+
+			if !g.alternation || distinctCharacters(pattern[g.start+1:i]) {
+				continue
+			}
+
+			if i+1 < len(pattern) && strings.IndexByte("*+{", pattern[i+1]) >= 0 {
+				return true
+			}
+
+			// The alternation is inside the enclosing group too
+			if len(groups) > 0 {
+				groups[len(groups)-1].alternation = true
+			}
+		}
+	}
+
+	return false
+}
+
+// distinctCharacters is whether the inside of a group is a choice between single letters or digits
+// that are all different, ignoring case as the regex operator does
+func distinctCharacters(group string) bool {
+	seen := map[rune]bool{}
+	for _, branch := range strings.Split(strings.TrimPrefix(group, "?:"), "|") {
+		runes := []rune(branch)
+		if len(runes) != 1 || !(unicode.IsLetter(runes[0]) || unicode.IsDigit(runes[0])) {
+			return false
+		}
+
+		r := unicode.ToLower(runes[0])
+		if seen[r] {
+			return false
+		}
+		seen[r] = true
+	}
+
+	return true
+}
+
+// classEnd is the index of the ] that closes the character class starting at i
+func classEnd(pattern string, i int) int {
+	i++
+	if i < len(pattern) && pattern[i] == '^' {
+		i++
+	}
+
+	// A ] straight after the [ or [^ is a literal
+	if i < len(pattern) && pattern[i] == ']' {
+		i++
+	}
+
+	for ; i < len(pattern); i++ {
+		switch {
+		case pattern[i] == '\\':
+			i = escapeEnd(pattern, i)
+		case pattern[i] == '[' && i+1 < len(pattern) && pattern[i+1] == ':':
+			// A named class such as [:alpha:]
+			if end := strings.Index(pattern[i:], ":]"); end >= 0 {
+				i += end + 1
+			}
+		case pattern[i] == ']':
+			return i
+		}
+	}
+
+	return i
+}
+
+// escapeEnd is the index of the last character of the escape starting with the \ at i, which for
+// \Q is the E of the \E that ends the quote, or the end of the pattern if there isn't one
+func escapeEnd(pattern string, i int) int {
+	if i+1 < len(pattern) && pattern[i+1] == 'Q' {
+		if end := strings.Index(pattern[i+2:], `\E`); end >= 0 {
+			return i + 2 + end + 1
+		}
+		return len(pattern)
+	}
+
+	return i + 1
+}
@@ -0,0 +1,200 @@
+package mongolist
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestTextFilterEscapes(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator string
+		value    string
+		expected string
+	}{
+		{"contains", filterOperatorContains, "a.b*(c", `a\.b\*\(c`},
+		{"starts", filterOperatorStartsWith, "^x|y", `^\^x\|y`},
+		{"ends", filterOperatorEndsWith, "$5.00", `\$5\.00$`},
+		{"plain", filterOperatorContains, "blue shirt", "blue shirt"},
+	}
+
+	for _, test := range tests {
+		fq, err := ListState{Filters: []Filter{{Field: "name", Operator: test.operator, Value: test.value}}}.FindQuery()
+		if err != nil {
+			t.Errorf("Testing %s. Unexpected error %v", test.name, err)
+			continue
+		}
+
+		expected := bson.D{{Key: "$regex", Value: test.expected}, {Key: "$options", Value: "i"}}
+		if got := filterFor(fq, "name"); !reflect.DeepEqual(got, expected) {
+			t.Errorf("Testing %s. Expected %v; got %v", test.name, expected, got)
+		}
+	}
+
+	if _, err := (ListState{Filters: []Filter{{Field: "name", Operator: filterOperatorContains, Value: 12}}}).FindQuery(); !errors.Is(err, ErrInvalidFilterValue) {
+		t.Errorf("Testing contains a number. Expected %v; got %v", ErrInvalidFilterValue, err)
+	}
+
+	if _, err := (ListState{Filters: []Filter{{Field: "name", Operator: filterOperatorRegex, Value: "^a"}}}).FindQuery(); !errors.Is(err, ErrInvalidFilterValue) {
+		t.Errorf("Testing regex without a schema. Expected %v; got %v", ErrInvalidFilterValue, err)
+	}
+}
+
+func TestCheckRegex(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		expected error
+	}{
+		{"^ab+c$", nil},
+		{"(red|blue)-[0-9]{2,4}", nil},
+		{"(a?)*b", ErrRegexTooComplex},
+		{"^(a?){30}a{30}$", ErrRegexTooComplex},
+		{`(\b)+x`, ErrRegexTooComplex},
+		{"(ab?)+", nil},
+		{"colou?r", nil},
+		{`\Q)\E`, nil},
+		{`\Q(\E(a|b)+`, nil},
+		{`\Q(\E(a|b)`, nil},
+		{`[)]+\Q)\E(a|b)`, nil},
+		{"(a|b|c)+", nil},
+		{"(?:a|A)+", ErrRegexTooComplex},
+		{"(a|bc)+", ErrRegexTooComplex},
+		{`\Qa(\E(x|y)+`, nil},
+		{`(\Q|\E)+`, nil},
+		{`\Q|(\E|(a|aa)+`, ErrRegexTooComplex},
+		{"(a+)+", ErrRegexTooComplex},
+		{`(\w+\s?)+$`, ErrRegexTooComplex},
+		{"(a*b)*", ErrRegexTooComplex},
+		{"(a|aa)+$", ErrRegexTooComplex},
+		{`(\w|\d)+$`, ErrRegexTooComplex},
+		{"((a|ab)c){2,}", ErrRegexTooComplex},
+		{".*.*.*.*.*.*.*.*.*.*x", ErrRegexTooComplex},
+		{"a+b+c+d+", nil},
+		{"a+b+c+d+e+", ErrRegexTooComplex},
+		{"a{2,}b{1,}c*d*e+", ErrRegexTooComplex},
+		{"(red|blue)?x+", nil},
+		{`[(|]+\(a|b\)+`, nil},
+		{"[]|(]+", nil},
+		{"a{1,1000}", ErrRegexTooComplex},
+		{"(", ErrInvalidFilterValue},
+		{`(a)\1`, ErrInvalidFilterValue},
+		{"abcdefghij" + "abcdefghij" + "abcdefghij" + "abcdefghij" + "abcdefghij" + "abcdefghij" + "abcdefghij" + "abcdefghij" + "abcdefghij" + "abcdefghij" + "a", ErrRegexTooComplex},
+	}
+
+	for _, test := range tests {
+		err := checkRegex(test.pattern, 0)
+		if !errors.Is(err, test.expected) {
+			t.Errorf("Testing %q. Expected %v; got %v", test.pattern, test.expected, err)
+		}
+	}
+
+	if err := checkRegex("abcdef", 5); !errors.Is(err, ErrRegexTooComplex) {
+		t.Errorf("Testing a lower limit. Expected %v; got %v", ErrRegexTooComplex, err)
+	}
+}
+
+func TestListSchemaRegex(t *testing.T) {
+	schema := ListSchema{
+		Fields: map[string]FieldSchema{
+			"notes": {Type: FieldTypeString, Operators: []string{filterOperatorContains, filterOperatorRegex}},
+			"name":  {Type: FieldTypeString},
+		},
+	}
+
+	fq, err := schema.FindQuery(ListState{Filters: []Filter{{Field: "notes", Operator: filterOperatorRegex, Value: "^(red|blue) "}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := bson.D{{Key: "$regex", Value: "^(red|blue) "}, {Key: "$options", Value: "i"}}
+	if got := filterFor(fq, "notes"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Testing regex filter. Expected %v; got %v", expected, got)
+	}
+
+	if fq.MaxTime != defaultMaxRegexTime || fq.FindOptions().MaxTime == nil || *fq.FindOptions().MaxTime != defaultMaxRegexTime {
+		t.Errorf("Testing regex time limit. Expected %v; got %v", defaultMaxRegexTime, fq.MaxTime)
+	}
+
+	if fq, _ := schema.FindQuery(ListState{Filters: []Filter{{Field: "notes", Operator: filterOperatorContains, Value: "a"}}}); fq.MaxTime != 0 || fq.FindOptions().MaxTime != nil {
+		t.Errorf("Testing no regex. Expected no time limit; got %v", fq.MaxTime)
+	}
+
+	schema.MaxRegexTime = time.Second
+	if res := schema.MaxTime(ListState{Filters: []Filter{{Field: "notes", Operator: filterOperatorRegex, Value: "a"}}}); res != time.Second {
+		t.Errorf("Testing MaxRegexTime. Expected %v; got %v", time.Second, res)
+	}
+
+	tests := []struct {
+		name         string
+		filter       Filter
+		expectedCode string
+	}{
+		{"too complex", Filter{Field: "notes", Operator: filterOperatorRegex, Value: "(a+)+$"}, ValidationCodeInvalidValue},
+		{"not a string", Filter{Field: "notes", Operator: filterOperatorRegex, Value: true}, ValidationCodeInvalidValue},
+		{"not opted in", Filter{Field: "name", Operator: filterOperatorRegex, Value: "^a"}, ValidationCodeInvalidOperator},
+	}
+
+	for _, test := range tests {
+		var errs ValidationErrors
+		err := schema.Validate(ListState{Filters: []Filter{test.filter}})
+		if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Code != test.expectedCode {
+			t.Errorf("Testing %s. Expected %s; got %v", test.name, test.expectedCode, err)
+		}
+	}
+}
+
+func TestListSchemaDiacriticInsensitive(t *testing.T) {
+	schema := ListSchema{
+		Fields:               map[string]FieldSchema{"name": {Type: FieldTypeString, Sortable: true}},
+		DiacriticInsensitive: true,
+	}
+
+	tests := []struct {
+		operator string
+		value    string
+		matches  []string
+		misses   []string
+	}{
+		{filterOperatorContains, "cafe", []string{"Café Noir", "CAFE", "le café"}, []string{"caff"}},
+		{filterOperatorStartsWith, "Zoë", []string{"zoe's", "ZOË"}, []string{"a zoe"}},
+		{filterOperatorEndsWith, "(2)", []string{"Crème (2)"}, []string{"Crème 2"}},
+	}
+
+	for _, test := range tests {
+		fq, err := schema.FindQuery(ListState{Filters: []Filter{{Field: "name", Operator: test.operator, Value: test.value}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pattern := filterFor(fq, "name").(bson.D)[0].Value.(string)
+		re := regexp.MustCompile("(?i)" + pattern)
+		for _, s := range test.matches {
+			if !re.MatchString(s) {
+				t.Errorf("Testing %s %q. Expected to match %q with %s", test.operator, test.value, s, pattern)
+			}
+		}
+		for _, s := range test.misses {
+			if re.MatchString(s) {
+				t.Errorf("Testing %s %q. Expected not to match %q with %s", test.operator, test.value, s, pattern)
+			}
+		}
+
+		if fq.Collation == nil || fq.Collation.Strength != 1 || fq.Collation.Locale != "en" {
+			t.Errorf("Testing %s collation. Expected strength 1 in en; got %+v", test.operator, fq.Collation)
+		}
+
+		if fq.FindOptions().Collation != fq.Collation {
+			t.Errorf("Testing %s find options. Expected the collation to be set", test.operator)
+		}
+	}
+
+	schema.DiacriticInsensitive = false
+	if fq, _ := schema.FindQuery(ListState{}); fq.Collation != nil {
+		t.Errorf("Testing without diacritic insensitivity. Expected no collation; got %+v", fq.Collation)
+	}
+}
@@ -0,0 +1,131 @@
+package mongoutil
+
+import (
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrKeysetMismatch = errors.New("keyset needs one value for each sort key")
+
+const idField = "_id"
+
+// SortKey is one field of a sort
+type SortKey struct {
+	Field      string
+	Descending bool
+}
+
+// SortKeys is a sort over several fields, in order of priority
+type SortKeys []SortKey
+
+// withID is the keys with _id as the final tiebreak.  _id follows the direction of the last key,
+// so that e.g. a list sorted newest first has the newest of any ties first too.  If the sort
+// already includes _id, nothing after it can matter, so the keys stop there.
+func (sk SortKeys) withID() SortKeys {
+	for i, k := range sk {
+		if k.Field == idField {
+			return sk[:i+1]
+		}
+	}
+
+	return append(append(SortKeys{}, sk...), SortKey{Field: idField, Descending: len(sk) > 0 && sk[len(sk)-1].Descending})
+}
+
+// KeysetSort is the sort for keyset pagination.  It is the keys and then _id, so that documents
+// with the same sort values always come in the same order and no page skips or repeats them.
+// Backwards reverses every direction, to fetch the page before a document.
+func (sk SortKeys) KeysetSort(backwards bool) bson.D {
+	keys := sk.withID()
+	res := make(bson.D, len(keys))
+	for i, k := range keys {
+		res[i] = bson.E{Key: k.Field, Value: order(k.Descending != backwards)}
+	}
+
+	return res
+}
+
+// KeysetQuery matches the documents that come after the one with the given sort values and _id,
+// in the order of KeysetSort.  For keys a and b, ascending, it is
+//
+//	{$or: [{a: {$gt: va}}, {a: va, b: {$gt: vb}}, {a: va, b: vb, _id: {$gt: id}}]}
+//
+// Mongo sorts a missing or null value before any other, but a range such as $gt: null matches nothing,
+// so nil values get ranges of their own: ascending, everything after null is {$ne: null}, and descending
+// nothing is, so the branch is left out.  Descending from any other value, the nulls come after it,
+// so the range is {$or: [{a: {$lt: va}}, {a: null}]}, apart from _id, which can't be null.  Mongo only compares values of the same type in a
+// range otherwise, so each sort field should hold one type, apart from being missing or null.
+func (sk SortKeys) KeysetQuery(values []interface{}, id interface{}, backwards bool) (Query, error) {
+	if len(values) != len(sk) {
+		return nil, fmt.Errorf("%w: %d keys and %d values", ErrKeysetMismatch, len(sk), len(values))
+	}
+
+	keys := sk.withID()
+	values = append(append([]interface{}{}, values...), id)
+
+	var branches Queries
+	for i, k := range keys {
+		// Each branch is equal on the keys before i and past the value for key i
+		descending := k.Descending != backwards
+		if values[i] == nil && descending {
+			continue
+		}
+
+		branch := NewBlankQuery()
+		for j := 0; j < i; j++ {
+			branch.AddFilter(keys[j].Field, values[j])
+		}
+
+		switch {
+		case values[i] == nil:
+			branch.AddFilter(k.Field, NewQuery(OpNe, nil))
+		case descending && k.Field == idField:
+			branch.AddFilter(k.Field, NewQuery(OpLt, values[i]))
+		case descending:
+			branch.AddFilter(OpOr, Queries{
+				NewQuery(k.Field, NewQuery(OpLt, values[i])),
+				NewQuery(k.Field, nil),
+			})
+		default:
+			branch.AddFilter(k.Field, NewQuery(OpGt, values[i]))
+		}
+
+		branches = append(branches, branch)
+	}
+
+	if len(branches) == 1 {
+		return branches[0], nil
+	}
+
+	return NewQuery(OpOr, branches), nil
+}
+
+// NewKeysetFindQuery is a FindQuery for the page after the document with the given sort values and _id,
+// or before it if backwards.  A nil id is the first page, or the last one if backwards.  Backwards pages
+// come in reverse order, so the results need reversing before they are shown.  The limit is as given,
+// so to tell whether there is another page, ask for one more than will be shown.
+func NewKeysetFindQuery(q Query, keys SortKeys, values []interface{}, id interface{}, backwards bool, limit int) (FindQuery, error) {
+	fq := FindQuery{
+		Query: q,
+		Sort:  keys.KeysetSort(backwards),
+		Limit: limit,
+	}
+
+	if id == nil {
+		return fq, nil
+	}
+
+	kq, err := keys.KeysetQuery(values, id, backwards)
+	if err != nil {
+		return fq, err
+	}
+
+	if len(q) == 0 {
+		fq.Query = kq
+	} else {
+		fq.Query = NewQuery(OpAnd, Queries{q, kq})
+	}
+
+	return fq, nil
+}
@@ -1,6 +1,8 @@
 package mongoutil
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
@@ -12,6 +14,12 @@ type FindQuery struct {
 	Sort   interface{}
 	Offset int
 	Limit  int
+
+	// Collation is optional, e.g. to compare strings ignoring case and accents
+	Collation *options.Collation
+
+	// MaxTime is optional, and stops the query on the server if it runs for longer
+	MaxTime time.Duration
 }
 
 func order(sortDescending bool) int {
@@ -58,6 +66,14 @@ func (fq FindQuery) FindOptions() *options.FindOptions {
 		opts.SetSort(fq.Sort)
 	}
 
+	if fq.Collation != nil {
+		opts.SetCollation(fq.Collation)
+	}
+
+	if fq.MaxTime > 0 {
+		opts.SetMaxTime(fq.MaxTime)
+	}
+
 	return opts
 }
 
@@ -72,6 +88,14 @@ func (fq FindQuery) FindOneOptions() *options.FindOneOptions {
 		opts.SetSort(fq.Sort)
 	}
 
+	if fq.Collation != nil {
+		opts.SetCollation(fq.Collation)
+	}
+
+	if fq.MaxTime > 0 {
+		opts.SetMaxTime(fq.MaxTime)
+	}
+
 	return opts
 }
 
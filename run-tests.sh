@@ -11,3 +11,15 @@ cd ..
 cd financial
 go test
 cd ..
+
+cd ledger
+go test
+cd ..
+
+cd datetime
+go test
+cd ..
+
+cd financial/stats
+go test
+cd ../..